@@ -1,9 +1,22 @@
 package app
 
 import (
+	"encoding/csv"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/simpplify-org/GO-simpzap/pkg/eventhub"
+	"github.com/simpplify-org/GO-simpzap/pkg/phone"
+	"github.com/simpplify-org/GO-simpzap/pkg/ratelimit"
+	"github.com/simpplify-org/GO-simpzap/pkg/whatsapp"
 )
 
 type WhatsAppHandler struct {
@@ -16,11 +29,45 @@ func NewWhatsAppHandler(svc *WhatsAppService) *WhatsAppHandler {
 }
 
 func (h *WhatsAppHandler) RegisterRoutes(e *echo.Echo) {
-	e.GET("/dash", h.Dash)
+	features := h.Service.Config.Features
+	if features.Dashboard {
+		e.GET("/dash", h.Dash)
+	}
+	if features.Metrics {
+		e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+	}
+	e.GET("/config", h.Config)
 	e.POST("/create", h.CreateDevice)
 	e.GET("/devices", h.ListDevices)
+	e.GET("/events", h.Events) // STREAM AGREGADO DE TODOS OS DEVICES
+	e.GET("/outbox", h.ListOutbox)
+	e.GET("/outbox/stats", h.OutboxStats)
+	e.GET("/outbox/:id", h.GetOutbox)
+	e.POST("/outbox/:id/retry", h.RetryOutbox)
+	e.GET("/webhooks", h.ListWebhooks)
+	e.POST("/webhooks", h.CreateWebhook)
+	e.GET("/webhooks/drift", h.WebhookDrift)
+	e.POST("/webhooks/sync", h.SyncWebhooks)
+	e.GET("/webhooks/:id", h.GetWebhook)
+	e.PUT("/webhooks/:id", h.UpdateWebhook)
+	e.DELETE("/webhooks/:id", h.DeleteWebhook)
+	e.GET("/ratelimits", h.ListRateLimits)
+	e.PUT("/ratelimits", h.UpsertRateLimit)
+	e.DELETE("/ratelimits/:id", h.DeleteRateLimit)
+	e.GET("/usage", h.Usage)
+	e.GET("/quotas", h.ListQuotas)
+	e.PUT("/quotas", h.UpsertQuota)
+	e.DELETE("/quotas/:id", h.DeleteQuota)
 	e.DELETE("/delete", h.DeleteDevice)
-	e.Any("/device/*", echo.WrapHandler(h.Service.ProxyHandler())) //DIRECIONA PARA O CONTAINER CHILD
+	e.POST("/devices/:number/session", h.UpdateDeviceSession) // AVISO DO CHILD (logout, pareamento)
+
+	var proxyMiddleware []echo.MiddlewareFunc
+	if h.Service.Config.RateLimit.Enabled {
+		proxyMiddleware = append(proxyMiddleware, h.Service.Limits.Middleware)
+	}
+	// Depois do rate limit: envio recusado por limite não reserva cota
+	proxyMiddleware = append(proxyMiddleware, h.Service.Usage.Middleware)
+	e.Any("/device/*", echo.WrapHandler(h.Service.ProxyHandler()), proxyMiddleware...) //DIRECIONA PARA O CONTAINER CHILD
 }
 
 func (h *WhatsAppHandler) Dash(c echo.Context) error {
@@ -30,6 +77,11 @@ func (h *WhatsAppHandler) Dash(c echo.Context) error {
 	return c.HTMLBlob(http.StatusOK, h.DashHTML)
 }
 
+// Config retorna a configuração efetiva do master, sem senhas e tokens.
+func (h *WhatsAppHandler) Config(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.Service.Config.Redacted())
+}
+
 func (h *WhatsAppHandler) CreateDevice(c echo.Context) error {
 	var req CreateDeviceRequest
 	if err := c.Bind(&req); err != nil || req.Number == "" {
@@ -38,7 +90,26 @@ func (h *WhatsAppHandler) CreateDevice(c echo.Context) error {
 		})
 	}
 
-	resp, err := h.Service.CreateDevice(req.Number)
+	number, err := phone.Normalize(req.Number)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	opts := whatsapp.DeviceOptions{Tenant: req.Tenant}
+	if req.IdleTimeout != "" {
+		timeout, err := time.ParseDuration(req.IdleTimeout)
+		if err != nil || timeout < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "idle_timeout inválido, use uma duração como \"30m\" (0 nunca para)",
+			})
+		}
+		opts.IdleTimeout = &timeout
+	}
+
+	resp, err := h.Service.CreateDevice(number, opts)
+	if errors.Is(err, whatsapp.ErrTenantLimit) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
@@ -53,7 +124,12 @@ func (h *WhatsAppHandler) DeleteDevice(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "JSON inválido"})
 	}
 
-	if err := h.Service.RemoveDevice(req.Number); err != nil {
+	number, err := phone.Normalize(req.Number)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	if err := h.Service.RemoveDevice(number); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
@@ -70,3 +146,469 @@ func (h *WhatsAppHandler) ListDevices(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, devices)
 }
+
+func (h *WhatsAppHandler) UpdateDeviceSession(c echo.Context) error {
+	number, err := phone.Normalize(c.Param("number"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	// Só o child do device conhece o token (MASTER_TOKEN, assinado com device_secret)
+	token, _ := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if !h.Service.Zap.VerifyDeviceToken(number, token) {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "token do device inválido"})
+	}
+
+	var evt DeviceSessionEvent
+	if err := c.Bind(&evt); err != nil || evt.Data.State == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "JSON inválido"})
+	}
+
+	h.Service.UpdateDeviceSession(number, evt)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *WhatsAppHandler) Events(c echo.Context) error {
+	filter, after := eventhub.ParseRequest(c.Request())
+
+	// ?device= aceita o número em qualquer formato
+	for i, d := range filter.Devices {
+		if number, err := phone.Normalize(d); err == nil {
+			filter.Devices[i] = number
+		}
+	}
+
+	h.Service.Events.Hub().Serve(c.Response(), c.Request(), filter, after)
+	return nil
+}
+
+// outboxError traduz os erros da outbox para o status HTTP.
+func outboxError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrOutboxDisabled):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, ErrOutboxNotFound):
+		status = http.StatusNotFound
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
+
+func (h *WhatsAppHandler) ListOutbox(c echo.Context) error {
+	number := c.QueryParam("number")
+	if number != "" {
+		normalized, err := phone.Normalize(number)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		number = normalized
+	}
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	list, err := h.Service.ListOutbox(c.QueryParam("status"), number, limit)
+	if err != nil {
+		return outboxError(c, err)
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+func (h *WhatsAppHandler) GetOutbox(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "id inválido"})
+	}
+
+	m, err := h.Service.GetOutbox(id)
+	if err != nil {
+		return outboxError(c, err)
+	}
+	return c.JSON(http.StatusOK, m)
+}
+
+func (h *WhatsAppHandler) RetryOutbox(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "id inválido"})
+	}
+
+	if err := h.Service.RetryOutbox(id); err != nil {
+		return outboxError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": OutboxPending})
+}
+
+func (h *WhatsAppHandler) OutboxStats(c echo.Context) error {
+	stats, err := h.Service.OutboxStats()
+	if err != nil {
+		return outboxError(c, err)
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+// webhookError traduz os erros das regras de webhook para o status HTTP.
+func webhookError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrWebhooksDisabled):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, ErrWebhookRuleNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrWebhookRuleExists):
+		status = http.StatusConflict
+	case errors.Is(err, ErrWebhookImport):
+		status = http.StatusBadGateway
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
+
+// bindWebhookRule valida o corpo de criação/edição e normaliza os números.
+func bindWebhookRule(c echo.Context) (WebhookRule, error) {
+	var req WebhookRuleRequest
+	if err := c.Bind(&req); err != nil || req.Device == "" || req.Number == "" || req.Phrase == "" || req.CallbackURL == "" {
+		return WebhookRule{}, errors.New("JSON inválido, envie {\"device\", \"number\", \"phrase\", \"callback_url\"}")
+	}
+
+	device, err := phone.Normalize(req.Device)
+	if err != nil {
+		return WebhookRule{}, fmt.Errorf("device: %w", err)
+	}
+	number, err := phone.Normalize(req.Number)
+	if err != nil {
+		return WebhookRule{}, fmt.Errorf("number: %w", err)
+	}
+	if u, err := url.Parse(req.CallbackURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return WebhookRule{}, errors.New("callback_url deve ser uma URL http(s)")
+	}
+
+	return WebhookRule{Device: device, Number: number, Phrase: req.Phrase, CallbackURL: req.CallbackURL}, nil
+}
+
+// deviceQuery normaliza o ?device= opcional.
+func deviceQuery(c echo.Context) (string, error) {
+	device := c.QueryParam("device")
+	if device == "" {
+		return "", nil
+	}
+	return phone.Normalize(device)
+}
+
+func (h *WhatsAppHandler) ListWebhooks(c echo.Context) error {
+	device, err := deviceQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	list, err := h.Service.ListWebhooks(device)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+func (h *WhatsAppHandler) CreateWebhook(c echo.Context) error {
+	rule, err := bindWebhookRule(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	resp, err := h.Service.CreateWebhook(rule)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func (h *WhatsAppHandler) GetWebhook(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "id inválido"})
+	}
+
+	rule, err := h.Service.GetWebhook(id)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(http.StatusOK, rule)
+}
+
+func (h *WhatsAppHandler) UpdateWebhook(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "id inválido"})
+	}
+	rule, err := bindWebhookRule(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	rule.ID = id
+
+	resp, err := h.Service.UpdateWebhook(rule)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *WhatsAppHandler) DeleteWebhook(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "id inválido"})
+	}
+
+	resp, err := h.Service.DeleteWebhook(id)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *WhatsAppHandler) WebhookDrift(c echo.Context) error {
+	device, err := deviceQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	report, err := h.Service.WebhookDrift(device)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+func (h *WhatsAppHandler) SyncWebhooks(c echo.Context) error {
+	device, err := deviceQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	report, err := h.Service.SyncWebhooks(device)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// rateLimitError traduz os erros dos limites gravados para o status HTTP.
+func rateLimitError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrRateLimitsDisabled):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, ErrRateLimitNotFound):
+		status = http.StatusNotFound
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
+
+// bindRateLimit valida o corpo de PUT /ratelimits e normaliza o número do device.
+func bindRateLimit(c echo.Context) (RateLimitRule, error) {
+	var req RateLimitRequest
+	if err := c.Bind(&req); err != nil || req.Scope == "" || req.Subject == "" || req.Budget == "" {
+		return RateLimitRule{}, errors.New("JSON inválido, envie {\"scope\", \"subject\", \"budget\", \"per_minute\", \"burst\"}")
+	}
+
+	switch req.Scope {
+	case ratelimit.ScopeDevice, ratelimit.ScopeTenant, ratelimit.ScopeKey:
+	default:
+		return RateLimitRule{}, errors.New("scope deve ser device, tenant ou key")
+	}
+	if req.Budget != ratelimit.BudgetSend && req.Budget != ratelimit.BudgetRead {
+		return RateLimitRule{}, errors.New("budget deve ser send ou read")
+	}
+	if req.Scope == ratelimit.ScopeKey && req.Subject == ratelimit.AnySubject {
+		return RateLimitRule{}, errors.New("scope key exige uma API key no subject (não há padrão para keys)")
+	}
+	if req.Scope == ratelimit.ScopeDevice && req.Subject != ratelimit.AnySubject {
+		number, err := phone.Normalize(req.Subject)
+		if err != nil {
+			return RateLimitRule{}, fmt.Errorf("subject: %w", err)
+		}
+		req.Subject = number
+	}
+	if req.PerMinute < 0 {
+		return RateLimitRule{}, errors.New("per_minute não pode ser negativo (0 tira o limite)")
+	}
+	if req.PerMinute == 0 {
+		req.Burst = max(req.Burst, 1)
+	}
+	if req.Burst <= 0 {
+		return RateLimitRule{}, errors.New("burst deve ser maior que zero")
+	}
+
+	return RateLimitRule{Scope: req.Scope, Subject: req.Subject, Budget: req.Budget, PerMinute: req.PerMinute, Burst: req.Burst}, nil
+}
+
+func (h *WhatsAppHandler) ListRateLimits(c echo.Context) error {
+	resp, err := h.Service.RateLimits()
+	if err != nil {
+		return rateLimitError(c, err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *WhatsAppHandler) UpsertRateLimit(c echo.Context) error {
+	rule, err := bindRateLimit(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	saved, err := h.Service.UpsertRateLimit(rule)
+	if err != nil {
+		return rateLimitError(c, err)
+	}
+	return c.JSON(http.StatusOK, saved)
+}
+
+func (h *WhatsAppHandler) DeleteRateLimit(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "id inválido"})
+	}
+
+	if err := h.Service.DeleteRateLimit(id); err != nil {
+		return rateLimitError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// Usage retorna o uso de mensagens por período, tenant, device e tipo, em JSON ou
+// CSV (format=csv ou Accept: text/csv). Sem from/to, vale o mês corrente.
+func (h *WhatsAppHandler) Usage(c echo.Context) error {
+	today := h.Service.Usage.Today()
+	to, err := parseDay(c.QueryParam("to"), today)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "to inválido, use AAAA-MM-DD"})
+	}
+	from, err := parseDay(c.QueryParam("from"), time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, to.Location()))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "from inválido, use AAAA-MM-DD"})
+	}
+	if from.After(to) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "from deve ser anterior ou igual a to"})
+	}
+
+	period := c.QueryParam("period")
+	switch period {
+	case "":
+		period = UsagePeriodDay
+	case UsagePeriodDay, UsagePeriodMonth:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "period deve ser day ou month"})
+	}
+
+	filter := UsageFilter{Tenant: c.QueryParam("tenant"), Type: c.QueryParam("type")}
+	if device := c.QueryParam("device"); device != "" {
+		if filter.Device, err = phone.Normalize(device); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+	if filter.Type != "" && !slices.Contains(usageTypes, filter.Type) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "type deve ser text, reply, reaction, edit ou revoke"})
+	}
+
+	report, err := h.Service.UsageReport(from.Format(time.DateOnly), to.Format(time.DateOnly), period, filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	if c.QueryParam("format") == "csv" || strings.Contains(c.Request().Header.Get(echo.HeaderAccept), "text/csv") {
+		return writeUsageCSV(c, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// parseDay lê uma data AAAA-MM-DD; vazia, retorna def.
+func parseDay(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	return time.ParseInLocation(time.DateOnly, value, def.Location())
+}
+
+func writeUsageCSV(c echo.Context, report UsageReport) error {
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	resp.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"usage-%s-%s.csv\"", report.From, report.To))
+	resp.WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(resp)
+	w.Write([]string{"period", "tenant", "device", "type", "count"})
+	for _, row := range report.Rows {
+		w.Write([]string{row.Period, row.Tenant, row.Device, row.Type, strconv.FormatInt(row.Count, 10)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// quotaError traduz os erros das cotas gravadas para o status HTTP.
+func quotaError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrQuotasDisabled):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, ErrQuotaNotFound):
+		status = http.StatusNotFound
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
+
+// bindQuota valida o corpo de PUT /quotas e normaliza o número do device.
+func bindQuota(c echo.Context) (QuotaRule, error) {
+	var req QuotaRequest
+	if err := c.Bind(&req); err != nil || req.Scope == "" || req.Subject == "" {
+		return QuotaRule{}, errors.New("JSON inválido, envie {\"scope\", \"subject\", \"daily\", \"monthly\"}")
+	}
+
+	switch req.Scope {
+	case QuotaScopeTenant:
+	case QuotaScopeDevice:
+		number, err := phone.Normalize(req.Subject)
+		if err != nil {
+			return QuotaRule{}, fmt.Errorf("subject: %w", err)
+		}
+		req.Subject = number
+	default:
+		return QuotaRule{}, errors.New("scope deve ser tenant ou device")
+	}
+	if (req.Daily != nil && *req.Daily < 0) || (req.Monthly != nil && *req.Monthly < 0) {
+		return QuotaRule{}, errors.New("daily e monthly não podem ser negativos (0 tira a cota, null usa o padrão)")
+	}
+
+	return QuotaRule{Scope: req.Scope, Subject: req.Subject, Daily: req.Daily, Monthly: req.Monthly}, nil
+}
+
+func (h *WhatsAppHandler) ListQuotas(c echo.Context) error {
+	resp, err := h.Service.Quotas()
+	if err != nil {
+		return quotaError(c, err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *WhatsAppHandler) UpsertQuota(c echo.Context) error {
+	rule, err := bindQuota(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	saved, err := h.Service.UpsertQuota(rule)
+	if err != nil {
+		return quotaError(c, err)
+	}
+	return c.JSON(http.StatusOK, saved)
+}
+
+func (h *WhatsAppHandler) DeleteQuota(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "id inválido"})
+	}
+
+	if err := h.Service.DeleteQuota(id); err != nil {
+		return quotaError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
+}
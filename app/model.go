@@ -1,7 +1,11 @@
 package app
 
+import "time"
+
 type CreateDeviceRequest struct {
-	Number string `json:"number" validate:"required"`
+	Number      string `json:"number" validate:"required"`
+	Tenant      string `json:"tenant"`
+	IdleTimeout string `json:"idle_timeout"` // sobrescreve idle.timeout do master (ex: "30m"; "0" nunca para)
 }
 
 type DeleteDeviceRequest struct {
@@ -9,12 +13,185 @@ type DeleteDeviceRequest struct {
 }
 
 type CreateDeviceResponse struct {
-	Status   string `json:"status"`
-	Endpoint string `json:"endpoint"`
-	ID       string `json:"id"`
-	WsUrl    string `json:"ws_url"`
+	Status      string `json:"status"`
+	Endpoint    string `json:"endpoint"`
+	ID          string `json:"id"`
+	WsUrl       string `json:"ws_url"`
+	Tenant      string `json:"tenant,omitempty"`
+	IdleTimeout string `json:"idle_timeout,omitempty"`
 }
 
 type DeleteDeviceResponse struct {
 	Status string `json:"status"`
 }
+
+// DeviceSessionEvent é o aviso enviado pelo child quando a sessão do device muda.
+type DeviceSessionEvent struct {
+	Type      string    `json:"type"`
+	Device    string    `json:"device"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      struct {
+		State  string `json:"state"`
+		Reason string `json:"reason"`
+	} `json:"data"`
+}
+
+// OutboxMessage é uma linha de whats_webhook: mensagem enviada pelo device Number para Recipient.
+type OutboxMessage struct {
+	ID            int64      `json:"id"`
+	Number        string     `json:"number"`
+	Recipient     string     `json:"recipient"`
+	Message       string     `json:"message"`
+	CallbackURL   string     `json:"callback_url,omitempty"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	Error         string     `json:"error,omitempty"`
+	MessageID     string     `json:"message_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	ProcessedAt   *time.Time `json:"processed_at,omitempty"`
+}
+
+// OutboxStats resume o processamento da outbox (GET /outbox/stats).
+type OutboxStats struct {
+	Listening bool             `json:"listening"` // LISTEN ativo; sem ele só o polling processa
+	LastRunAt *time.Time       `json:"last_run_at,omitempty"`
+	Counts    map[string]int64 `json:"counts"`
+}
+
+// WebhookRule é uma regra de webhook gravada no banco: quando Number envia Phrase
+// para o Device, o child chama CallbackURL.
+type WebhookRule struct {
+	ID          int64     `json:"id"`
+	Device      string    `json:"device"`
+	Number      string    `json:"number"`
+	Phrase      string    `json:"phrase"`
+	CallbackURL string    `json:"callback_url"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type WebhookRuleRequest struct {
+	Device      string `json:"device" validate:"required"`
+	Number      string `json:"number" validate:"required"`
+	Phrase      string `json:"phrase" validate:"required"`
+	CallbackURL string `json:"callback_url" validate:"required"`
+}
+
+// WebhookRuleResponse traz a regra e o resultado do envio para o child.
+type WebhookRuleResponse struct {
+	Rule      WebhookRule `json:"rule"`
+	Synced    bool        `json:"synced"`
+	SyncError string      `json:"sync_error,omitempty"`
+}
+
+// WebhookDrift compara as regras gravadas com as ativas no child de um device.
+type WebhookDrift struct {
+	Device   string              `json:"device"`
+	InSync   bool                `json:"in_sync"`
+	Stored   int                 `json:"stored"`
+	Active   int                 `json:"active"`
+	Missing  []WebhookRuleTarget `json:"missing,omitempty"` // gravadas mas ausentes no child
+	Extra    []WebhookRuleTarget `json:"extra,omitempty"`   // ativas no child mas não gravadas
+	Error    string              `json:"error,omitempty"`   // child inacessível
+	SyncedAt *time.Time          `json:"synced_at,omitempty"`
+}
+
+// WebhookRuleTarget é a parte da regra que o child conhece.
+type WebhookRuleTarget struct {
+	Number      string `json:"number"`
+	Phrase      string `json:"phrase"`
+	CallbackURL string `json:"callback_url"`
+}
+
+// RateLimitRule é um limite do proxy: uma linha de whats_rate_limit ou um padrão da configuração.
+type RateLimitRule struct {
+	ID        int64     `json:"id,omitempty"`
+	Scope     string    `json:"scope"`   // device, tenant ou key
+	Subject   string    `json:"subject"` // número, tenant, API key ou "*" (padrão do escopo)
+	Budget    string    `json:"budget"`  // send ou read
+	PerMinute float64   `json:"per_minute"`
+	Burst     int       `json:"burst"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+type RateLimitRequest struct {
+	Scope     string  `json:"scope" validate:"required"`
+	Subject   string  `json:"subject" validate:"required"`
+	Budget    string  `json:"budget" validate:"required"`
+	PerMinute float64 `json:"per_minute"` // 0 tira o limite do assunto
+	Burst     int     `json:"burst"`
+}
+
+// RateLimitsResponse traz os padrões da configuração e as regras gravadas, que os sobrescrevem.
+type RateLimitsResponse struct {
+	Enabled  bool            `json:"enabled"`
+	Defaults []RateLimitRule `json:"defaults"`
+	Rules    []RateLimitRule `json:"rules"`
+	LoadedAt *time.Time      `json:"loaded_at,omitempty"` // última leitura das regras do banco
+}
+
+// UsageRow é o total de mensagens enviadas de um tipo por um device num período
+// (dia "2026-10-19" ou mês "2026-10").
+type UsageRow struct {
+	Period string `json:"period"`
+	Tenant string `json:"tenant"`
+	Device string `json:"device"`
+	Type   string `json:"type"` // text, reply, reaction, edit ou revoke
+	Count  int64  `json:"count"`
+}
+
+// UsageFilter restringe o relatório de /usage; campos vazios não filtram.
+type UsageFilter struct {
+	Tenant string
+	Device string
+	Type   string
+}
+
+type UsageReport struct {
+	From   string     `json:"from"`
+	To     string     `json:"to"`
+	Period string     `json:"period"` // day ou month
+	Total  int64      `json:"total"`
+	Rows   []UsageRow `json:"rows"`
+}
+
+// QuotaRule é uma cota de mensagens: uma linha de whats_quota ou um padrão da configuração.
+// nil usa o padrão do escopo; 0 tira a cota.
+type QuotaRule struct {
+	ID        int64     `json:"id,omitempty"`
+	Scope     string    `json:"scope"`   // tenant ou device
+	Subject   string    `json:"subject"` // tenant, número ou "*" (padrão do escopo)
+	Daily     *int64    `json:"daily"`
+	Monthly   *int64    `json:"monthly"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+type QuotaRequest struct {
+	Scope   string `json:"scope" validate:"required"`
+	Subject string `json:"subject" validate:"required"`
+	Daily   *int64 `json:"daily"`
+	Monthly *int64 `json:"monthly"`
+}
+
+// QuotaUsage é o consumo atual de um assunto com as cotas que valem para ele.
+type QuotaUsage struct {
+	Scope        string `json:"scope"`
+	Subject      string `json:"subject"`
+	Daily        int64  `json:"daily"`
+	DailyLimit   int64  `json:"daily_limit"` // 0 sem cota
+	Monthly      int64  `json:"monthly"`
+	MonthlyLimit int64  `json:"monthly_limit"`
+}
+
+// QuotasResponse traz os padrões da configuração, as cotas gravadas e o consumo atual.
+type QuotasResponse struct {
+	Enabled  bool         `json:"enabled"`
+	Timezone string       `json:"timezone"`
+	Defaults []QuotaRule  `json:"defaults"`
+	Rules    []QuotaRule  `json:"rules"`
+	Usage    []QuotaUsage `json:"usage"`
+	LoadedAt *time.Time   `json:"loaded_at,omitempty"` // última leitura do banco
+}
@@ -0,0 +1,290 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/simpplify-org/GO-simpzap/pkg/config"
+	"github.com/simpplify-org/GO-simpzap/pkg/logging"
+	"github.com/simpplify-org/GO-simpzap/pkg/phone"
+	"github.com/simpplify-org/GO-simpzap/pkg/tracing"
+	"github.com/simpplify-org/GO-simpzap/pkg/whatsapp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	outboxChannel   = "whatsapp_webhook_channel" // canal do trigger TRI_whatsapp_webhook_notify
+	outboxRetryBase = 30 * time.Second
+	outboxRetryMax  = 30 * time.Minute
+)
+
+// errPermanent marca falhas que não adianta repetir (número inválido, payload ruim).
+var errPermanent = errors.New("falha permanente")
+
+// OutboxWorker envia as mensagens inseridas em whats_webhook pelo device de cada linha.
+// Acorda com o NOTIFY do trigger e também faz polling (cfg.PollInterval), para não
+// perder linhas inseridas enquanto o master estava fora.
+type OutboxWorker struct {
+	repo   *WebhookRepository
+	zap    *whatsapp.ZapPkg
+	usage  *UsageMeter
+	dsn    string
+	cfg    config.Outbox
+	client *http.Client
+	log    *slog.Logger
+
+	mu        sync.RWMutex
+	listening bool
+	lastRunAt time.Time
+}
+
+func NewOutboxWorker(repo *WebhookRepository, zap *whatsapp.ZapPkg, usage *UsageMeter, dsn string, cfg config.Outbox) *OutboxWorker {
+	return &OutboxWorker{
+		repo:   repo,
+		zap:    zap,
+		usage:  usage,
+		dsn:    dsn,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.RequestTimeout.Std()},
+		log:    slog.Default().With("component", "outbox"),
+	}
+}
+
+// Run processa a outbox até ctx ser cancelado.
+func (w *OutboxWorker) Run(ctx context.Context) {
+	listener := pq.NewListener(w.dsn, 2*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventConnected, pq.ListenerEventReconnected:
+			w.setListening(true)
+		case pq.ListenerEventDisconnected, pq.ListenerEventConnectionAttemptFailed:
+			w.setListening(false)
+			if err != nil {
+				w.log.Warn("LISTEN indisponível", "error", err)
+			}
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(outboxChannel); err != nil {
+		w.log.Warn("Erro no LISTEN, seguindo só com polling", "channel", outboxChannel, "error", err)
+	} else {
+		w.log.Info("Escutando canal da outbox", "channel", outboxChannel)
+	}
+
+	ticker := time.NewTicker(w.cfg.PollInterval.Std())
+	defer ticker.Stop()
+
+	for {
+		w.drain(ctx)
+		select {
+		case <-listener.Notify:
+			// O payload não importa: qualquer aviso (ou nil após reconexão) dispara uma varredura
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Stats retorna o estado do worker e os totais por status.
+func (w *OutboxWorker) Stats(ctx context.Context) (OutboxStats, error) {
+	counts, err := w.repo.CountOutboxByStatus(ctx)
+	if err != nil {
+		return OutboxStats{}, err
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	stats := OutboxStats{Listening: w.listening, Counts: counts}
+	if !w.lastRunAt.IsZero() {
+		lastRun := w.lastRunAt
+		stats.LastRunAt = &lastRun
+	}
+	return stats, nil
+}
+
+func (w *OutboxWorker) setListening(v bool) {
+	w.mu.Lock()
+	w.listening = v
+	w.mu.Unlock()
+}
+
+// drain reserva e envia lotes até a fila esvaziar. Cada device envia em sequência
+// (preservando a ordem das linhas); devices diferentes enviam em paralelo.
+func (w *OutboxWorker) drain(ctx context.Context) {
+	w.mu.Lock()
+	w.lastRunAt = time.Now()
+	w.mu.Unlock()
+
+	for ctx.Err() == nil {
+		batch, err := w.repo.ClaimOutbox(ctx, w.cfg.MaxAttempts, w.cfg.BatchSize)
+		if err != nil {
+			w.log.Error("Erro ao buscar pendentes", "error", err)
+			return
+		}
+		if len(batch) == 0 {
+			return
+		}
+
+		byDevice := make(map[string][]OutboxMessage)
+		for _, m := range batch {
+			byDevice[m.Number] = append(byDevice[m.Number], m)
+		}
+
+		// Linhas já reservadas terminam mesmo com o master encerrando; o Shutdown espera
+		// o lote até o prazo. O próximo lote não é reservado depois do cancelamento.
+		sendCtx := context.WithoutCancel(ctx)
+		var wg sync.WaitGroup
+		for _, msgs := range byDevice {
+			wg.Add(1)
+			go func(msgs []OutboxMessage) {
+				defer wg.Done()
+				for _, m := range msgs {
+					w.process(sendCtx, m)
+				}
+			}(msgs)
+		}
+		wg.Wait()
+	}
+}
+
+// process envia uma linha e grava o resultado.
+func (w *OutboxWorker) process(ctx context.Context, m OutboxMessage) {
+	// Cada linha vira uma requisição própria para o child, correlacionada pelo request_id
+	ctx = logging.WithRequestID(ctx, logging.NewRequestID())
+	ctx, span := tracing.Start(ctx, "outbox.send", trace.WithAttributes(attribute.Int64("outbox.id", m.ID)))
+	defer span.End()
+
+	messageID, err := w.send(ctx, m)
+	if err == nil {
+		if err := w.repo.MarkOutboxSent(ctx, m.ID, messageID); err != nil {
+			w.log.ErrorContext(ctx, "Erro ao marcar linha como enviada", "outbox_id", m.ID, "error", err)
+		}
+		w.log.InfoContext(ctx, "✅ Linha enviada", "outbox_id", m.ID, "device", m.Number, "message_id", messageID)
+		m.Status, m.MessageID = OutboxSent, messageID
+		w.notify(ctx, m)
+		return
+	}
+
+	var next *time.Time
+	if !errors.Is(err, errPermanent) && m.Attempts < w.cfg.MaxAttempts {
+		at := time.Now().Add(outboxRetryDelay(m.Attempts))
+		next = &at
+	}
+	span.RecordError(err)
+	if err := w.repo.MarkOutboxFailed(ctx, m.ID, err.Error(), next); err != nil {
+		w.log.ErrorContext(ctx, "Erro ao marcar linha como falha", "outbox_id", m.ID, "error", err)
+	}
+
+	m.Status, m.Error = OutboxFailed, err.Error()
+	if next == nil {
+		w.log.ErrorContext(ctx, "❌ Linha falhou de vez", "outbox_id", m.ID, "device", m.Number, "attempts", m.Attempts, "error", err)
+		w.notify(ctx, m)
+	} else {
+		w.log.WarnContext(ctx, "⚠️ Linha falhou, nova tentativa agendada", "outbox_id", m.ID, "device", m.Number, "attempts", m.Attempts, "next_attempt_at", *next, "error", err)
+	}
+}
+
+// send encaminha a linha para o /send do child do device.
+func (w *OutboxWorker) send(ctx context.Context, m OutboxMessage) (id string, err error) {
+	number, err := phone.Normalize(m.Number)
+	if err != nil {
+		return "", fmt.Errorf("%w: device: %v", errPermanent, err)
+	}
+	if strings.TrimSpace(m.Recipient) == "" {
+		return "", fmt.Errorf("%w: recipient não informado", errPermanent)
+	}
+
+	// Cota esgotada não se resolve nas próximas tentativas; a linha pode ser reenviada
+	// com /outbox/{id}/retry depois que a cota renovar
+	res, err := w.usage.Reserve(w.zap.DeviceTenant(number), number, 1)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	defer func() {
+		var sent int64
+		if err == nil {
+			sent = 1
+		}
+		res.Commit(UsageText, sent)
+	}()
+
+	// Acorda o device parado por inatividade antes de enviar
+	endpoint, release, err := w.zap.Acquire(ctx, number)
+	if err != nil {
+		return "", fmt.Errorf("device %s: %w", number, err)
+	}
+	defer release()
+
+	body, _ := json.Marshal(map[string]string{"number": m.Recipient, "message": m.Message})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/send", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	switch {
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return "", fmt.Errorf("%w: %d %s", errPermanent, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("child respondeu %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var sent struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(respBody, &sent)
+	return sent.ID, nil
+}
+
+// notify avisa o resultado final em callback_url, se informado.
+func (w *OutboxWorker) notify(ctx context.Context, m OutboxMessage) {
+	if m.CallbackURL == "" {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		payload, _ := json.Marshal(m)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.CallbackURL, bytes.NewReader(payload))
+		if err != nil {
+			w.log.WarnContext(ctx, "Erro no callback da linha", "outbox_id", m.ID, "error", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		tracing.Inject(ctx, req.Header)
+		resp, err := w.client.Do(req)
+		if err != nil {
+			w.log.WarnContext(ctx, "Erro no callback da linha", "outbox_id", m.ID, "error", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
+
+// outboxRetryDelay dobra a espera a cada tentativa, até outboxRetryMax.
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := outboxRetryBase
+	for i := 1; i < attempts && delay < outboxRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxRetryMax)
+}
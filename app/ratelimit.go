@@ -0,0 +1,278 @@
+package app
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/simpplify-org/GO-simpzap/pkg/config"
+	"github.com/simpplify-org/GO-simpzap/pkg/phone"
+	"github.com/simpplify-org/GO-simpzap/pkg/ratelimit"
+	"github.com/simpplify-org/GO-simpzap/pkg/whatsapp"
+)
+
+// HeaderAPIKey identifica o cliente da API para o rate limit. Não é autenticada: só conta
+// quando a key tem regra própria em whats_rate_limit, e sempre junto com o tenant do device.
+const HeaderAPIKey = "X-API-Key"
+
+var ErrRateLimitsDisabled = errors.New("limites gravados desativados: banco de dados não configurado")
+
+// RateLimiter aplica os limites do proxy /device/{number}/.... Os padrões vêm da
+// configuração e as regras de whats_rate_limit os sobrescrevem; se o banco ficar
+// fora, continuam valendo as últimas regras lidas (ou só os padrões).
+type RateLimiter struct {
+	limiter *ratelimit.Limiter
+	repo    *WebhookRepository // nil quando o master roda sem banco
+	zap     *whatsapp.ZapPkg
+	cfg     config.RateLimit
+	log     *slog.Logger
+
+	mu       sync.Mutex
+	loadedAt time.Time // última leitura bem-sucedida do banco
+}
+
+func NewRateLimiter(cfg config.RateLimit, repo *WebhookRepository, zap *whatsapp.ZapPkg) *RateLimiter {
+	rl := &RateLimiter{
+		limiter: ratelimit.New(),
+		repo:    repo,
+		zap:     zap,
+		cfg:     cfg,
+		log:     slog.Default().With("component", "ratelimit"),
+	}
+	rl.limiter.SetRules(rl.defaultRules())
+	return rl
+}
+
+// defaultRules converte os limites da configuração em regras "*" de cada escopo. API keys
+// não têm padrão: qualquer um pode inventar uma key nova e ganhar um bucket cheio.
+func (rl *RateLimiter) defaultRules() map[ratelimit.Key]config.Limit {
+	rules := map[ratelimit.Key]config.Limit{}
+	set := func(scope, budget string, limit config.Limit) {
+		if !limit.Unlimited() {
+			rules[ratelimit.Key{Scope: scope, Subject: ratelimit.AnySubject, Budget: budget}] = limit
+		}
+	}
+	set(ratelimit.ScopeDevice, ratelimit.BudgetSend, rl.cfg.DeviceSend)
+	set(ratelimit.ScopeDevice, ratelimit.BudgetRead, rl.cfg.DeviceRead)
+	set(ratelimit.ScopeTenant, ratelimit.BudgetSend, rl.cfg.ClientSend)
+	set(ratelimit.ScopeTenant, ratelimit.BudgetRead, rl.cfg.ClientRead)
+	return rules
+}
+
+// Reload relê as regras do banco e as aplica sobre os padrões.
+func (rl *RateLimiter) Reload(ctx context.Context) error {
+	if rl.repo == nil {
+		return nil
+	}
+	rows, err := rl.repo.ListRateLimits(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules := rl.defaultRules()
+	for _, row := range rows {
+		key := ratelimit.Key{Scope: row.Scope, Subject: row.Subject, Budget: row.Budget}
+		// per_minute 0 fica no mapa: sobrescreve o padrão do escopo e tira o limite
+		rules[key] = config.Limit{PerMinute: row.PerMinute, Burst: row.Burst}
+	}
+	rl.limiter.SetRules(rules)
+
+	rl.mu.Lock()
+	rl.loadedAt = time.Now()
+	rl.mu.Unlock()
+	return nil
+}
+
+// Run relê as regras a cada rate_limit.reload_interval até ctx ser cancelado.
+func (rl *RateLimiter) Run(ctx context.Context) {
+	if err := rl.Reload(ctx); err != nil {
+		rl.log.Warn("Erro ao ler limites do banco, usando os padrões da configuração", "error", err)
+	}
+
+	ticker := time.NewTicker(rl.cfg.ReloadInterval.Std())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := rl.Reload(ctx); err != nil {
+				rl.log.Warn("Erro ao reler limites do banco, mantendo os atuais", "error", err)
+			}
+			rl.limiter.Sweep()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Middleware limita as requisições de /device/{number}/... antes do proxy. Envios
+// (/send e /send/*) e leituras têm orçamentos separados, por device, por tenant e,
+// para API keys com regra gravada, também por key.
+func (rl *RateLimiter) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r := c.Request()
+		device, route, ok := proxiedRoute(r.URL.Path)
+		if !ok {
+			return next(c) // número inválido: o proxy responde 400
+		}
+
+		budget, cost := ratelimit.BudgetRead, 1
+		if isSendRoute(route) {
+			budget, cost = ratelimit.BudgetSend, sendCost(c, route)
+		}
+
+		keys := []ratelimit.Key{{Scope: ratelimit.ScopeDevice, Subject: device, Budget: budget}}
+		if tenant := rl.zap.DeviceTenant(device); tenant != "" {
+			keys = append(keys, ratelimit.Key{Scope: ratelimit.ScopeTenant, Subject: tenant, Budget: budget})
+		}
+		// A key é um bucket a mais, nunca substitui o do tenant: trocar de key não zera o limite
+		if apiKey := r.Header.Get(HeaderAPIKey); apiKey != "" {
+			key := ratelimit.Key{Scope: ratelimit.ScopeKey, Subject: apiKey, Budget: budget}
+			if rl.limiter.HasRule(key) {
+				keys = append(keys, key)
+			}
+		}
+
+		d := rl.limiter.Allow(cost, keys...)
+		d.SetHeaders(c.Response().Header())
+		if d.TooLarge {
+			// Esperar não adianta: o lote passa da rajada e precisa ser dividido
+			return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{
+				"error": fmt.Sprintf("lote de %d envios maior que o limite (%s): máximo de %d por requisição", cost, scopeName(d.Key.Scope), d.Limit),
+			})
+		}
+		if !d.Allowed {
+			return c.JSON(http.StatusTooManyRequests, map[string]string{
+				"error": fmt.Sprintf("limite de %s excedido (%s), tente novamente em %ds", budgetName(budget), scopeName(d.Key.Scope), d.RetryAfterSeconds()),
+			})
+		}
+		return next(c)
+	}
+}
+
+// Rules retorna os padrões da configuração e as regras gravadas (API keys mascaradas).
+func (rl *RateLimiter) Rules(ctx context.Context) (RateLimitsResponse, error) {
+	resp := RateLimitsResponse{Enabled: rl.cfg.Enabled, Defaults: []RateLimitRule{}, Rules: []RateLimitRule{}}
+	for key, limit := range rl.defaultRules() {
+		resp.Defaults = append(resp.Defaults, RateLimitRule{
+			Scope: key.Scope, Subject: key.Subject, Budget: key.Budget, PerMinute: limit.PerMinute, Burst: limit.Burst,
+		})
+	}
+	slices.SortFunc(resp.Defaults, func(a, b RateLimitRule) int {
+		return cmp.Or(cmp.Compare(a.Scope, b.Scope), cmp.Compare(a.Budget, b.Budget))
+	})
+
+	if rl.repo == nil {
+		return resp, nil
+	}
+	rows, err := rl.repo.ListRateLimits(ctx)
+	if err != nil {
+		return resp, err
+	}
+	for _, row := range rows {
+		resp.Rules = append(resp.Rules, maskRateLimit(row))
+	}
+
+	rl.mu.Lock()
+	if !rl.loadedAt.IsZero() {
+		loadedAt := rl.loadedAt
+		resp.LoadedAt = &loadedAt
+	}
+	rl.mu.Unlock()
+	return resp, nil
+}
+
+// proxiedRoute separa /device/{number}/resto em número normalizado e rota do child.
+func proxiedRoute(path string) (device, route string, ok bool) {
+	rest, found := strings.CutPrefix(path, "/device/")
+	if !found {
+		return "", "", false
+	}
+	raw, route, _ := strings.Cut(rest, "/")
+	device, err := phone.Normalize(raw)
+	if err != nil {
+		return "", "", false
+	}
+	return device, "/" + route, true
+}
+
+func isSendRoute(route string) bool {
+	return route == "/send" || strings.HasPrefix(route, "/send/")
+}
+
+// sendCost é o sendCount da requisição, calculado uma vez e guardado no contexto
+// para o rate limit e as cotas.
+func sendCost(c echo.Context, route string) int {
+	if cost, ok := c.Get(sendCostKey).(int); ok {
+		return cost
+	}
+	cost := sendCount(c.Request(), route)
+	c.Set(sendCostKey, cost)
+	return cost
+}
+
+const sendCostKey = "send_cost"
+
+// maxCountedBody limita o corpo lido para contar os destinatários de /send/many.
+const maxCountedBody = 1 << 20
+
+// sendCount conta as mensagens de um envio: uma por número em /send/many e uma nas
+// demais rotas. O corpo lido é devolvido intacto para o proxy.
+func sendCount(r *http.Request, route string) int {
+	if route != "/send/many" || r.Body == nil {
+		return 1
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCountedBody))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil {
+		return 1
+	}
+
+	var req struct {
+		Numbers []string `json:"numbers"`
+	}
+	if json.Unmarshal(body, &req) != nil || len(req.Numbers) == 0 {
+		return 1
+	}
+	return len(req.Numbers)
+}
+
+func budgetName(budget string) string {
+	if budget == ratelimit.BudgetSend {
+		return "envios"
+	}
+	return "requisições"
+}
+
+func scopeName(scope string) string {
+	switch scope {
+	case ratelimit.ScopeKey:
+		return "API key"
+	case ratelimit.ScopeTenant:
+		return "tenant"
+	default:
+		return "device"
+	}
+}
+
+// maskRateLimit esconde a API key, que não deve aparecer inteira nas respostas.
+func maskRateLimit(rule RateLimitRule) RateLimitRule {
+	if rule.Scope == ratelimit.ScopeKey && rule.Subject != ratelimit.AnySubject {
+		visible := min(4, len(rule.Subject)/2)
+		rule.Subject = rule.Subject[:visible] + "***"
+	}
+	return rule
+}
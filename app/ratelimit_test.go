@@ -0,0 +1,88 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/simpplify-org/GO-simpzap/pkg/config"
+	"github.com/simpplify-org/GO-simpzap/pkg/whatsapp"
+)
+
+func sendManyBody(n int) string {
+	numbers := make([]string, n)
+	for i := range numbers {
+		numbers[i] = fmt.Sprintf("55119%08d", i)
+	}
+	body, _ := json.Marshal(map[string]any{"numbers": numbers, "message": "oi"})
+	return string(body)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	type call struct {
+		numbers    int // 0 = POST /send
+		status     int
+		retryAfter bool
+		errorHas   string
+	}
+	tests := []struct {
+		name  string
+		calls []call
+	}{
+		{
+			name: "lote maior que a rajada não é repetível",
+			calls: []call{
+				{numbers: 11, status: http.StatusRequestEntityTooLarge, errorHas: "máximo de 10"},
+				// A recusa não consome: o lote dentro da rajada ainda passa
+				{numbers: 10, status: http.StatusOK},
+			},
+		},
+		{
+			name: "rajada esgotada pede para esperar",
+			calls: []call{
+				{numbers: 10, status: http.StatusOK},
+				{status: http.StatusTooManyRequests, retryAfter: true, errorHas: "tente novamente"},
+				{numbers: 11, status: http.StatusRequestEntityTooLarge, errorHas: "máximo de 10"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.RateLimit{Enabled: true, DeviceSend: config.Limit{PerMinute: 30, Burst: 10}}
+			rl := NewRateLimiter(cfg, nil, &whatsapp.ZapPkg{})
+			e := echo.New()
+			handler := rl.Middleware(func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+			for i, c := range tt.calls {
+				path, body := "/device/5511999999999/send", `{"number":"5511888888888","message":"oi"}`
+				if c.numbers > 0 {
+					path, body = "/device/5511999999999/send/many", sendManyBody(c.numbers)
+				}
+				req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				if err := handler(e.NewContext(req, rec)); err != nil {
+					t.Fatalf("chamada %d: %v", i, err)
+				}
+
+				if rec.Code != c.status {
+					t.Fatalf("chamada %d: status %d, quer %d (%s)", i, rec.Code, c.status, rec.Body)
+				}
+				if got := rec.Header().Get("Retry-After") != ""; got != c.retryAfter {
+					t.Errorf("chamada %d: Retry-After %q, quer presente=%v", i, rec.Header().Get("Retry-After"), c.retryAfter)
+				}
+				if rec.Header().Get("RateLimit-Limit") != "10" {
+					t.Errorf("chamada %d: RateLimit-Limit %q, quer 10", i, rec.Header().Get("RateLimit-Limit"))
+				}
+				if c.errorHas != "" && !strings.Contains(rec.Body.String(), c.errorHas) {
+					t.Errorf("chamada %d: corpo %s sem %q", i, rec.Body, c.errorHas)
+				}
+			}
+		})
+	}
+}
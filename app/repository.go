@@ -0,0 +1,482 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Status das linhas de whats_webhook (outbox).
+const (
+	OutboxPending    = "pending"
+	OutboxProcessing = "processing"
+	OutboxSent       = "sent"
+	OutboxFailed     = "failed"
+	OutboxLegacy     = "legacy" // linhas anteriores à migration da outbox; nunca são enviadas
+)
+
+var ErrOutboxNotFound = errors.New("mensagem não encontrada na outbox")
+
+// WebhookRepository acessa a tabela whats_webhook (queries em db/queries/whats_webhook.sql).
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const claimOutbox = `
+UPDATE whats_webhook
+SET status = 'processing', attempts = attempts + 1, locked_at = NOW()
+WHERE id IN (
+    SELECT id FROM whats_webhook
+    WHERE (status IN ('pending', 'failed') AND next_attempt_at <= NOW() AND attempts < $1)
+       OR (status = 'processing' AND locked_at < NOW() - INTERVAL '5 minutes')
+    ORDER BY id
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, number, COALESCE(recipient, ''), message, COALESCE(callback_url, ''), attempts`
+
+// ClaimOutbox reserva até limit linhas prontas para envio.
+func (r *WebhookRepository) ClaimOutbox(ctx context.Context, maxAttempts, limit int) ([]OutboxMessage, error) {
+	rows, err := r.db.QueryContext(ctx, claimOutbox, maxAttempts, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []OutboxMessage
+	for rows.Next() {
+		m := OutboxMessage{Status: OutboxProcessing}
+		if err := rows.Scan(&m.ID, &m.Number, &m.Recipient, &m.Message, &m.CallbackURL, &m.Attempts); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+const markOutboxSent = `
+UPDATE whats_webhook
+SET status = 'sent', message_id = $2, error = NULL, locked_at = NULL, processed_at = NOW()
+WHERE id = $1`
+
+func (r *WebhookRepository) MarkOutboxSent(ctx context.Context, id int64, messageID string) error {
+	_, err := r.db.ExecContext(ctx, markOutboxSent, id, messageID)
+	return err
+}
+
+const markOutboxFailed = `
+UPDATE whats_webhook
+SET status = 'failed', error = $2, locked_at = NULL, next_attempt_at = $3, processed_at = NOW()
+WHERE id = $1`
+
+// MarkOutboxFailed registra a falha; nextAttempt nil encerra as tentativas.
+func (r *WebhookRepository) MarkOutboxFailed(ctx context.Context, id int64, errMsg string, nextAttempt *time.Time) error {
+	_, err := r.db.ExecContext(ctx, markOutboxFailed, id, errMsg, nextAttempt)
+	return err
+}
+
+const retryOutbox = `
+UPDATE whats_webhook
+SET status = 'pending', attempts = 0, error = NULL, next_attempt_at = NOW()
+WHERE id = $1 AND status = 'failed'`
+
+// RetryOutbox recoloca uma linha com falha na fila.
+func (r *WebhookRepository) RetryOutbox(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, retryOutbox, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrOutboxNotFound
+	}
+	return nil
+}
+
+const outboxColumns = `id, number, COALESCE(recipient, ''), message, COALESCE(callback_url, ''), status, attempts,
+       COALESCE(error, ''), COALESCE(message_id, ''), created_at, next_attempt_at, processed_at`
+
+func (r *WebhookRepository) GetOutbox(ctx context.Context, id int64) (OutboxMessage, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+outboxColumns+` FROM whats_webhook WHERE id = $1`, id)
+	m, err := scanOutbox(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return m, ErrOutboxNotFound
+	}
+	return m, err
+}
+
+const listOutbox = `SELECT ` + outboxColumns + `
+FROM whats_webhook
+WHERE ($1 = '' OR status = $1) AND ($2 = '' OR number = $2)
+ORDER BY id DESC
+LIMIT $3`
+
+// ListOutbox lista as linhas mais recentes, filtrando por status e device (vazios ignoram).
+func (r *WebhookRepository) ListOutbox(ctx context.Context, status, number string, limit int) ([]OutboxMessage, error) {
+	rows, err := r.db.QueryContext(ctx, listOutbox, status, number, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []OutboxMessage{}
+	for rows.Next() {
+		m, err := scanOutbox(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// CountOutboxByStatus retorna o total de linhas por status.
+func (r *WebhookRepository) CountOutboxByStatus(ctx context.Context) (map[string]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM whats_webhook GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int64{}
+	for rows.Next() {
+		var status string
+		var n int64
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+func scanOutbox(row interface{ Scan(...any) error }) (OutboxMessage, error) {
+	var m OutboxMessage
+	var nextAttempt, processedAt sql.NullTime
+	err := row.Scan(&m.ID, &m.Number, &m.Recipient, &m.Message, &m.CallbackURL, &m.Status, &m.Attempts,
+		&m.Error, &m.MessageID, &m.CreatedAt, &nextAttempt, &processedAt)
+	if nextAttempt.Valid {
+		m.NextAttemptAt = &nextAttempt.Time
+	}
+	if processedAt.Valid {
+		m.ProcessedAt = &processedAt.Time
+	}
+	return m, err
+}
+
+var (
+	ErrWebhookRuleNotFound = errors.New("regra de webhook não encontrada")
+	ErrWebhookRuleExists   = errors.New("regra de webhook já cadastrada para este device")
+)
+
+// webhookRuleError traduz a violação do índice único para ErrWebhookRuleExists.
+func webhookRuleError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrWebhookRuleExists
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWebhookRuleNotFound
+	}
+	return err
+}
+
+const webhookRuleColumns = `id, device, number, phrase, callback_url, created_at, updated_at`
+
+func scanWebhookRule(row interface{ Scan(...any) error }) (WebhookRule, error) {
+	var rule WebhookRule
+	err := row.Scan(&rule.ID, &rule.Device, &rule.Number, &rule.Phrase, &rule.CallbackURL, &rule.CreatedAt, &rule.UpdatedAt)
+	return rule, err
+}
+
+const createWebhookRule = `
+INSERT INTO whats_webhook_rule (device, number, phrase, callback_url)
+VALUES ($1, $2, $3, $4)
+RETURNING ` + webhookRuleColumns
+
+func (r *WebhookRepository) CreateWebhookRule(ctx context.Context, rule WebhookRule) (WebhookRule, error) {
+	row := r.db.QueryRowContext(ctx, createWebhookRule, rule.Device, rule.Number, rule.Phrase, rule.CallbackURL)
+	created, err := scanWebhookRule(row)
+	return created, webhookRuleError(err)
+}
+
+const updateWebhookRule = `
+UPDATE whats_webhook_rule
+SET device = $2, number = $3, phrase = $4, callback_url = $5, updated_at = NOW()
+WHERE id = $1
+RETURNING ` + webhookRuleColumns
+
+func (r *WebhookRepository) UpdateWebhookRule(ctx context.Context, rule WebhookRule) (WebhookRule, error) {
+	row := r.db.QueryRowContext(ctx, updateWebhookRule, rule.ID, rule.Device, rule.Number, rule.Phrase, rule.CallbackURL)
+	updated, err := scanWebhookRule(row)
+	return updated, webhookRuleError(err)
+}
+
+func (r *WebhookRepository) GetWebhookRule(ctx context.Context, id int64) (WebhookRule, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+webhookRuleColumns+` FROM whats_webhook_rule WHERE id = $1`, id)
+	rule, err := scanWebhookRule(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return rule, ErrWebhookRuleNotFound
+	}
+	return rule, err
+}
+
+// DeleteWebhookRule remove a regra e retorna o device dela, para reenviar as regras restantes.
+func (r *WebhookRepository) DeleteWebhookRule(ctx context.Context, id int64) (string, error) {
+	var device string
+	err := r.db.QueryRowContext(ctx, `DELETE FROM whats_webhook_rule WHERE id = $1 RETURNING device`, id).Scan(&device)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrWebhookRuleNotFound
+	}
+	return device, err
+}
+
+const listWebhookRules = `SELECT ` + webhookRuleColumns + `
+FROM whats_webhook_rule
+WHERE ($1 = '' OR device = $1)
+ORDER BY device, id`
+
+// ListWebhookRules lista as regras de um device (vazio lista todas).
+func (r *WebhookRepository) ListWebhookRules(ctx context.Context, device string) ([]WebhookRule, error) {
+	rows, err := r.db.QueryContext(ctx, listWebhookRules, device)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []WebhookRule{}
+	for rows.Next() {
+		rule, err := scanWebhookRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, rule)
+	}
+	return list, rows.Err()
+}
+
+// ListWebhookRuleDevices retorna os devices que têm ao menos uma regra.
+func (r *WebhookRepository) ListWebhookRuleDevices(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT device FROM whats_webhook_rule ORDER BY device`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []string
+	for rows.Next() {
+		var device string
+		if err := rows.Scan(&device); err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+	return devices, rows.Err()
+}
+
+var ErrRateLimitNotFound = errors.New("limite não encontrado")
+
+const rateLimitColumns = `id, scope, subject, budget, per_minute, burst, created_at, updated_at`
+
+func scanRateLimit(row interface{ Scan(...any) error }) (RateLimitRule, error) {
+	var rule RateLimitRule
+	err := row.Scan(&rule.ID, &rule.Scope, &rule.Subject, &rule.Budget, &rule.PerMinute, &rule.Burst, &rule.CreatedAt, &rule.UpdatedAt)
+	return rule, err
+}
+
+const upsertRateLimit = `
+INSERT INTO whats_rate_limit (scope, subject, budget, per_minute, burst)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (scope, subject, budget)
+DO UPDATE SET per_minute = EXCLUDED.per_minute, burst = EXCLUDED.burst, updated_at = NOW()
+RETURNING ` + rateLimitColumns
+
+// UpsertRateLimit grava o limite do assunto, substituindo o anterior do mesmo escopo e orçamento.
+func (r *WebhookRepository) UpsertRateLimit(ctx context.Context, rule RateLimitRule) (RateLimitRule, error) {
+	row := r.db.QueryRowContext(ctx, upsertRateLimit, rule.Scope, rule.Subject, rule.Budget, rule.PerMinute, rule.Burst)
+	return scanRateLimit(row)
+}
+
+func (r *WebhookRepository) ListRateLimits(ctx context.Context) ([]RateLimitRule, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+rateLimitColumns+` FROM whats_rate_limit ORDER BY scope, subject, budget`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []RateLimitRule{}
+	for rows.Next() {
+		rule, err := scanRateLimit(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, rule)
+	}
+	return list, rows.Err()
+}
+
+func (r *WebhookRepository) DeleteRateLimit(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM whats_rate_limit WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrRateLimitNotFound
+	}
+	return nil
+}
+
+// UsageTotal é o uso de um device no dia e no mês correntes.
+type UsageTotal struct {
+	Tenant  string
+	Device  string
+	Daily   int64
+	Monthly int64
+}
+
+const addUsage = `
+INSERT INTO whats_usage_daily (day, tenant, device, type, count)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (day, tenant, device, type)
+DO UPDATE SET count = whats_usage_daily.count + EXCLUDED.count, updated_at = NOW()`
+
+// AddUsage soma as contagens aos agregados diários numa única transação.
+func (r *WebhookRepository) AddUsage(ctx context.Context, rows []UsageRow) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, addUsage)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row.Period, row.Tenant, row.Device, row.Type, row.Count); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+const usageTotals = `
+SELECT tenant, device,
+       COALESCE(SUM(count) FILTER (WHERE day = $2), 0)::BIGINT AS daily,
+       SUM(count)::BIGINT AS monthly
+FROM whats_usage_daily
+WHERE day >= $1
+GROUP BY tenant, device`
+
+// UsageTotals soma o uso desde monthStart e o do dia today, por tenant e device.
+func (r *WebhookRepository) UsageTotals(ctx context.Context, monthStart, today string) ([]UsageTotal, error) {
+	rows, err := r.db.QueryContext(ctx, usageTotals, monthStart, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []UsageTotal
+	for rows.Next() {
+		var t UsageTotal
+		if err := rows.Scan(&t.Tenant, &t.Device, &t.Daily, &t.Monthly); err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+const listUsage = `
+SELECT to_char(day, 'YYYY-MM-DD'), tenant, device, type, count
+FROM whats_usage_daily
+WHERE day BETWEEN $1 AND $2
+  AND ($3 = '' OR tenant = $3)
+  AND ($4 = '' OR device = $4)
+  AND ($5 = '' OR type = $5)
+ORDER BY day, tenant, device, type`
+
+// ListUsage retorna os agregados diários entre from e to (inclusive) que batem com o filtro.
+func (r *WebhookRepository) ListUsage(ctx context.Context, from, to string, f UsageFilter) ([]UsageRow, error) {
+	rows, err := r.db.QueryContext(ctx, listUsage, from, to, f.Tenant, f.Device, f.Type)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []UsageRow
+	for rows.Next() {
+		var u UsageRow
+		if err := rows.Scan(&u.Period, &u.Tenant, &u.Device, &u.Type, &u.Count); err != nil {
+			return nil, err
+		}
+		list = append(list, u)
+	}
+	return list, rows.Err()
+}
+
+var ErrQuotaNotFound = errors.New("cota não encontrada")
+
+const quotaColumns = `id, scope, subject, daily, monthly, created_at, updated_at`
+
+func scanQuota(row interface{ Scan(...any) error }) (QuotaRule, error) {
+	var rule QuotaRule
+	var daily, monthly sql.NullInt64
+	err := row.Scan(&rule.ID, &rule.Scope, &rule.Subject, &daily, &monthly, &rule.CreatedAt, &rule.UpdatedAt)
+	if daily.Valid {
+		rule.Daily = &daily.Int64
+	}
+	if monthly.Valid {
+		rule.Monthly = &monthly.Int64
+	}
+	return rule, err
+}
+
+const upsertQuota = `
+INSERT INTO whats_quota (scope, subject, daily, monthly)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (scope, subject)
+DO UPDATE SET daily = EXCLUDED.daily, monthly = EXCLUDED.monthly, updated_at = NOW()
+RETURNING ` + quotaColumns
+
+// UpsertQuota grava a cota do assunto, substituindo a anterior do mesmo escopo.
+func (r *WebhookRepository) UpsertQuota(ctx context.Context, rule QuotaRule) (QuotaRule, error) {
+	row := r.db.QueryRowContext(ctx, upsertQuota, rule.Scope, rule.Subject, rule.Daily, rule.Monthly)
+	return scanQuota(row)
+}
+
+func (r *WebhookRepository) ListQuotas(ctx context.Context) ([]QuotaRule, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+quotaColumns+` FROM whats_quota ORDER BY scope, subject`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []QuotaRule{}
+	for rows.Next() {
+		rule, err := scanQuota(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, rule)
+	}
+	return list, rows.Err()
+}
+
+func (r *WebhookRepository) DeleteQuota(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM whats_quota WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrQuotaNotFound
+	}
+	return nil
+}
@@ -2,24 +2,312 @@ package app
 
 import (
 	"context"
+	"errors"
+	"github.com/simpplify-org/GO-simpzap/pkg/config"
 	"github.com/simpplify-org/GO-simpzap/pkg/whatsapp"
+	"log/slog"
 	"net/http"
+	"sync"
+)
+
+var (
+	ErrOutboxDisabled   = errors.New("outbox desativada: banco de dados não configurado ou outbox.enabled=false")
+	ErrWebhooksDisabled = errors.New("regras de webhook desativadas: banco de dados não configurado")
 )
 
 type WhatsAppService struct {
-	Zap *whatsapp.ZapPkg
-	Ctx context.Context
+	Zap      *whatsapp.ZapPkg
+	Events   *whatsapp.EventStream
+	Repo     *WebhookRepository // nil quando o master roda sem banco
+	Outbox   *OutboxWorker
+	Webhooks *WebhookSyncer // nil quando o master roda sem banco
+	Limits   *RateLimiter
+	Usage    *UsageMeter
+	Config   config.Master
+	Ctx      context.Context
+
+	runCtx  context.Context    // cancelado no Shutdown; usado pelos workers em segundo plano
+	stop    context.CancelFunc // encerra o stream de eventos e a outbox
+	workers sync.WaitGroup
+}
+
+func NewWhatsAppService(ctx context.Context, cfg config.Master, repo *WebhookRepository) *WhatsAppService {
+	zap := whatsapp.NewZapPkg(cfg)
+	if err := zap.RegisterMetrics(); err != nil {
+		slog.Error("Erro ao registrar métricas de devices", "component", "metrics", "error", err)
+	}
+	events := whatsapp.NewEventStream(zap)
+
+	svc := &WhatsAppService{
+		Zap:    zap,
+		Events: events,
+		Repo:   repo,
+		Config: cfg,
+		Ctx:    ctx,
+	}
+	svc.runCtx, svc.stop = context.WithCancel(ctx)
+	svc.Limits = NewRateLimiter(cfg.RateLimit, repo, zap)
+	if cfg.RateLimit.Enabled {
+		svc.workers.Go(func() { svc.Limits.Run(svc.runCtx) })
+	}
+	svc.Usage = NewUsageMeter(cfg.Quota, repo, zap)
+	svc.workers.Go(func() { svc.Usage.Run(svc.runCtx) })
+	if repo != nil {
+		svc.Webhooks = NewWebhookSyncer(repo, zap)
+		if cfg.Features.WebhookSync {
+			// Reenvia as regras sempre que um child sobe ou reinicia (as regras do child ficam só em memória)
+			events.OnConnect(svc.Webhooks.PushLogged)
+		}
+	}
+
+	svc.workers.Go(func() { events.Run(svc.runCtx) })
+	// Para os devices ociosos (idle.timeout global ou o de cada device)
+	svc.workers.Go(func() { zap.RunIdle(svc.runCtx) })
+	return svc
+}
+
+// StartOutbox passa a enviar as mensagens inseridas em whats_webhook.
+func (s *WhatsAppService) StartOutbox(dsn string) {
+	if s.Repo == nil {
+		return
+	}
+	if !s.Config.Outbox.Enabled {
+		slog.Warn("Outbox desativada pela configuração (outbox.enabled)")
+		return
+	}
+	s.Outbox = NewOutboxWorker(s.Repo, s.Zap, s.Usage, dsn, s.Config.Outbox)
+	s.workers.Go(func() { s.Outbox.Run(s.runCtx) })
 }
 
-func NewWhatsAppService(ctx context.Context) *WhatsAppService {
-	return &WhatsAppService{
-		Zap: whatsapp.NewZapPkg(),
-		Ctx: ctx,
+// Shutdown para os workers em segundo plano (assinaturas dos childs, LISTEN e
+// polling da outbox) e espera o lote da outbox em andamento, até o prazo de ctx.
+func (s *WhatsAppService) Shutdown(ctx context.Context) error {
+	s.stop()
+
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-func (s *WhatsAppService) CreateDevice(number string) (CreateDeviceResponse, error) {
-	cc, err := s.Zap.CreateDevice(s.Ctx, number)
+func (s *WhatsAppService) ListOutbox(status, number string, limit int) ([]OutboxMessage, error) {
+	if s.Repo == nil {
+		return nil, ErrOutboxDisabled
+	}
+	return s.Repo.ListOutbox(s.Ctx, status, number, limit)
+}
+
+func (s *WhatsAppService) GetOutbox(id int64) (OutboxMessage, error) {
+	if s.Repo == nil {
+		return OutboxMessage{}, ErrOutboxDisabled
+	}
+	return s.Repo.GetOutbox(s.Ctx, id)
+}
+
+func (s *WhatsAppService) RetryOutbox(id int64) error {
+	if s.Repo == nil {
+		return ErrOutboxDisabled
+	}
+	return s.Repo.RetryOutbox(s.Ctx, id)
+}
+
+func (s *WhatsAppService) OutboxStats() (OutboxStats, error) {
+	if s.Outbox == nil {
+		return OutboxStats{}, ErrOutboxDisabled
+	}
+	return s.Outbox.Stats(s.Ctx)
+}
+
+func (s *WhatsAppService) CreateWebhook(rule WebhookRule) (WebhookRuleResponse, error) {
+	if s.Webhooks == nil {
+		return WebhookRuleResponse{}, ErrWebhooksDisabled
+	}
+	if err := s.Webhooks.Adopt(s.Ctx, rule.Device); err != nil {
+		return WebhookRuleResponse{}, err
+	}
+	created, err := s.Repo.CreateWebhookRule(s.Ctx, rule)
+	if err != nil {
+		return WebhookRuleResponse{}, err
+	}
+	return s.pushWebhooks(created, created.Device), nil
+}
+
+func (s *WhatsAppService) UpdateWebhook(rule WebhookRule) (WebhookRuleResponse, error) {
+	if s.Webhooks == nil {
+		return WebhookRuleResponse{}, ErrWebhooksDisabled
+	}
+	old, err := s.Repo.GetWebhookRule(s.Ctx, rule.ID)
+	if err != nil {
+		return WebhookRuleResponse{}, err
+	}
+	if old.Device != rule.Device {
+		if err := s.Webhooks.Adopt(s.Ctx, rule.Device); err != nil {
+			return WebhookRuleResponse{}, err
+		}
+	}
+	updated, err := s.Repo.UpdateWebhookRule(s.Ctx, rule)
+	if err != nil {
+		return WebhookRuleResponse{}, err
+	}
+	if old.Device != updated.Device {
+		// A regra mudou de device: o anterior também precisa perder a regra
+		s.pushWebhooks(updated, old.Device)
+	}
+	return s.pushWebhooks(updated, updated.Device), nil
+}
+
+func (s *WhatsAppService) DeleteWebhook(id int64) (WebhookRuleResponse, error) {
+	if s.Webhooks == nil {
+		return WebhookRuleResponse{}, ErrWebhooksDisabled
+	}
+	rule, err := s.Repo.GetWebhookRule(s.Ctx, id)
+	if err != nil {
+		return WebhookRuleResponse{}, err
+	}
+	if _, err := s.Repo.DeleteWebhookRule(s.Ctx, id); err != nil {
+		return WebhookRuleResponse{}, err
+	}
+	return s.pushWebhooks(rule, rule.Device), nil
+}
+
+// pushWebhooks envia as regras do device ao child. A regra já está gravada, então uma
+// falha aqui só é informada: o envio é refeito quando o child (re)conectar ou em /webhooks/sync.
+func (s *WhatsAppService) pushWebhooks(rule WebhookRule, device string) WebhookRuleResponse {
+	resp := WebhookRuleResponse{Rule: rule, Synced: true}
+	if err := s.Webhooks.Push(s.Ctx, device); err != nil {
+		slog.Warn("Regra gravada, mas não enviada para o child", "component", "webhooks", "rule_id", rule.ID, "device", device, "error", err)
+		resp.Synced, resp.SyncError = false, err.Error()
+	}
+	return resp
+}
+
+func (s *WhatsAppService) GetWebhook(id int64) (WebhookRule, error) {
+	if s.Webhooks == nil {
+		return WebhookRule{}, ErrWebhooksDisabled
+	}
+	return s.Repo.GetWebhookRule(s.Ctx, id)
+}
+
+func (s *WhatsAppService) ListWebhooks(device string) ([]WebhookRule, error) {
+	if s.Webhooks == nil {
+		return nil, ErrWebhooksDisabled
+	}
+	return s.Repo.ListWebhookRules(s.Ctx, device)
+}
+
+// WebhookDrift compara as regras gravadas com as ativas nos childs (device vazio verifica todos).
+func (s *WhatsAppService) WebhookDrift(device string) ([]WebhookDrift, error) {
+	if s.Webhooks == nil {
+		return nil, ErrWebhooksDisabled
+	}
+	if device == "" {
+		return s.Webhooks.DriftAll(s.Ctx)
+	}
+	drift, err := s.Webhooks.Drift(s.Ctx, device)
+	if err != nil {
+		return nil, err
+	}
+	return []WebhookDrift{drift}, nil
+}
+
+// SyncWebhooks reenvia as regras e retorna o estado após o envio.
+func (s *WhatsAppService) SyncWebhooks(device string) ([]WebhookDrift, error) {
+	if s.Webhooks == nil {
+		return nil, ErrWebhooksDisabled
+	}
+
+	devices := []string{device}
+	if device == "" {
+		var err error
+		if devices, err = s.Repo.ListWebhookRuleDevices(s.Ctx); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range devices {
+		if err := s.Webhooks.Push(s.Ctx, d); err != nil {
+			slog.Warn("Erro ao enviar regras para o child", "component", "webhooks", "device", d, "error", err)
+		}
+	}
+	return s.WebhookDrift(device)
+}
+
+func (s *WhatsAppService) RateLimits() (RateLimitsResponse, error) {
+	return s.Limits.Rules(s.Ctx)
+}
+
+// UpsertRateLimit grava o limite e o aplica na hora, sem esperar a próxima releitura.
+func (s *WhatsAppService) UpsertRateLimit(rule RateLimitRule) (RateLimitRule, error) {
+	if s.Repo == nil {
+		return RateLimitRule{}, ErrRateLimitsDisabled
+	}
+	saved, err := s.Repo.UpsertRateLimit(s.Ctx, rule)
+	if err != nil {
+		return RateLimitRule{}, err
+	}
+	if err := s.Limits.Reload(s.Ctx); err != nil {
+		slog.Warn("Limite gravado, mas não recarregado", "component", "ratelimit", "id", saved.ID, "error", err)
+	}
+	return maskRateLimit(saved), nil
+}
+
+func (s *WhatsAppService) DeleteRateLimit(id int64) error {
+	if s.Repo == nil {
+		return ErrRateLimitsDisabled
+	}
+	if err := s.Repo.DeleteRateLimit(s.Ctx, id); err != nil {
+		return err
+	}
+	if err := s.Limits.Reload(s.Ctx); err != nil {
+		slog.Warn("Limite removido, mas não recarregado", "component", "ratelimit", "id", id, "error", err)
+	}
+	return nil
+}
+
+func (s *WhatsAppService) UsageReport(from, to, period string, filter UsageFilter) (UsageReport, error) {
+	return s.Usage.Report(s.Ctx, from, to, period, filter)
+}
+
+func (s *WhatsAppService) Quotas() (QuotasResponse, error) {
+	return s.Usage.Quotas(s.Ctx)
+}
+
+// UpsertQuota grava a cota e a aplica na hora, sem esperar a próxima releitura.
+func (s *WhatsAppService) UpsertQuota(rule QuotaRule) (QuotaRule, error) {
+	if s.Repo == nil {
+		return QuotaRule{}, ErrQuotasDisabled
+	}
+	saved, err := s.Repo.UpsertQuota(s.Ctx, rule)
+	if err != nil {
+		return QuotaRule{}, err
+	}
+	if err := s.Usage.Reload(s.Ctx); err != nil {
+		slog.Warn("Cota gravada, mas não recarregada", "component", "usage", "id", saved.ID, "error", err)
+	}
+	return saved, nil
+}
+
+func (s *WhatsAppService) DeleteQuota(id int64) error {
+	if s.Repo == nil {
+		return ErrQuotasDisabled
+	}
+	if err := s.Repo.DeleteQuota(s.Ctx, id); err != nil {
+		return err
+	}
+	if err := s.Usage.Reload(s.Ctx); err != nil {
+		slog.Warn("Cota removida, mas não recarregada", "component", "usage", "id", id, "error", err)
+	}
+	return nil
+}
+
+func (s *WhatsAppService) CreateDevice(number string, opts whatsapp.DeviceOptions) (CreateDeviceResponse, error) {
+	cc, err := s.Zap.CreateDevice(s.Ctx, number, opts)
 	if err != nil {
 		return CreateDeviceResponse{}, err
 	}
@@ -28,6 +316,10 @@ func (s *WhatsAppService) CreateDevice(number string) (CreateDeviceResponse, err
 		Endpoint: cc.Endpoint,
 		ID:       cc.ID,
 		WsUrl:    "/device/" + number + "/connect/ws",
+		Tenant:   cc.Tenant,
+	}
+	if cc.IdleTimeout != nil {
+		response.IdleTimeout = cc.IdleTimeout.String()
 	}
 	return response, nil
 }
@@ -40,6 +332,14 @@ func (s *WhatsAppService) ProxyHandler() http.Handler {
 	return s.Zap.ProxyHandler()
 }
 
+func (s *WhatsAppService) UpdateDeviceSession(number string, evt DeviceSessionEvent) {
+	s.Zap.SetSessionState(number, whatsapp.SessionState{
+		State:     evt.Data.State,
+		Reason:    evt.Data.Reason,
+		UpdatedAt: evt.Timestamp,
+	})
+}
+
 func (s *WhatsAppService) ListDevices() ([]whatsapp.DeviceInfo, error) {
 	return s.Zap.ListDevices(s.Ctx)
 }
@@ -0,0 +1,597 @@
+package app
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/simpplify-org/GO-simpzap/pkg/config"
+	"github.com/simpplify-org/GO-simpzap/pkg/whatsapp"
+)
+
+// Tipos de mensagem contados em whats_usage_daily, pela rota de envio do child.
+const (
+	UsageText     = "text" // /send, /send/many e a outbox
+	UsageReply    = "reply"
+	UsageReaction = "reaction"
+	UsageEdit     = "edit"
+	UsageRevoke   = "revoke"
+)
+
+var usageTypes = []string{UsageText, UsageReply, UsageReaction, UsageEdit, UsageRevoke}
+
+// Escopos das cotas.
+const (
+	QuotaScopeTenant = "tenant"
+	QuotaScopeDevice = "device"
+)
+
+// Agrupamentos do relatório de /usage.
+const (
+	UsagePeriodDay   = "day"
+	UsagePeriodMonth = "month"
+)
+
+var ErrQuotasDisabled = errors.New("cotas gravadas desativadas: banco de dados não configurado")
+
+var (
+	messagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "whatsapp_master_messages_sent_total",
+		Help: "Mensagens enviadas pelos devices (proxy e outbox), por tipo.",
+	}, []string{"type"})
+	quotaExceeded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "whatsapp_master_quota_exceeded_total",
+		Help: "Envios recusados por cota esgotada, por escopo e período.",
+	}, []string{"scope", "period"})
+)
+
+// QuotaError recusa um envio que passaria da cota do tenant ou do device.
+type QuotaError struct {
+	Scope   string
+	Subject string
+	Monthly bool
+	Used    int64
+	Limit   int64
+	Reset   time.Time // início do próximo dia ou mês
+}
+
+func (e *QuotaError) Error() string {
+	period := "diária"
+	if e.Monthly {
+		period = "mensal"
+	}
+	return fmt.Sprintf("cota %s do %s %s esgotada (%d/%d), renova em %s",
+		period, e.Scope, e.Subject, e.Used, e.Limit, e.Reset.Format(time.RFC3339))
+}
+
+// RetryAfterSeconds é a espera até a cota renovar, em segundos inteiros (no mínimo 1).
+func (e *QuotaError) RetryAfterSeconds() int {
+	return max(1, int(math.Ceil(time.Until(e.Reset).Seconds())))
+}
+
+func (e *QuotaError) period() string {
+	if e.Monthly {
+		return "monthly"
+	}
+	return "daily"
+}
+
+type usageKey struct {
+	day    string // "2006-01-02" no fuso das cotas
+	tenant string
+	device string
+	typ    string // "" enquanto a mensagem está reservada
+}
+
+type quotaKey struct {
+	scope   string
+	subject string
+}
+
+type usageCount struct {
+	daily   int64
+	monthly int64
+}
+
+// UsageMeter conta as mensagens enviadas por device e tenant e aplica as cotas
+// diárias e mensais. As contagens ficam em memória e são somadas a whats_usage_daily
+// a cada quota.flush_interval; o uso já gravado é relido junto, então vários masters
+// no mesmo banco enxergam o consumo uns dos outros com esse atraso. Sem banco, o uso
+// fica só em memória e zera quando o master reinicia.
+type UsageMeter struct {
+	repo *WebhookRepository // nil quando o master roda sem banco
+	zap  *whatsapp.ZapPkg
+	cfg  config.Quota
+	loc  *time.Location
+	log  *slog.Logger
+
+	mu         sync.Mutex
+	rules      map[quotaKey]QuotaRule
+	flushed    map[quotaKey]usageCount // uso gravado no banco, lido em flushedDay
+	flushedDay string
+	local      map[usageKey]int64 // uso ainda não gravado, incluindo as reservas
+	loadedAt   time.Time
+}
+
+func NewUsageMeter(cfg config.Quota, repo *WebhookRepository, zap *whatsapp.ZapPkg) *UsageMeter {
+	return &UsageMeter{
+		repo:    repo,
+		zap:     zap,
+		cfg:     cfg,
+		loc:     cfg.Location(),
+		log:     slog.Default().With("component", "usage"),
+		rules:   make(map[quotaKey]QuotaRule),
+		flushed: make(map[quotaKey]usageCount),
+		local:   make(map[usageKey]int64),
+	}
+}
+
+// UsageReservation guarda as mensagens reservadas por Reserve até o envio terminar.
+type UsageReservation struct {
+	m    *UsageMeter
+	key  usageKey
+	cost int64
+}
+
+// Reserve confere as cotas do device e do tenant e reserva cost mensagens. A reserva
+// conta para as próximas verificações até Commit, evitando que envios simultâneos
+// passem juntos da cota.
+func (m *UsageMeter) Reserve(tenant, device string, cost int64) (*UsageReservation, error) {
+	now := time.Now().In(m.loc)
+	day := now.Format(time.DateOnly)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cfg.Enabled {
+		subjects := []quotaKey{{QuotaScopeDevice, device}}
+		if tenant != "" {
+			subjects = append(subjects, quotaKey{QuotaScopeTenant, tenant})
+		}
+		for _, subject := range subjects {
+			if err := m.check(subject, day, now, cost); err != nil {
+				quotaExceeded.WithLabelValues(subject.scope, err.period()).Inc()
+				return nil, err
+			}
+		}
+	}
+
+	key := usageKey{day: day, tenant: tenant, device: device}
+	m.local[key] += cost
+	return &UsageReservation{m: m, key: key, cost: cost}, nil
+}
+
+// check recusa cost mensagens que passariam de alguma cota do assunto; exige m.mu.
+func (m *UsageMeter) check(subject quotaKey, day string, now time.Time, cost int64) *QuotaError {
+	daily, monthly := m.limitsFor(subject)
+	used := m.used(subject, day)
+	if daily > 0 && used.daily+cost > daily {
+		return &QuotaError{Scope: subject.scope, Subject: subject.subject, Used: used.daily, Limit: daily,
+			Reset: time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, m.loc)}
+	}
+	if monthly > 0 && used.monthly+cost > monthly {
+		return &QuotaError{Scope: subject.scope, Subject: subject.subject, Monthly: true, Used: used.monthly, Limit: monthly,
+			Reset: time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, m.loc)}
+	}
+	return nil
+}
+
+// Commit registra as sent mensagens realmente enviadas (0 se o envio falhou) e
+// devolve o resto da reserva.
+func (r *UsageReservation) Commit(typ string, sent int64) {
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.local[r.key] -= r.cost; m.local[r.key] <= 0 {
+		delete(m.local, r.key)
+	}
+	if sent > 0 {
+		key := r.key
+		key.typ = typ
+		m.local[key] += sent
+		messagesSent.WithLabelValues(typ).Add(float64(sent))
+	}
+}
+
+// limitsFor retorna as cotas diária e mensal do assunto (0 sem cota); exige m.mu.
+func (m *UsageMeter) limitsFor(subject quotaKey) (daily, monthly int64) {
+	daily, monthly = m.cfg.DeviceDaily, m.cfg.DeviceMonthly
+	if subject.scope == QuotaScopeTenant {
+		daily, monthly = m.cfg.TenantDaily, m.cfg.TenantMonthly
+	}
+	if rule, ok := m.rules[subject]; ok {
+		if rule.Daily != nil {
+			daily = *rule.Daily
+		}
+		if rule.Monthly != nil {
+			monthly = *rule.Monthly
+		}
+	}
+	return daily, monthly
+}
+
+// used soma o uso gravado e o local do assunto no dia e no mês de day; exige m.mu.
+func (m *UsageMeter) used(subject quotaKey, day string) usageCount {
+	var total usageCount
+	// O uso gravado vale enquanto for do mesmo dia/mês; na virada até a próxima leitura, zera
+	if flushed, ok := m.flushed[subject]; ok && sameMonth(m.flushedDay, day) {
+		total.monthly = flushed.monthly
+		if m.flushedDay == day {
+			total.daily = flushed.daily
+		}
+	}
+	for key, n := range m.local {
+		if !key.matches(subject) || !sameMonth(key.day, day) {
+			continue
+		}
+		total.monthly += n
+		if key.day == day {
+			total.daily += n
+		}
+	}
+	return total
+}
+
+func (k usageKey) matches(subject quotaKey) bool {
+	if subject.scope == QuotaScopeTenant {
+		return k.tenant == subject.subject
+	}
+	return k.device == subject.subject
+}
+
+func sameMonth(a, b string) bool {
+	return len(a) >= 7 && len(b) >= 7 && a[:7] == b[:7]
+}
+
+// Flush soma o uso local a whats_usage_daily e relê o uso do dia e do mês, que passa
+// a incluir o dos outros masters.
+func (m *UsageMeter) Flush(ctx context.Context) error {
+	if m.repo == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	var rows []UsageRow
+	for key, n := range m.local {
+		if key.typ != "" {
+			rows = append(rows, UsageRow{Period: key.day, Tenant: key.tenant, Device: key.device, Type: key.typ, Count: n})
+		}
+	}
+	m.mu.Unlock()
+
+	if len(rows) > 0 {
+		if err := m.repo.AddUsage(ctx, rows); err != nil {
+			return fmt.Errorf("gravar uso: %w", err)
+		}
+	}
+
+	now := time.Now().In(m.loc)
+	day := now.Format(time.DateOnly)
+	totals, err := m.repo.UsageTotals(ctx, now.Format("2006-01")+"-01", day)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// As linhas gravadas saem do uso local; até a releitura, entram no uso gravado
+	for _, row := range rows {
+		key := usageKey{day: row.Period, tenant: row.Tenant, device: row.Device, typ: row.Type}
+		if m.local[key] -= row.Count; m.local[key] <= 0 {
+			delete(m.local, key)
+		}
+		if !sameMonth(m.flushedDay, row.Period) {
+			continue
+		}
+		for _, subject := range []quotaKey{{QuotaScopeDevice, row.Device}, {QuotaScopeTenant, row.Tenant}} {
+			c := m.flushed[subject]
+			c.monthly += row.Count
+			if row.Period == m.flushedDay {
+				c.daily += row.Count
+			}
+			m.flushed[subject] = c
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("ler uso: %w", err)
+	}
+
+	flushed := make(map[quotaKey]usageCount)
+	for _, t := range totals {
+		for _, subject := range []quotaKey{{QuotaScopeDevice, t.Device}, {QuotaScopeTenant, t.Tenant}} {
+			c := flushed[subject]
+			c.daily += t.Daily
+			c.monthly += t.Monthly
+			flushed[subject] = c
+		}
+	}
+	m.flushed, m.flushedDay = flushed, day
+	return nil
+}
+
+// Reload relê as cotas gravadas em whats_quota.
+func (m *UsageMeter) Reload(ctx context.Context) error {
+	if m.repo == nil {
+		return nil
+	}
+	list, err := m.repo.ListQuotas(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules := make(map[quotaKey]QuotaRule, len(list))
+	for _, rule := range list {
+		rules[quotaKey{rule.Scope, rule.Subject}] = rule
+	}
+	m.mu.Lock()
+	m.rules, m.loadedAt = rules, time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+// Run grava o uso e relê as cotas a cada quota.flush_interval até ctx ser cancelado;
+// no encerramento, grava o que ainda estiver em memória.
+func (m *UsageMeter) Run(ctx context.Context) {
+	if m.repo == nil {
+		return
+	}
+	m.sync(ctx)
+
+	ticker := time.NewTicker(m.cfg.FlushInterval.Std())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.sync(ctx)
+		case <-ctx.Done():
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			defer cancel()
+			if err := m.Flush(ctx); err != nil {
+				m.log.Error("Erro ao gravar o uso no encerramento", "error", err)
+			}
+			return
+		}
+	}
+}
+
+func (m *UsageMeter) sync(ctx context.Context) {
+	if err := m.Reload(ctx); err != nil {
+		m.log.Warn("Erro ao ler cotas do banco, mantendo as atuais", "error", err)
+	}
+	if err := m.Flush(ctx); err != nil {
+		m.log.Warn("Erro ao gravar o uso, nova tentativa no próximo ciclo", "error", err)
+	}
+}
+
+// Middleware conta os envios de /device/{number}/send... e recusa com 429 os que
+// passariam da cota. Só o que o child aceitou é contado; em /send/many, os números
+// que voltaram sem id não contam.
+func (m *UsageMeter) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		device, route, ok := proxiedRoute(c.Request().URL.Path)
+		typ := usageType(route)
+		if !ok || typ == "" {
+			return next(c)
+		}
+
+		cost := sendCost(c, route)
+		res, err := m.Reserve(m.zap.DeviceTenant(device), device, int64(cost))
+		if err != nil {
+			var quotaErr *QuotaError
+			if errors.As(err, &quotaErr) {
+				c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(quotaErr.RetryAfterSeconds()))
+			}
+			return c.JSON(http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+		}
+
+		var body *cappedBuffer
+		if route == "/send/many" {
+			body = &cappedBuffer{limit: maxCountedBody}
+			resp := c.Response()
+			resp.Writer = &teeWriter{ResponseWriter: resp.Writer, w: body}
+		}
+
+		var sent int64
+		defer func() { res.Commit(typ, sent) }()
+		err = next(c)
+		if status := c.Response().Status; status >= 200 && status < 300 {
+			sent = int64(cost)
+			if body != nil {
+				sent = sentCount(body, sent)
+			}
+		}
+		return err
+	}
+}
+
+// usageType é o tipo de mensagem da rota de envio ("" se não for envio).
+func usageType(route string) string {
+	switch route {
+	case "/send", "/send/many":
+		return UsageText
+	case "/send/reply":
+		return UsageReply
+	case "/send/reaction":
+		return UsageReaction
+	case "/send/edit":
+		return UsageEdit
+	case "/send/revoke":
+		return UsageRevoke
+	}
+	return ""
+}
+
+// sentCount conta os resultados com id na resposta de /send/many; se não der para
+// ler a resposta, vale o número de destinatários.
+func sentCount(body *cappedBuffer, fallback int64) int64 {
+	if body.truncated {
+		return fallback
+	}
+	var resp struct {
+		Results []struct {
+			ID string `json:"id"`
+		} `json:"results"`
+	}
+	if json.Unmarshal(body.Bytes(), &resp) != nil || resp.Results == nil {
+		return fallback
+	}
+	var n int64
+	for _, r := range resp.Results {
+		if r.ID != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// cappedBuffer guarda até limit bytes e descarta o resto.
+type cappedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); len(p) > room {
+		b.truncated = true
+		b.Buffer.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+// teeWriter copia o corpo da resposta do proxy sem atrapalhar o Flush.
+type teeWriter struct {
+	http.ResponseWriter
+	w *cappedBuffer
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	n, err := t.ResponseWriter.Write(p)
+	t.w.Write(p[:n])
+	return n, err
+}
+
+func (t *teeWriter) Flush() {
+	http.NewResponseController(t.ResponseWriter).Flush()
+}
+
+func (t *teeWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// Report agrega o uso entre from e to (datas "2006-01-02", inclusive) por dia ou mês,
+// tenant, device e tipo. O uso ainda não gravado entra no relatório.
+func (m *UsageMeter) Report(ctx context.Context, from, to, period string, f UsageFilter) (UsageReport, error) {
+	var rows []UsageRow
+	if m.repo != nil {
+		var err error
+		if rows, err = m.repo.ListUsage(ctx, from, to, f); err != nil {
+			return UsageReport{}, err
+		}
+	}
+
+	m.mu.Lock()
+	for key, n := range m.local {
+		if key.typ == "" || key.day < from || key.day > to ||
+			(f.Tenant != "" && key.tenant != f.Tenant) || (f.Device != "" && key.device != f.Device) || (f.Type != "" && key.typ != f.Type) {
+			continue
+		}
+		rows = append(rows, UsageRow{Period: key.day, Tenant: key.tenant, Device: key.device, Type: key.typ, Count: n})
+	}
+	m.mu.Unlock()
+
+	report := UsageReport{From: from, To: to, Period: period, Rows: []UsageRow{}}
+	index := make(map[usageKey]int)
+	for _, row := range rows {
+		if period == UsagePeriodMonth {
+			row.Period = row.Period[:7]
+		}
+		key := usageKey{day: row.Period, tenant: row.Tenant, device: row.Device, typ: row.Type}
+		if i, ok := index[key]; ok {
+			report.Rows[i].Count += row.Count
+		} else {
+			index[key] = len(report.Rows)
+			report.Rows = append(report.Rows, row)
+		}
+		report.Total += row.Count
+	}
+	slices.SortFunc(report.Rows, func(a, b UsageRow) int {
+		return cmp.Or(cmp.Compare(a.Period, b.Period), cmp.Compare(a.Tenant, b.Tenant), cmp.Compare(a.Device, b.Device), cmp.Compare(a.Type, b.Type))
+	})
+	return report, nil
+}
+
+// Quotas retorna os padrões da configuração, as cotas gravadas e o consumo atual de
+// cada tenant e device que enviou algo no mês.
+func (m *UsageMeter) Quotas(ctx context.Context) (QuotasResponse, error) {
+	int64p := func(v int64) *int64 { return &v }
+	resp := QuotasResponse{
+		Enabled:  m.cfg.Enabled,
+		Timezone: m.loc.String(),
+		Defaults: []QuotaRule{
+			{Scope: QuotaScopeDevice, Subject: "*", Daily: int64p(m.cfg.DeviceDaily), Monthly: int64p(m.cfg.DeviceMonthly)},
+			{Scope: QuotaScopeTenant, Subject: "*", Daily: int64p(m.cfg.TenantDaily), Monthly: int64p(m.cfg.TenantMonthly)},
+		},
+		Rules: []QuotaRule{},
+		Usage: []QuotaUsage{},
+	}
+	if m.repo != nil {
+		rules, err := m.repo.ListQuotas(ctx)
+		if err != nil {
+			return resp, err
+		}
+		resp.Rules = rules
+	}
+
+	day := time.Now().In(m.loc).Format(time.DateOnly)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	subjects := make(map[quotaKey]bool)
+	if sameMonth(m.flushedDay, day) {
+		for subject := range m.flushed {
+			subjects[subject] = true
+		}
+	}
+	for key := range m.local {
+		if sameMonth(key.day, day) {
+			subjects[quotaKey{QuotaScopeDevice, key.device}] = true
+			subjects[quotaKey{QuotaScopeTenant, key.tenant}] = true
+		}
+	}
+	for subject := range subjects {
+		if subject.subject == "" {
+			continue // devices sem tenant
+		}
+		used := m.used(subject, day)
+		daily, monthly := m.limitsFor(subject)
+		resp.Usage = append(resp.Usage, QuotaUsage{
+			Scope: subject.scope, Subject: subject.subject,
+			Daily: used.daily, DailyLimit: daily, Monthly: used.monthly, MonthlyLimit: monthly,
+		})
+	}
+	slices.SortFunc(resp.Usage, func(a, b QuotaUsage) int {
+		return cmp.Or(cmp.Compare(a.Scope, b.Scope), cmp.Compare(a.Subject, b.Subject))
+	})
+	if !m.loadedAt.IsZero() {
+		loadedAt := m.loadedAt
+		resp.LoadedAt = &loadedAt
+	}
+	return resp, nil
+}
+
+// Today é a data corrente no fuso das cotas.
+func (m *UsageMeter) Today() time.Time {
+	now := time.Now().In(m.loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, m.loc)
+}
@@ -0,0 +1,273 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/tracing"
+	"github.com/simpplify-org/GO-simpzap/pkg/whatsapp"
+)
+
+// ErrWebhookImport indica que as regras ativas no child não puderam ser lidas antes da
+// primeira regra gravada do device; gravar mesmo assim apagaria as cadastradas direto nele.
+var ErrWebhookImport = errors.New("não foi possível importar as regras ativas na instância")
+
+// WebhookSyncer mantém as regras de webhook de cada child iguais às gravadas no banco.
+// O banco é a fonte da verdade: cada envio substitui todas as regras do child.
+type WebhookSyncer struct {
+	repo   *WebhookRepository
+	zap    *whatsapp.ZapPkg
+	client *http.Client
+	log    *slog.Logger
+
+	mu       sync.Mutex
+	syncedAt map[string]time.Time // último envio bem-sucedido por device
+	locks    map[string]*sync.Mutex
+}
+
+func NewWebhookSyncer(repo *WebhookRepository, zap *whatsapp.ZapPkg) *WebhookSyncer {
+	return &WebhookSyncer{
+		repo:     repo,
+		zap:      zap,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		log:      slog.Default().With("component", "webhooks"),
+		syncedAt: make(map[string]time.Time),
+		locks:    make(map[string]*sync.Mutex),
+	}
+}
+
+// Push envia ao child do device todas as regras gravadas para ele.
+func (w *WebhookSyncer) Push(ctx context.Context, device string) error {
+	// Um envio por vez para cada device, para que um envio antigo não sobrescreva um novo
+	lock := w.deviceLock(device)
+	lock.Lock()
+	defer lock.Unlock()
+
+	rules, err := w.repo.ListWebhookRules(ctx, device)
+	if err != nil {
+		return err
+	}
+
+	targets := make([]WebhookRuleTarget, 0, len(rules))
+	for _, rule := range rules {
+		targets = append(targets, ruleTarget(rule))
+	}
+	body, _ := json.Marshal(targets)
+
+	endpoint, err := w.zap.GetDeviceEndpoint(device)
+	if err != nil {
+		return fmt.Errorf("device %s: %w", device, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/webhook/sync", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("child respondeu %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	w.mu.Lock()
+	w.syncedAt[device] = time.Now()
+	w.mu.Unlock()
+
+	w.log.InfoContext(ctx, "Regras enviadas para o child", "device", device, "rules", len(rules))
+	return nil
+}
+
+// Adopt prepara o device para a primeira regra gravada: as regras cadastradas direto no
+// child (/webhook/register) são importadas para o banco, já que o envio seguinte substitui
+// todas as regras do child. Devices que já têm regras gravadas não mudam; um device parado
+// ou não iniciado não tem regras ativas (o child as guarda só em memória).
+func (w *WebhookSyncer) Adopt(ctx context.Context, device string) error {
+	lock := w.deviceLock(device)
+	lock.Lock()
+	defer lock.Unlock()
+
+	rules, err := w.repo.ListWebhookRules(ctx, device)
+	if err != nil || len(rules) > 0 {
+		return err
+	}
+	if _, err := w.zap.GetDeviceEndpoint(device); err != nil {
+		return nil
+	}
+
+	active, err := w.activeRules(ctx, device)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWebhookImport, err)
+	}
+	for _, target := range active {
+		rule := WebhookRule{Device: device, Number: target.Number, Phrase: target.Phrase, CallbackURL: target.CallbackURL}
+		if _, err := w.repo.CreateWebhookRule(ctx, rule); err != nil && !errors.Is(err, ErrWebhookRuleExists) {
+			return err
+		}
+	}
+	if len(active) > 0 {
+		w.log.InfoContext(ctx, "Regras do child importadas para o banco", "device", device, "rules", len(active))
+	}
+	return nil
+}
+
+// PushLogged envia as regras e só registra a falha; usado quando o child (re)conecta.
+// Devices sem regras gravadas são ignorados: as cadastradas direto no child só passam
+// a ser substituídas depois de importadas pelo Adopt, na primeira regra gravada.
+func (w *WebhookSyncer) PushLogged(device string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rules, err := w.repo.ListWebhookRules(ctx, device)
+	if err != nil {
+		w.log.Error("Erro ao buscar regras", "device", device, "error", err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+	if err := w.Push(ctx, device); err != nil {
+		w.log.Warn("Erro ao enviar regras para o child", "device", device, "error", err)
+	}
+}
+
+// Drift compara as regras gravadas do device com as ativas no child.
+func (w *WebhookSyncer) Drift(ctx context.Context, device string) (WebhookDrift, error) {
+	rules, err := w.repo.ListWebhookRules(ctx, device)
+	if err != nil {
+		return WebhookDrift{}, err
+	}
+
+	drift := WebhookDrift{Device: device, Stored: len(rules)}
+	w.mu.Lock()
+	if at, ok := w.syncedAt[device]; ok {
+		drift.SyncedAt = &at
+	}
+	w.mu.Unlock()
+
+	active, err := w.activeRules(ctx, device)
+	if err != nil {
+		drift.Error = err.Error()
+		return drift, nil
+	}
+	drift.Active = len(active)
+
+	stored := make(map[WebhookRuleTarget]bool, len(rules))
+	for _, rule := range rules {
+		stored[ruleTarget(rule)] = true
+	}
+	running := make(map[WebhookRuleTarget]bool, len(active))
+	for _, target := range active {
+		running[target] = true
+		if !stored[target] {
+			drift.Extra = append(drift.Extra, target)
+		}
+	}
+	for _, rule := range rules {
+		if target := ruleTarget(rule); !running[target] {
+			drift.Missing = append(drift.Missing, target)
+		}
+	}
+
+	drift.InSync = len(drift.Missing) == 0 && len(drift.Extra) == 0
+	return drift, nil
+}
+
+// DriftAll verifica todos os devices que têm regras ou estão em execução.
+func (w *WebhookSyncer) DriftAll(ctx context.Context) ([]WebhookDrift, error) {
+	devices, err := w.repo.ListWebhookRuleDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(devices))
+	for _, device := range devices {
+		seen[device] = true
+	}
+	for _, device := range w.zap.DeviceNumbers() {
+		if !seen[device] {
+			seen[device] = true
+			devices = append(devices, device)
+		}
+	}
+	sort.Strings(devices)
+
+	report := make([]WebhookDrift, 0, len(devices))
+	for _, device := range devices {
+		drift, err := w.Drift(ctx, device)
+		if err != nil {
+			return nil, err
+		}
+		report = append(report, drift)
+	}
+	return report, nil
+}
+
+// activeRules lê as regras em execução no child (/webhook/list).
+func (w *WebhookSyncer) activeRules(ctx context.Context, device string) ([]WebhookRuleTarget, error) {
+	endpoint, err := w.zap.GetDeviceEndpoint(device)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/webhook/list", nil)
+	if err != nil {
+		return nil, err
+	}
+	tracing.Inject(ctx, req.Header)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("child respondeu %d", resp.StatusCode)
+	}
+
+	// O child responde {"<número>": [{"Phrase": "...", "CallbackURL": "..."}]}
+	var byNumber map[string][]struct {
+		Phrase      string
+		CallbackURL string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&byNumber); err != nil {
+		return nil, err
+	}
+
+	var targets []WebhookRuleTarget
+	for number, rules := range byNumber {
+		for _, rule := range rules {
+			targets = append(targets, WebhookRuleTarget{Number: number, Phrase: rule.Phrase, CallbackURL: rule.CallbackURL})
+		}
+	}
+	return targets, nil
+}
+
+func (w *WebhookSyncer) deviceLock(device string) *sync.Mutex {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	lock, ok := w.locks[device]
+	if !ok {
+		lock = &sync.Mutex{}
+		w.locks[device] = lock
+	}
+	return lock
+}
+
+func ruleTarget(rule WebhookRule) WebhookRuleTarget {
+	return WebhookRuleTarget{Number: rule.Number, Phrase: rule.Phrase, CallbackURL: rule.CallbackURL}
+}
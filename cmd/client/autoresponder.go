@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/simpplify-org/GO-simpzap/cmd/client/clientservice"
+	"gopkg.in/yaml.v3"
+)
+
+// handleAutoResponder - GET/PUT/DELETE /autoresponder — consulta, define ou desliga as respostas automáticas.
+//
+// O PUT aceita JSON ou YAML (Content-Type application/yaml); o GET responde YAML com ?format=yaml.
+func handleAutoResponder(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		cfg := service.AutoResponderConfig()
+		if r.URL.Query().Get("format") == "yaml" || strings.Contains(r.Header.Get("Accept"), "yaml") {
+			out, err := yaml.Marshal(cfg)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/yaml")
+			w.Write(out)
+			return
+		}
+		writeJSON(w, http.StatusOK, cfg)
+	case http.MethodPut:
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "payload inválido", http.StatusBadRequest)
+			return
+		}
+
+		var cfg clientservice.AutoResponderConfig
+		if strings.Contains(r.Header.Get("Content-Type"), "yaml") {
+			err = yaml.Unmarshal(body, &cfg)
+		} else {
+			err = json.Unmarshal(body, &cfg)
+		}
+		if err != nil {
+			http.Error(w, "payload inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := service.SetAutoResponder(cfg); err != nil {
+			http.Error(w, err.Error(), sendErrorStatus(err))
+			return
+		}
+		writeJSON(w, http.StatusOK, service.AutoResponderConfig())
+	case http.MethodDelete:
+		cfg := service.AutoResponderConfig()
+		cfg.Enabled = false
+		if err := service.SetAutoResponder(cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "disabled"})
+	default:
+		http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAutoResponderSessions - GET/DELETE /autoresponder/sessions — lista os chats em um menu ou
+// encerra o fluxo de um chat (?chat=<JID>; sem chat encerra todos).
+func handleAutoResponderSessions(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, service.AutoResponderSessions())
+	case http.MethodDelete:
+		service.ResetAutoResponderSession(r.URL.Query().Get("chat"))
+		writeJSON(w, http.StatusOK, map[string]string{"status": "reset"})
+	default:
+		http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,591 @@
+package clientservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // a imagem alpine não traz o banco de fusos horários
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+const (
+	defaultFlowTimeout  = 10 * time.Minute
+	defaultAwayCooldown = time.Hour
+	defaultTimezone     = "America/Sao_Paulo"
+	autoSweepInterval   = time.Minute // limpeza dos menus expirados e avisos de ausência vencidos
+)
+
+// Modos de comparação das regras do auto-responder.
+const (
+	MatchExact    = "exact"    // texto igual a uma das palavras-chave (padrão)
+	MatchContains = "contains" // texto contém uma das palavras-chave
+	MatchRegex    = "regex"    // texto casa com pattern
+)
+
+// AutoResponderConfig define as respostas automáticas do device. É aceito em JSON ou YAML.
+type AutoResponderConfig struct {
+	Enabled       bool                `json:"enabled" yaml:"enabled"`
+	Groups        bool                `json:"groups,omitempty" yaml:"groups,omitempty"`             // responde também em grupos
+	Timezone      string              `json:"timezone,omitempty" yaml:"timezone,omitempty"`         // padrão America/Sao_Paulo
+	FlowTimeout   string              `json:"flow_timeout,omitempty" yaml:"flow_timeout,omitempty"` // inatividade que encerra um menu (padrão 10m)
+	ExitKeywords  []string            `json:"exit_keywords,omitempty" yaml:"exit_keywords,omitempty"`
+	Rules         []AutoReplyRule     `json:"rules" yaml:"rules"`
+	Menus         map[string]AutoMenu `json:"menus,omitempty" yaml:"menus,omitempty"`
+	BusinessHours *BusinessHours      `json:"business_hours,omitempty" yaml:"business_hours,omitempty"`
+}
+
+// AutoReplyRule associa palavras-chave (ou uma regex) a uma resposta.
+type AutoReplyRule struct {
+	Name       string    `json:"name,omitempty" yaml:"name,omitempty"`
+	Match      string    `json:"match,omitempty" yaml:"match,omitempty"`
+	Keywords   []string  `json:"keywords,omitempty" yaml:"keywords,omitempty"`
+	Pattern    string    `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	AnyTime    bool      `json:"any_time,omitempty" yaml:"any_time,omitempty"` // responde também fora do horário de atendimento
+	Reply      AutoReply `json:"reply" yaml:"reply"`
+	re         *regexp.Regexp
+	normalized []string
+}
+
+// AutoReply é o que o device envia: texto, mídia (media_url, com text como legenda)
+// e/ou a entrada em um menu.
+type AutoReply struct {
+	Text     string `json:"text,omitempty" yaml:"text,omitempty"`
+	MediaURL string `json:"media_url,omitempty" yaml:"media_url,omitempty"`
+	FileName string `json:"file_name,omitempty" yaml:"file_name,omitempty"`
+	Menu     string `json:"menu,omitempty" yaml:"menu,omitempty"`
+}
+
+// AutoMenu é um passo do fluxo: o texto é enviado ao entrar e as opções esperam a resposta do contato.
+type AutoMenu struct {
+	Text    string           `json:"text" yaml:"text"`
+	Options []AutoMenuOption `json:"options" yaml:"options"`
+	Invalid string           `json:"invalid,omitempty" yaml:"invalid,omitempty"` // resposta para opção desconhecida (padrão: reenvia o menu)
+}
+
+// AutoMenuOption é uma escolha do menu; Reply.Menu leva ao próximo menu e vazio encerra o fluxo.
+type AutoMenuOption struct {
+	Keys  []string  `json:"keys" yaml:"keys"`
+	Reply AutoReply `json:"reply" yaml:"reply"`
+}
+
+// BusinessHours define o horário de atendimento. Fora dele só as regras any_time respondem
+// e o contato recebe away_message (no máximo uma vez por away_cooldown).
+type BusinessHours struct {
+	Days         map[string][]string `json:"days" yaml:"days"` // "mon": ["09:00-12:00", "13:00-18:00"]
+	AwayMessage  string              `json:"away_message" yaml:"away_message"`
+	AwayCooldown string              `json:"away_cooldown,omitempty" yaml:"away_cooldown,omitempty"` // padrão 1h
+}
+
+// FlowSession é o menu em que um chat está.
+type FlowSession struct {
+	Chat      string    `json:"chat"`
+	Menu      string    `json:"menu"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+type dayRange struct{ from, to int } // minutos desde a meia-noite
+
+// AutoResponder aplica a configuração às mensagens recebidas e guarda o estado de cada chat.
+type AutoResponder struct {
+	path string
+
+	mu           sync.Mutex
+	config       AutoResponderConfig
+	location     *time.Location
+	flowTimeout  time.Duration
+	awayCooldown time.Duration
+	hours        map[time.Weekday][]dayRange
+	exit         map[string]bool
+	sessions     map[string]FlowSession // key: JID do chat
+	awaySent     map[string]time.Time   // key: JID do chat
+	generation   int                    // muda a cada configuração; descarta commits da anterior
+}
+
+// NewAutoResponder carrega a configuração gravada em path (ausente = desligado).
+func NewAutoResponder(path string) (*AutoResponder, error) {
+	ar := &AutoResponder{
+		path:     path,
+		sessions: make(map[string]FlowSession),
+		awaySent: make(map[string]time.Time),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ar, ar.apply(AutoResponderConfig{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %w", path, err)
+	}
+
+	var cfg AutoResponderConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %w", path, err)
+	}
+	if err := ar.apply(cfg); err != nil {
+		return nil, fmt.Errorf("configuração inválida em %s: %w", path, err)
+	}
+	return ar, nil
+}
+
+// Config retorna a configuração atual.
+func (ar *AutoResponder) Config() AutoResponderConfig {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	return ar.config
+}
+
+// SetConfig valida, aplica e grava a configuração. Os fluxos em andamento são encerrados.
+func (ar *AutoResponder) SetConfig(cfg AutoResponderConfig) error {
+	if err := ar.apply(cfg); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(ar.Config(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(ar.path, data, 0o644); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %w", ar.path, err)
+	}
+	return nil
+}
+
+// Sessions lista os chats que estão em um menu.
+func (ar *AutoResponder) Sessions() []FlowSession {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	now := time.Now()
+	list := []FlowSession{}
+	for chat, session := range ar.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(ar.sessions, chat)
+			continue
+		}
+		list = append(list, session)
+	}
+	return list
+}
+
+// ResetSession encerra o fluxo de um chat (vazio encerra todos).
+func (ar *AutoResponder) ResetSession(chat string) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	if chat == "" {
+		ar.sessions = make(map[string]FlowSession)
+		return
+	}
+	delete(ar.sessions, chat)
+}
+
+// apply valida a configuração e prepara as regex, os horários e o fuso.
+func (ar *AutoResponder) apply(cfg AutoResponderConfig) error {
+	if cfg.Timezone == "" {
+		cfg.Timezone = defaultTimezone
+	}
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return fmt.Errorf("%w: timezone %q", ErrInvalidRequest, cfg.Timezone)
+	}
+
+	flowTimeout, err := parseDurationOr(cfg.FlowTimeout, defaultFlowTimeout)
+	if err != nil {
+		return fmt.Errorf("%w: flow_timeout: %v", ErrInvalidRequest, err)
+	}
+
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		if rule.Match == "" {
+			rule.Match = MatchExact
+		}
+		switch rule.Match {
+		case MatchExact, MatchContains:
+			if len(rule.Keywords) == 0 {
+				return fmt.Errorf("%w: regra %d sem keywords", ErrInvalidRequest, i)
+			}
+			rule.normalized = make([]string, len(rule.Keywords))
+			for j, k := range rule.Keywords {
+				rule.normalized[j] = normalizeText(k)
+			}
+		case MatchRegex:
+			rule.re, err = regexp.Compile(rule.Pattern)
+			if err != nil || rule.Pattern == "" {
+				return fmt.Errorf("%w: regra %d com pattern inválido: %v", ErrInvalidRequest, i, err)
+			}
+		default:
+			return fmt.Errorf("%w: regra %d com match desconhecido %q", ErrInvalidRequest, i, rule.Match)
+		}
+		if err := checkReply(rule.Reply, cfg.Menus); err != nil {
+			return fmt.Errorf("%w: regra %d: %v", ErrInvalidRequest, i, err)
+		}
+	}
+
+	for name, menu := range cfg.Menus {
+		if menu.Text == "" || len(menu.Options) == 0 {
+			return fmt.Errorf("%w: menu %q precisa de text e options", ErrInvalidRequest, name)
+		}
+		for j, opt := range menu.Options {
+			if len(opt.Keys) == 0 {
+				return fmt.Errorf("%w: menu %q, opção %d sem keys", ErrInvalidRequest, name, j)
+			}
+			if err := checkReply(opt.Reply, cfg.Menus); err != nil {
+				return fmt.Errorf("%w: menu %q, opção %d: %v", ErrInvalidRequest, name, j, err)
+			}
+		}
+	}
+
+	awayCooldown := defaultAwayCooldown
+	var hours map[time.Weekday][]dayRange
+	if bh := cfg.BusinessHours; bh != nil {
+		if awayCooldown, err = parseDurationOr(bh.AwayCooldown, defaultAwayCooldown); err != nil {
+			return fmt.Errorf("%w: away_cooldown: %v", ErrInvalidRequest, err)
+		}
+		hours = make(map[time.Weekday][]dayRange)
+		for day, ranges := range bh.Days {
+			weekday, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				return fmt.Errorf("%w: dia %q (use sun, mon, tue, wed, thu, fri, sat)", ErrInvalidRequest, day)
+			}
+			for _, r := range ranges {
+				parsed, err := parseDayRange(r)
+				if err != nil {
+					return fmt.Errorf("%w: horário %q de %s: %v", ErrInvalidRequest, r, day, err)
+				}
+				hours[weekday] = append(hours[weekday], parsed)
+			}
+		}
+	}
+
+	exit := make(map[string]bool, len(cfg.ExitKeywords))
+	for _, k := range cfg.ExitKeywords {
+		exit[normalizeText(k)] = true
+	}
+
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	ar.config = cfg
+	ar.location = location
+	ar.flowTimeout = flowTimeout
+	ar.awayCooldown = awayCooldown
+	ar.hours = hours
+	ar.exit = exit
+	ar.sessions = make(map[string]FlowSession)
+	ar.generation++
+	return nil
+}
+
+// Run remove periodicamente os menus expirados e os avisos de ausência fora do cooldown,
+// de chats que não voltaram a escrever, até ctx ser cancelado.
+func (ar *AutoResponder) Run(ctx context.Context) {
+	ticker := time.NewTicker(autoSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			ar.sweep(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (ar *AutoResponder) sweep(now time.Time) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	for chat, session := range ar.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(ar.sessions, chat)
+		}
+	}
+	for chat, at := range ar.awaySent {
+		if now.Sub(at) >= ar.awayCooldown {
+			delete(ar.awaySent, chat)
+		}
+	}
+}
+
+// Respond decide o que enviar para uma mensagem recebida no chat (nil = nada). O menu
+// do chat e o aviso de ausência só mudam quando commit é chamado, depois que as respostas
+// foram enviadas: se o envio falha, o contato continua onde estava.
+func (ar *AutoResponder) Respond(chat, text string, isGroup bool, now time.Time) (replies []AutoReply, commit func()) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	replies, change := ar.respond(chat, text, isGroup, now)
+	generation := ar.generation
+	commit = func() {
+		if change == nil {
+			return
+		}
+		ar.mu.Lock()
+		defer ar.mu.Unlock()
+		if ar.generation == generation {
+			change()
+		}
+	}
+	return replies, commit
+}
+
+// respond monta as respostas e a mudança de estado a aplicar depois do envio; exige ar.mu.
+func (ar *AutoResponder) respond(chat, text string, isGroup bool, now time.Time) ([]AutoReply, func()) {
+	if !ar.config.Enabled || (isGroup && !ar.config.Groups) {
+		return nil, nil
+	}
+	normalized := normalizeText(text)
+	if normalized == "" {
+		return nil, nil
+	}
+
+	// Contato dentro de um menu: a mensagem é a escolha de uma opção
+	if session, ok := ar.sessions[chat]; ok {
+		if now.After(session.ExpiresAt) {
+			delete(ar.sessions, chat)
+		} else if ar.exit[normalized] {
+			delete(ar.sessions, chat)
+			return nil, nil
+		} else if menu, ok := ar.config.Menus[session.Menu]; ok {
+			if opt, ok := matchOption(menu, normalized); ok {
+				return ar.reply(chat, opt.Reply, now)
+			}
+			// Uma palavra-chave de regra continua valendo no meio do fluxo
+			if rule, ok := ar.matchRule(text, normalized, now); ok {
+				return ar.reply(chat, rule.Reply, now)
+			}
+			session.ExpiresAt = now.Add(ar.flowTimeout)
+			renew := func() { ar.sessions[chat] = session }
+			if menu.Invalid != "" {
+				return []AutoReply{{Text: menu.Invalid}}, renew
+			}
+			return []AutoReply{{Text: menu.Text}}, renew
+		}
+	}
+
+	if rule, ok := ar.matchRule(text, normalized, now); ok {
+		return ar.reply(chat, rule.Reply, now)
+	}
+
+	if !ar.open(now) && ar.config.BusinessHours.AwayMessage != "" {
+		if last, ok := ar.awaySent[chat]; ok && now.Sub(last) < ar.awayCooldown {
+			return nil, nil
+		}
+		return []AutoReply{{Text: ar.config.BusinessHours.AwayMessage}}, func() { ar.awaySent[chat] = now }
+	}
+	return nil, nil
+}
+
+// reply monta as mensagens da resposta e a troca de menu do chat.
+func (ar *AutoResponder) reply(chat string, r AutoReply, now time.Time) ([]AutoReply, func()) {
+	replies := []AutoReply{}
+	if r.Text != "" || r.MediaURL != "" {
+		replies = append(replies, AutoReply{Text: r.Text, MediaURL: r.MediaURL, FileName: r.FileName})
+	}
+
+	if r.Menu == "" {
+		return replies, func() { delete(ar.sessions, chat) }
+	}
+	session := FlowSession{Chat: chat, Menu: r.Menu, ExpiresAt: now.Add(ar.flowTimeout)}
+	return append(replies, AutoReply{Text: ar.config.Menus[r.Menu].Text}), func() { ar.sessions[chat] = session }
+}
+
+// matchRule retorna a primeira regra que casa com o texto; fora do horário só as any_time.
+func (ar *AutoResponder) matchRule(text, normalized string, now time.Time) (AutoReplyRule, bool) {
+	open := ar.open(now)
+	for _, rule := range ar.config.Rules {
+		if !open && !rule.AnyTime {
+			continue
+		}
+		switch rule.Match {
+		case MatchRegex:
+			if rule.re.MatchString(text) {
+				return rule, true
+			}
+		case MatchContains:
+			for _, k := range rule.normalized {
+				if strings.Contains(normalized, k) {
+					return rule, true
+				}
+			}
+		default:
+			for _, k := range rule.normalized {
+				if normalized == k {
+					return rule, true
+				}
+			}
+		}
+	}
+	return AutoReplyRule{}, false
+}
+
+// open informa se now está dentro do horário de atendimento (sem horário = sempre aberto).
+func (ar *AutoResponder) open(now time.Time) bool {
+	if ar.config.BusinessHours == nil {
+		return true
+	}
+	local := now.In(ar.location)
+	minute := local.Hour()*60 + local.Minute()
+	for _, r := range ar.hours[local.Weekday()] {
+		if minute >= r.from && minute < r.to {
+			return true
+		}
+	}
+	return false
+}
+
+func matchOption(menu AutoMenu, normalized string) (AutoMenuOption, bool) {
+	for _, opt := range menu.Options {
+		for _, k := range opt.Keys {
+			if normalizeText(k) == normalized {
+				return opt, true
+			}
+		}
+	}
+	return AutoMenuOption{}, false
+}
+
+func checkReply(r AutoReply, menus map[string]AutoMenu) error {
+	if r.Text == "" && r.MediaURL == "" && r.Menu == "" {
+		return errors.New("reply vazio (informe text, media_url ou menu)")
+	}
+	if r.Menu != "" {
+		if _, ok := menus[r.Menu]; !ok {
+			return fmt.Errorf("menu %q não existe", r.Menu)
+		}
+	}
+	return nil
+}
+
+// normalizeText ignora caixa e espaços extras na comparação de palavras-chave.
+func normalizeText(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+func parseDurationOr(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err == nil && d <= 0 {
+		err = errors.New("deve ser positivo")
+	}
+	return d, err
+}
+
+// parseDayRange interpreta "09:00-18:00".
+func parseDayRange(s string) (dayRange, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return dayRange{}, errors.New("use HH:MM-HH:MM")
+	}
+	start, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return dayRange{}, err
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(to))
+	if err != nil {
+		return dayRange{}, err
+	}
+	r := dayRange{from: start.Hour()*60 + start.Minute(), to: end.Hour()*60 + end.Minute()}
+	if r.to == 0 {
+		r.to = 24 * 60 // "18:00-00:00" vai até a meia-noite
+	}
+	if r.to <= r.from {
+		return dayRange{}, errors.New("fim deve ser depois do início")
+	}
+	return r, nil
+}
+
+// AutoResponderConfig retorna a configuração do auto-responder.
+func (s *WhatsAppService) AutoResponderConfig() AutoResponderConfig {
+	return s.autoResponder.Config()
+}
+
+// SetAutoResponder valida e grava a nova configuração do auto-responder.
+func (s *WhatsAppService) SetAutoResponder(cfg AutoResponderConfig) error {
+	return s.autoResponder.SetConfig(cfg)
+}
+
+// AutoResponderSessions lista os chats que estão em um menu.
+func (s *WhatsAppService) AutoResponderSessions() []FlowSession {
+	return s.autoResponder.Sessions()
+}
+
+// ResetAutoResponderSession encerra o fluxo de um chat (vazio encerra todos).
+func (s *WhatsAppService) ResetAutoResponderSession(chat string) {
+	s.autoResponder.ResetSession(chat)
+}
+
+// autoRespond envia as respostas automáticas para uma mensagem recebida. Roda na fila
+// do chat (enqueueChat), então as respostas de mensagens seguidas não se cruzam.
+func (s *WhatsAppService) autoRespond(v *events.Message) {
+	if !s.cfg.Features.AutoResponder {
+		return
+	}
+	// Reação traz o emoji como texto e status@broadcast não é conversa: nenhuma tem resposta
+	if v.Message.GetReactionMessage() != nil || v.Info.Chat.Server == types.BroadcastServer {
+		return
+	}
+	chat := v.Info.Chat.String()
+	replies, commit := s.autoResponder.Respond(chat, messageText(v.Message), v.Info.IsGroup, time.Now())
+
+	for _, r := range replies {
+		var err error
+		if r.MediaURL != "" {
+			_, err = s.SendMediaURL(chat, r.MediaURL, r.Text, r.FileName)
+		} else {
+			_, err = s.SendMessage(chat, r.Text)
+		}
+		if err != nil {
+			slog.Error("❌ Erro na resposta automática", "chat", chat, "error", err)
+			return
+		}
+	}
+	commit()
+	if len(replies) > 0 {
+		slog.Info("🤖 Resposta automática enviada", "chat", chat, "replies", len(replies))
+	}
+}
+
+// enqueueChat roda fn em segundo plano depois das tarefas já enfileiradas para o mesmo
+// chat, na ordem de chegada. Chamado pelo handler de eventos, que entrega as mensagens em ordem.
+func (s *WhatsAppService) enqueueChat(chat string, fn func()) {
+	s.chatMu.Lock()
+	defer s.chatMu.Unlock()
+	if queue, ok := s.chatQueues[chat]; ok {
+		// Já há uma tarefa do chat rodando; ela executa esta ao terminar
+		s.chatQueues[chat] = append(queue, fn)
+		return
+	}
+	s.chatQueues[chat] = nil
+	s.async(func() { s.drainChat(chat, fn) })
+}
+
+// drainChat executa fn e as tarefas que chegarem para o chat até a fila esvaziar.
+func (s *WhatsAppService) drainChat(chat string, fn func()) {
+	for fn != nil {
+		fn()
+
+		s.chatMu.Lock()
+		if queue := s.chatQueues[chat]; len(queue) > 0 {
+			fn = queue[0]
+			s.chatQueues[chat] = queue[1:]
+		} else {
+			delete(s.chatQueues, chat)
+			fn = nil
+		}
+		s.chatMu.Unlock()
+	}
+}
@@ -0,0 +1,223 @@
+package clientservice
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	testChat  = "5511999999999@s.whatsapp.net"
+	otherChat = "5511888888888@s.whatsapp.net"
+)
+
+// menuConfig tem a regra "menu" que abre o fluxo main → vendas.
+func menuConfig() AutoResponderConfig {
+	return AutoResponderConfig{
+		Enabled:      true,
+		FlowTimeout:  "5m",
+		ExitKeywords: []string{"sair"},
+		Rules: []AutoReplyRule{
+			{Keywords: []string{"menu"}, Reply: AutoReply{Text: "Olá", Menu: "main"}},
+		},
+		Menus: map[string]AutoMenu{
+			"main": {
+				Text:    "1-Vendas 2-Suporte",
+				Invalid: "Opção inválida",
+				Options: []AutoMenuOption{
+					{Keys: []string{"1", "vendas"}, Reply: AutoReply{Text: "Vendas", Menu: "vendas"}},
+					{Keys: []string{"2"}, Reply: AutoReply{Text: "Suporte aberto"}},
+				},
+			},
+			"vendas": {
+				Text:    "a-Planos",
+				Options: []AutoMenuOption{{Keys: []string{"a"}, Reply: AutoReply{Text: "Planos"}}},
+			},
+		},
+	}
+}
+
+// hoursConfig atende seg 09:00-12:00 e 13:00-18:00; "urgente" responde a qualquer hora.
+func hoursConfig() AutoResponderConfig {
+	return AutoResponderConfig{
+		Enabled: true,
+		Rules: []AutoReplyRule{
+			{Keywords: []string{"oi"}, Reply: AutoReply{Text: "Olá"}},
+			{Match: MatchContains, Keywords: []string{"urgente"}, AnyTime: true, Reply: AutoReply{Text: "Plantão"}},
+		},
+		BusinessHours: &BusinessHours{
+			Days:         map[string][]string{"mon": {"09:00-12:00", "13:00-18:00"}},
+			AwayMessage:  "Fechado",
+			AwayCooldown: "1h",
+		},
+	}
+}
+
+func TestAutoResponderRespond(t *testing.T) {
+	location, err := time.LoadLocation(defaultTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	monday := time.Date(2026, 10, 19, 0, 0, 0, 0, location)
+	at := func(day, hour, minute int) time.Time {
+		return monday.AddDate(0, 0, day).Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	type step struct {
+		chat     string // padrão testChat
+		text     string
+		group    bool
+		at       time.Time
+		want     []string // textos das respostas, na ordem
+		noCommit bool     // simula falha no envio
+	}
+	tests := []struct {
+		name   string
+		config AutoResponderConfig
+		steps  []step
+	}{
+		{
+			name:   "menus encadeados",
+			config: menuConfig(),
+			steps: []step{
+				{text: "Menu", at: at(0, 10, 0), want: []string{"Olá", "1-Vendas 2-Suporte"}},
+				{text: "x", at: at(0, 10, 1), want: []string{"Opção inválida"}},
+				{text: "vendas", at: at(0, 10, 2), want: []string{"Vendas", "a-Planos"}},
+				{text: "A", at: at(0, 10, 3), want: []string{"Planos"}},
+				// Fluxo encerrado: a opção não vale mais fora do menu
+				{text: "a", at: at(0, 10, 4)},
+			},
+		},
+		{
+			name:   "menu é por chat",
+			config: menuConfig(),
+			steps: []step{
+				{text: "menu", at: at(0, 10, 0), want: []string{"Olá", "1-Vendas 2-Suporte"}},
+				{chat: otherChat, text: "2", at: at(0, 10, 1)},
+				{text: "2", at: at(0, 10, 2), want: []string{"Suporte aberto"}},
+			},
+		},
+		{
+			name:   "regra vale no meio do fluxo",
+			config: menuConfig(),
+			steps: []step{
+				{text: "menu", at: at(0, 10, 0), want: []string{"Olá", "1-Vendas 2-Suporte"}},
+				{text: "1", at: at(0, 10, 1), want: []string{"Vendas", "a-Planos"}},
+				{text: "menu", at: at(0, 10, 2), want: []string{"Olá", "1-Vendas 2-Suporte"}},
+				{text: "2", at: at(0, 10, 3), want: []string{"Suporte aberto"}},
+			},
+		},
+		{
+			name:   "palavra de saída encerra o fluxo",
+			config: menuConfig(),
+			steps: []step{
+				{text: "menu", at: at(0, 10, 0), want: []string{"Olá", "1-Vendas 2-Suporte"}},
+				{text: "SAIR", at: at(0, 10, 1)},
+				{text: "1", at: at(0, 10, 2)},
+			},
+		},
+		{
+			name:   "menu expira por inatividade",
+			config: menuConfig(),
+			steps: []step{
+				{text: "menu", at: at(0, 10, 0), want: []string{"Olá", "1-Vendas 2-Suporte"}},
+				{text: "1", at: at(0, 10, 6)},
+			},
+		},
+		{
+			name:   "opção inválida renova o prazo do menu",
+			config: menuConfig(),
+			steps: []step{
+				{text: "menu", at: at(0, 10, 0), want: []string{"Olá", "1-Vendas 2-Suporte"}},
+				{text: "x", at: at(0, 10, 4), want: []string{"Opção inválida"}},
+				{text: "1", at: at(0, 10, 8), want: []string{"Vendas", "a-Planos"}},
+			},
+		},
+		{
+			name:   "sem commit o chat continua onde estava",
+			config: menuConfig(),
+			steps: []step{
+				{text: "menu", at: at(0, 10, 0), want: []string{"Olá", "1-Vendas 2-Suporte"}, noCommit: true},
+				{text: "1", at: at(0, 10, 1)},
+			},
+		},
+		{
+			name:   "grupos só com groups ligado",
+			config: menuConfig(),
+			steps: []step{
+				{chat: "120363000000000000@g.us", text: "menu", group: true, at: at(0, 10, 0)},
+			},
+		},
+		{
+			name:   "faixas do horário de atendimento",
+			config: hoursConfig(),
+			steps: []step{
+				{text: "oi", at: at(0, 9, 0), want: []string{"Olá"}},
+				{text: "oi", at: at(0, 11, 59), want: []string{"Olá"}},
+				{text: "oi", at: at(0, 12, 0), want: []string{"Fechado"}},
+				{text: "oi", at: at(0, 13, 0), want: []string{"Olá"}},
+				{text: "oi", at: at(0, 17, 59), want: []string{"Olá"}},
+				{text: "oi", at: at(0, 18, 0), want: []string{"Fechado"}},
+				// Terça não tem faixa
+				{text: "oi", at: at(1, 10, 0), want: []string{"Fechado"}},
+			},
+		},
+		{
+			name:   "any_time responde fora do horário",
+			config: hoursConfig(),
+			steps: []step{
+				{text: "é urgente", at: at(0, 20, 0), want: []string{"Plantão"}},
+				{text: "oi", at: at(0, 20, 1), want: []string{"Fechado"}},
+			},
+		},
+		{
+			name:   "cooldown do aviso de ausência",
+			config: hoursConfig(),
+			steps: []step{
+				{text: "oi", at: at(0, 20, 0), want: []string{"Fechado"}},
+				{text: "oi", at: at(0, 20, 59)},
+				{chat: otherChat, text: "oi", at: at(0, 20, 59), want: []string{"Fechado"}},
+				{text: "oi", at: at(0, 21, 0), want: []string{"Fechado"}},
+			},
+		},
+		{
+			name:   "aviso não enviado não conta para o cooldown",
+			config: hoursConfig(),
+			steps: []step{
+				{text: "oi", at: at(0, 20, 0), want: []string{"Fechado"}, noCommit: true},
+				{text: "oi", at: at(0, 20, 1), want: []string{"Fechado"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ar, err := NewAutoResponder(filepath.Join(t.TempDir(), "autoresponder.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ar.SetConfig(tt.config); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, s := range tt.steps {
+				chat := s.chat
+				if chat == "" {
+					chat = testChat
+				}
+				replies, commit := ar.Respond(chat, s.text, s.group, s.at)
+				var got []string
+				for _, r := range replies {
+					got = append(got, r.Text)
+				}
+				if !slices.Equal(got, s.want) {
+					t.Fatalf("passo %d (%q às %s): respostas %q, quer %q", i, s.text, s.at.Format("Mon 15:04"), got, s.want)
+				}
+				if !s.noCommit {
+					commit()
+				}
+			}
+		})
+	}
+}
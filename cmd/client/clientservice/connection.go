@@ -0,0 +1,266 @@
+package clientservice
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Estados da conexão com o WhatsApp.
+const (
+	StateConnecting   = "connecting"
+	StateConnected    = "connected"
+	StateDisconnected = "disconnected"
+	StateLoggedOut    = "logged_out"
+	StateReplaced     = "replaced"
+	StateBanned       = "banned"
+)
+
+// Limites do backoff exponencial entre tentativas de reconexão.
+const (
+	reconnectMinDelay = 2 * time.Second
+	reconnectMaxDelay = 5 * time.Minute
+)
+
+// ConnectionStatus é o estado atual da conexão, exposto em /status e publicado como
+// evento "connection_state" a cada transição.
+type ConnectionStatus struct {
+	State       string     `json:"state"`
+	Since       time.Time  `json:"since"`
+	Reason      string     `json:"reason,omitempty"`
+	Attempts    int        `json:"attempts"` // tentativas de conexão desde a última conexão bem-sucedida
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+	LoggedIn    bool       `json:"logged_in"`
+	JID         string     `json:"jid,omitempty"`
+	Reconnect   bool       `json:"reconnect"` // há sessão e a instância vai (re)conectar sozinha
+}
+
+// Status retorna o estado atual da conexão.
+func (s *WhatsAppService) Status() ConnectionStatus {
+	s.connMu.Lock()
+	status := s.conn
+	want := s.wantConnected
+	s.connMu.Unlock()
+
+	cli := s.client.Load()
+	status.LoggedIn = cli.IsLoggedIn()
+	if id := cli.Store.ID; id != nil {
+		status.JID = id.String()
+		status.Reconnect = want
+	}
+	return status
+}
+
+// setState registra uma transição de estado e a publica no webhook de eventos.
+func (s *WhatsAppService) setState(state, reason string) {
+	s.connMu.Lock()
+	if s.conn.State == state && s.conn.Reason == reason {
+		s.connMu.Unlock()
+		return
+	}
+	s.conn.State = state
+	s.conn.Reason = reason
+	s.conn.Since = time.Now()
+	s.conn.NextRetryAt = nil
+	if state == StateConnected {
+		s.conn.Attempts = 0
+	}
+	s.connMu.Unlock()
+
+	setConnectionStateMetric(state)
+	slog.Info("🔌 Conexão", "state", state, "reason", reason)
+	s.publishEvent(s.ctx, "connection_state", s.Status())
+}
+
+// Connect estabelece a conexão com o WhatsApp e mantém o device conectado: quedas
+// inesperadas passam a ser reconectadas com backoff exponencial.
+func (s *WhatsAppService) Connect() error {
+	if s.IsConnected() {
+		return nil
+	}
+
+	s.connMu.Lock()
+	s.wantConnected = true
+	s.conn.Attempts++
+	s.connMu.Unlock()
+
+	s.setState(StateConnecting, "")
+	if err := s.client.Load().Connect(); err != nil {
+		s.setState(StateDisconnected, err.Error())
+		return err
+	}
+	return nil
+}
+
+// Disconnect encerra a conexão com o WhatsApp e desliga a reconexão automática.
+func (s *WhatsAppService) Disconnect() {
+	s.connMu.Lock()
+	s.wantConnected = false
+	s.connMu.Unlock()
+
+	s.client.Load().Disconnect()
+	s.setState(StateDisconnected, "")
+}
+
+// Shutdown encerra o device sem perder o que está saindo: espera os webhooks e as
+// respostas automáticas em andamento (até o prazo de ctx), esvazia os sinks (o que
+// não for entregue fica no spool para a próxima execução), fecha os streams de
+// /events e só então desconecta do WhatsApp.
+func (s *WhatsAppService) Shutdown(ctx context.Context) {
+	s.pendingMu.Lock()
+	s.draining = true
+	s.pendingMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.Warn("⚠️ Prazo de encerramento esgotado com envios em andamento")
+	}
+
+	s.sinks.Close()
+	s.events.Shutdown()
+	s.Disconnect()
+}
+
+// async roda fn em segundo plano; Shutdown espera essas tarefas antes de desconectar.
+func (s *WhatsAppService) async(fn func()) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	if s.draining {
+		// Já encerrando: a tarefa ainda roda, mas ninguém espera por ela
+		go fn()
+		return
+	}
+	s.pending.Go(fn)
+}
+
+// AutoConnect conecta em segundo plano quando já existe sessão salva (ex: após
+// reinício do container), sem esperar alguém abrir /connect/ws.
+func (s *WhatsAppService) AutoConnect() {
+	if !s.HasID() {
+		s.setState(StateLoggedOut, "sem sessão")
+		return
+	}
+	s.connMu.Lock()
+	s.wantConnected = true
+	s.connMu.Unlock()
+	go s.reconnectLoop()
+}
+
+// reconnectLoop tenta conectar até conseguir, esperando cada vez mais entre as tentativas.
+// Só um loop roda por vez; ele para se a sessão sumir ou se a reconexão for desligada.
+func (s *WhatsAppService) reconnectLoop() {
+	s.connMu.Lock()
+	if s.reconnecting {
+		s.connMu.Unlock()
+		return
+	}
+	s.reconnecting = true
+	s.connMu.Unlock()
+
+	defer func() {
+		s.connMu.Lock()
+		s.reconnecting = false
+		s.connMu.Unlock()
+	}()
+
+	for {
+		s.connMu.Lock()
+		want, attempts := s.wantConnected, s.conn.Attempts
+		s.connMu.Unlock()
+		if !want || !s.HasID() || s.IsConnected() {
+			return
+		}
+
+		if attempts > 0 {
+			delay := reconnectDelay(attempts)
+			next := time.Now().Add(delay)
+			s.connMu.Lock()
+			s.conn.NextRetryAt = &next
+			s.connMu.Unlock()
+			slog.Info("⏳ Reconectando", "delay", delay.Round(time.Second).String(), "attempt", attempts+1)
+
+			select {
+			case <-time.After(delay):
+			case <-s.ctx.Done():
+				return
+			}
+
+			s.connMu.Lock()
+			want = s.wantConnected
+			s.connMu.Unlock()
+			if !want {
+				return
+			}
+		}
+
+		reconnectAttempts.Inc()
+		if err := s.Connect(); err != nil {
+			slog.Warn("❌ Falha ao conectar", "attempt", attempts+1, "error", err)
+			continue
+		}
+		return
+	}
+}
+
+// reconnectDelay calcula o atraso da próxima tentativa: dobra a cada falha, até
+// reconnectMaxDelay, com até 20% de variação para não sincronizar reconexões.
+func reconnectDelay(attempts int) time.Duration {
+	delay := reconnectMaxDelay
+	if attempts < 16 {
+		delay = min(reconnectMinDelay<<(attempts-1), reconnectMaxDelay)
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay) / 5))
+	return delay - delay/10 + jitter
+}
+
+// handleConnectionEvent atualiza a máquina de estados a partir dos eventos do client.
+func (s *WhatsAppService) handleConnectionEvent(evt any) {
+	switch v := evt.(type) {
+	case *events.Connected:
+		slog.Info("✅ WhatsApp conectado com sucesso!")
+		s.setState(StateConnected, "")
+	case *events.Disconnected:
+		slog.Warn("❌ WhatsApp desconectado!")
+		s.setState(StateDisconnected, "conexão perdida")
+		go s.reconnectLoop()
+	case *events.ConnectFailure:
+		slog.Error("❌ Falha na conexão", "reason", v.Reason.String(), "message", v.Message)
+		s.setState(StateDisconnected, v.Reason.String())
+		go s.reconnectLoop()
+	case *events.StreamReplaced:
+		// Outra instância abriu a mesma sessão; reconectar só faria as duas se derrubarem
+		slog.Warn("⚠️ Sessão substituída em outro dispositivo.")
+		s.connMu.Lock()
+		s.wantConnected = false
+		s.connMu.Unlock()
+		s.setState(StateReplaced, "sessão aberta em outro lugar")
+	case *events.TemporaryBan:
+		slog.Error("⛔ Banimento temporário", "reason", v.String(), "expire", v.Expire.String())
+		s.connMu.Lock()
+		s.wantConnected = false
+		s.connMu.Unlock()
+		s.setState(StateBanned, v.String())
+		if v.Expire > 0 {
+			time.AfterFunc(v.Expire, func() {
+				if err := s.Connect(); err != nil {
+					slog.Error("❌ Falha ao reconectar após banimento", "error", err)
+				}
+			})
+		}
+	case *events.ClientOutdated:
+		slog.Error("⚠️ Versão do client desatualizada — atualize o whatsmeow.")
+		s.connMu.Lock()
+		s.wantConnected = false
+		s.connMu.Unlock()
+		s.setState(StateDisconnected, "client desatualizado")
+	}
+}
@@ -0,0 +1,108 @@
+package clientservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/logging"
+	"github.com/simpplify-org/GO-simpzap/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DeviceEvent é o envelope enviado ao webhook de eventos do device.
+type DeviceEvent struct {
+	Type      string    `json:"type"`
+	Device    string    `json:"device"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+	RequestID string    `json:"request_id,omitempty"` // Requisição que originou o evento, se houver
+}
+
+// SetEventWebhook define a URL que recebe os eventos do device (vazio desativa).
+func (s *WhatsAppService) SetEventWebhook(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.eventWebhookURL = url
+}
+
+// EventWebhook retorna a URL configurada para os eventos do device.
+func (s *WhatsAppService) EventWebhook() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.eventWebhookURL
+}
+
+// publishEvent encaminha um evento para o stream de /events e para o webhook de eventos, se configurado.
+func (s *WhatsAppService) publishEvent(ctx context.Context, eventType string, data any) {
+	s.stream(ctx, eventType, data)
+	s.postEvent(ctx, s.EventWebhook(), eventType, data)
+}
+
+// postEvent envia o evento de forma assíncrona para a URL informada (vazia ignora).
+// O request_id de ctx vai no payload e no X-Request-Id, junto com o traceparent.
+func (s *WhatsAppService) postEvent(ctx context.Context, url, eventType string, data any) {
+	s.postEventAuth(ctx, url, "", eventType, data)
+}
+
+// postEventAuth é o postEvent com um token Bearer (vazio não envia Authorization).
+func (s *WhatsAppService) postEventAuth(ctx context.Context, url, token, eventType string, data any) {
+	if url == "" {
+		return
+	}
+
+	evt := DeviceEvent{
+		Type:      eventType,
+		Device:    s.phoneNumber,
+		Timestamp: time.Now(),
+		Data:      data,
+		RequestID: logging.RequestID(ctx),
+	}
+
+	// O envio continua depois que a requisição de origem já respondeu
+	ctx = context.WithoutCancel(ctx)
+	s.async(func() {
+		payload, err := json.Marshal(evt)
+		if err != nil {
+			slog.ErrorContext(ctx, "Erro ao serializar evento", "event", eventType, "error", err)
+			return
+		}
+
+		ctx, span := tracing.Start(ctx, "webhook "+eventType, trace.WithSpanKind(trace.SpanKindClient))
+		start := time.Now()
+		resp, err := postJSON(ctx, url, token, payload)
+		if err != nil {
+			tracing.End(span, err)
+			observeWebhook("event", start, 0, err)
+			slog.WarnContext(ctx, "Erro ao enviar evento", "event", eventType, "url", url, "error", err)
+			return
+		}
+		resp.Body.Close()
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		span.End()
+		observeWebhook("event", start, resp.StatusCode, nil)
+
+		if resp.StatusCode >= 300 {
+			slog.WarnContext(ctx, "Webhook de eventos respondeu com erro", "event", eventType, "url", url, "status", resp.StatusCode)
+		}
+	})
+}
+
+// postJSON faz o POST propagando o X-Request-Id e o traceparent de ctx; token, se
+// informado, vai no Authorization.
+func postJSON(ctx context.Context, url, token string, payload []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	tracing.Inject(ctx, req.Header)
+	return http.DefaultClient.Do(req)
+}
@@ -0,0 +1,340 @@
+package clientservice
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// GroupParticipant é a visão de um participante exposta pela API.
+type GroupParticipant struct {
+	JID          string `json:"jid"`
+	PhoneNumber  string `json:"phone_number,omitempty"`
+	DisplayName  string `json:"display_name,omitempty"`
+	IsAdmin      bool   `json:"is_admin"`
+	IsSuperAdmin bool   `json:"is_super_admin"`
+}
+
+// Group é a visão de um grupo exposta pela API.
+type Group struct {
+	JID          string             `json:"jid"`
+	Name         string             `json:"name"`
+	Topic        string             `json:"topic,omitempty"`
+	Owner        string             `json:"owner,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	Participants []GroupParticipant `json:"participants"`
+}
+
+func newGroup(info *types.GroupInfo) Group {
+	g := Group{
+		JID:          info.JID.String(),
+		Name:         info.Name,
+		Topic:        info.Topic,
+		CreatedAt:    info.GroupCreated,
+		Participants: make([]GroupParticipant, 0, len(info.Participants)),
+	}
+	if !info.OwnerJID.IsEmpty() {
+		g.Owner = info.OwnerJID.String()
+	}
+
+	for _, p := range info.Participants {
+		gp := GroupParticipant{
+			JID:          p.JID.String(),
+			DisplayName:  p.DisplayName,
+			IsAdmin:      p.IsAdmin,
+			IsSuperAdmin: p.IsSuperAdmin,
+		}
+		if !p.PhoneNumber.IsEmpty() {
+			gp.PhoneNumber = p.PhoneNumber.User
+		}
+		g.Participants = append(g.Participants, gp)
+	}
+	return g
+}
+
+// ListGroups retorna os grupos dos quais o número participa.
+func (s *WhatsAppService) ListGroups() ([]Group, error) {
+	if !s.IsConnected() {
+		return nil, fmt.Errorf("cliente WhatsApp não conectado")
+	}
+
+	infos, err := s.client.Load().GetJoinedGroups(s.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar grupos: %w", err)
+	}
+
+	groups := make([]Group, 0, len(infos))
+	for _, info := range infos {
+		groups = append(groups, newGroup(info))
+	}
+	return groups, nil
+}
+
+// GetGroup retorna os dados de um grupo específico.
+func (s *WhatsAppService) GetGroup(group string) (Group, error) {
+	if !s.IsConnected() {
+		return Group{}, fmt.Errorf("cliente WhatsApp não conectado")
+	}
+
+	jid, err := parseGroupJID(group)
+	if err != nil {
+		return Group{}, err
+	}
+
+	info, err := s.client.Load().GetGroupInfo(s.ctx, jid)
+	if err != nil {
+		return Group{}, fmt.Errorf("erro ao obter grupo %s: %w", group, err)
+	}
+	return newGroup(info), nil
+}
+
+// parseGroupJID aceita "120363...@g.us" ou apenas o ID do grupo.
+func parseGroupJID(group string) (types.JID, error) {
+	group = strings.TrimSpace(group)
+	if group == "" {
+		return types.JID{}, fmt.Errorf("%w: grupo não informado", ErrInvalidRecipient)
+	}
+	if !strings.Contains(group, "@") {
+		return types.NewJID(group, types.GroupServer), nil
+	}
+
+	jid, err := types.ParseJID(group)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("%w: %v", ErrInvalidRecipient, err)
+	}
+	if jid.Server != types.GroupServer {
+		return types.JID{}, fmt.Errorf("%w: %s não é um grupo", ErrInvalidRecipient, group)
+	}
+	return jid, nil
+}
+
+// ParticipantResult é o resultado de uma alteração para cada participante.
+type ParticipantResult struct {
+	JID   string `json:"jid"`
+	Error int    `json:"error,omitempty"` // código retornado pelo WhatsApp (ex: 403 privacidade, 409 já participa)
+}
+
+var participantActions = map[string]whatsmeow.ParticipantChange{
+	"add":     whatsmeow.ParticipantChangeAdd,
+	"remove":  whatsmeow.ParticipantChangeRemove,
+	"promote": whatsmeow.ParticipantChangePromote,
+	"demote":  whatsmeow.ParticipantChangeDemote,
+}
+
+// resolveParticipants converte números/JIDs em JIDs de usuário.
+func (s *WhatsAppService) resolveParticipants(participants []string) ([]types.JID, error) {
+	jids := make([]types.JID, 0, len(participants))
+	for _, p := range participants {
+		jid, err := s.ResolveRecipient(p)
+		if err != nil {
+			return nil, fmt.Errorf("participante %s: %w", p, err)
+		}
+		if jid.Server == types.GroupServer {
+			return nil, fmt.Errorf("%w: participante %s é um grupo", ErrInvalidRecipient, p)
+		}
+		jids = append(jids, jid)
+	}
+	return jids, nil
+}
+
+// CreateGroup cria um grupo com os participantes informados.
+func (s *WhatsAppService) CreateGroup(name string, participants []string) (Group, error) {
+	if !s.IsConnected() {
+		return Group{}, fmt.Errorf("cliente WhatsApp não conectado")
+	}
+	if strings.TrimSpace(name) == "" {
+		return Group{}, fmt.Errorf("%w: nome do grupo não informado", ErrInvalidRequest)
+	}
+
+	jids, err := s.resolveParticipants(participants)
+	if err != nil {
+		return Group{}, err
+	}
+
+	info, err := s.client.Load().CreateGroup(s.ctx, whatsmeow.ReqCreateGroup{
+		Name:         name,
+		Participants: jids,
+	})
+	if err != nil {
+		return Group{}, fmt.Errorf("erro ao criar grupo: %w", err)
+	}
+	return newGroup(info), nil
+}
+
+// SetGroupName altera o nome do grupo.
+func (s *WhatsAppService) SetGroupName(group, name string) error {
+	jid, err := s.groupForAdmin(group)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Load().SetGroupName(s.ctx, jid, name); err != nil {
+		return fmt.Errorf("erro ao alterar nome do grupo %s: %w", group, err)
+	}
+	return nil
+}
+
+// SetGroupDescription altera a descrição (topic) do grupo.
+func (s *WhatsAppService) SetGroupDescription(group, description string) error {
+	jid, err := s.groupForAdmin(group)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Load().SetGroupTopic(s.ctx, jid, "", "", description); err != nil {
+		return fmt.Errorf("erro ao alterar descrição do grupo %s: %w", group, err)
+	}
+	return nil
+}
+
+// SetGroupPicture altera a foto do grupo. A imagem deve ser JPEG.
+func (s *WhatsAppService) SetGroupPicture(group string, image []byte) (string, error) {
+	jid, err := s.groupForAdmin(group)
+	if err != nil {
+		return "", err
+	}
+	pictureID, err := s.client.Load().SetGroupPhoto(s.ctx, jid, image)
+	if err != nil {
+		return "", fmt.Errorf("erro ao alterar foto do grupo %s: %w", group, err)
+	}
+	return pictureID, nil
+}
+
+// UpdateGroupParticipants adiciona, remove, promove ou rebaixa participantes.
+func (s *WhatsAppService) UpdateGroupParticipants(group, action string, participants []string) ([]ParticipantResult, error) {
+	change, ok := participantActions[action]
+	if !ok {
+		return nil, fmt.Errorf("%w: ação %q inválida (use add, remove, promote ou demote)", ErrInvalidRequest, action)
+	}
+
+	jid, err := s.groupForAdmin(group)
+	if err != nil {
+		return nil, err
+	}
+
+	jids, err := s.resolveParticipants(participants)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.client.Load().UpdateGroupParticipants(s.ctx, jid, jids, change)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao atualizar participantes do grupo %s: %w", group, err)
+	}
+
+	results := make([]ParticipantResult, 0, len(updated))
+	for _, p := range updated {
+		results = append(results, ParticipantResult{JID: p.JID.String(), Error: p.Error})
+	}
+	return results, nil
+}
+
+// GetGroupInviteLink retorna o link de convite; com reset, gera um novo e invalida o anterior.
+func (s *WhatsAppService) GetGroupInviteLink(group string, reset bool) (string, error) {
+	jid, err := s.groupForAdmin(group)
+	if err != nil {
+		return "", err
+	}
+	link, err := s.client.Load().GetGroupInviteLink(s.ctx, jid, reset)
+	if err != nil {
+		return "", fmt.Errorf("erro ao obter link de convite do grupo %s: %w", group, err)
+	}
+	return link, nil
+}
+
+// RevokeGroupInviteLink invalida o link de convite atual.
+func (s *WhatsAppService) RevokeGroupInviteLink(group string) error {
+	_, err := s.GetGroupInviteLink(group, true)
+	return err
+}
+
+// JoinGroup entra em um grupo pelo link (https://chat.whatsapp.com/<código>) ou código.
+func (s *WhatsAppService) JoinGroup(link string) (string, error) {
+	if !s.IsConnected() {
+		return "", fmt.Errorf("cliente WhatsApp não conectado")
+	}
+
+	code := strings.TrimSpace(link)
+	code = strings.TrimPrefix(code, "https://")
+	code = strings.TrimPrefix(code, "chat.whatsapp.com/")
+	if code == "" || strings.Contains(code, "/") {
+		return "", fmt.Errorf("%w: link de convite inválido", ErrInvalidRequest)
+	}
+
+	jid, err := s.client.Load().JoinGroupWithLink(s.ctx, code)
+	if err != nil {
+		return "", fmt.Errorf("erro ao entrar no grupo: %w", err)
+	}
+	return jid.String(), nil
+}
+
+// LeaveGroup sai do grupo.
+func (s *WhatsAppService) LeaveGroup(group string) error {
+	jid, err := s.groupForAdmin(group)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Load().LeaveGroup(s.ctx, jid); err != nil {
+		return fmt.Errorf("erro ao sair do grupo %s: %w", group, err)
+	}
+	return nil
+}
+
+// groupForAdmin valida a conexão e o JID antes de operações de administração.
+func (s *WhatsAppService) groupForAdmin(group string) (types.JID, error) {
+	if !s.IsConnected() {
+		return types.JID{}, fmt.Errorf("cliente WhatsApp não conectado")
+	}
+	return parseGroupJID(group)
+}
+
+// GroupEvent é o payload de eventos de grupo enviado ao webhook de eventos.
+type GroupEvent struct {
+	Group       string    `json:"group"`
+	Sender      string    `json:"sender,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	Name        *string   `json:"name,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Join        []string  `json:"join,omitempty"`
+	Leave       []string  `json:"leave,omitempty"`
+	Promote     []string  `json:"promote,omitempty"`
+	Demote      []string  `json:"demote,omitempty"`
+}
+
+func jidStrings(jids []types.JID) []string {
+	if len(jids) == 0 {
+		return nil
+	}
+	out := make([]string, len(jids))
+	for i, j := range jids {
+		out[i] = j.String()
+	}
+	return out
+}
+
+// handleGroupInfoEvent repassa alterações de grupo (nome, descrição, entradas e saídas).
+func (s *WhatsAppService) handleGroupInfoEvent(v *events.GroupInfo) {
+	evt := GroupEvent{
+		Group:     v.JID.String(),
+		Timestamp: v.Timestamp,
+		Join:      jidStrings(v.Join),
+		Leave:     jidStrings(v.Leave),
+		Promote:   jidStrings(v.Promote),
+		Demote:    jidStrings(v.Demote),
+	}
+	if v.Sender != nil {
+		evt.Sender = v.Sender.String()
+	}
+	if v.Name != nil {
+		evt.Name = &v.Name.Name
+	}
+	if v.Topic != nil {
+		evt.Description = &v.Topic.Topic
+	}
+
+	slog.Info("👥 Evento de grupo", "group", evt.Group, "joined", len(v.Join), "left", len(v.Leave))
+	s.publishEvent(s.ctx, "group_info", evt)
+}
@@ -0,0 +1,124 @@
+package clientservice
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// recordInbound registra no histórico as mensagens recebidas (e as enviadas pelo celular).
+func (s *WhatsAppService) recordInbound(v *events.Message) {
+	kind := messageType(v.Message)
+	if kind == "" {
+		return
+	}
+	if !v.Info.IsFromMe {
+		messagesReceived.WithLabelValues(kind).Inc()
+	}
+
+	record := MessageRecord{
+		ID:        v.Info.ID,
+		Chat:      v.Info.Chat.String(),
+		Sender:    v.Info.Sender.ToNonAD().String(),
+		Direction: DirectionInbound,
+		Type:      kind,
+		Text:      messageText(v.Message),
+		Media:     mediaRef(v.Message),
+		Timestamp: v.Info.Timestamp,
+		Status:    StatusReceived,
+		StatusAt:  v.Info.Timestamp,
+	}
+	if v.Info.IsFromMe {
+		// Enviada por outro aparelho da mesma conta
+		record.Direction = DirectionOutbound
+		record.Status = StatusSent
+	}
+
+	if err := s.messages.SaveIfAbsent(s.ctx, record); err != nil {
+		slog.Warn("⚠️ Erro ao registrar mensagem recebida", "message_id", record.ID, "error", err)
+	}
+	s.stream(s.ctx, "message", record)
+}
+
+// chatKey converte o chat informado (JID ou número) na chave usada no histórico.
+func (s *WhatsAppService) chatKey(chat string) (string, error) {
+	chat = strings.TrimSpace(chat)
+	if chat == "" {
+		return "", fmt.Errorf("%w: chat não informado", ErrInvalidRequest)
+	}
+	if strings.Contains(chat, "@") {
+		return chat, nil
+	}
+
+	jid, err := s.ResolveJID(chat)
+	if err != nil {
+		return "", err
+	}
+	return jid.String(), nil
+}
+
+// ListChats lista os chats do histórico com a última mensagem e o total não lido.
+func (s *WhatsAppService) ListChats(limit, offset int) ([]ChatSummary, error) {
+	return s.messages.ListChats(s.ctx, limit, offset)
+}
+
+// ListChatMessages pagina as mensagens de um chat, mais recentes primeiro, e
+// retorna o cursor da próxima página.
+func (s *WhatsAppService) ListChatMessages(chat string, before MessageCursor, limit int) ([]MessageRecord, MessageCursor, error) {
+	key, err := s.chatKey(chat)
+	if err != nil {
+		return nil, MessageCursor{}, err
+	}
+	return s.messages.ListMessages(s.ctx, key, before, limit)
+}
+
+// SearchMessages faz busca textual no histórico; chat vazio busca em todos.
+func (s *WhatsAppService) SearchMessages(query, chat string, limit int) ([]MessageRecord, error) {
+	if chat != "" {
+		key, err := s.chatKey(chat)
+		if err != nil {
+			return nil, err
+		}
+		chat = key
+	}
+	return s.messages.Search(s.ctx, query, chat, limit)
+}
+
+// MarkChatRead zera os não lidos do chat e, se conectado, envia o recibo de leitura ao WhatsApp.
+func (s *WhatsAppService) MarkChatRead(chat string) (int, error) {
+	key, err := s.chatKey(chat)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	read, err := s.messages.MarkChatRead(s.ctx, key, now)
+	if err != nil {
+		return 0, err
+	}
+	if len(read) == 0 || !s.IsConnected() {
+		return len(read), nil
+	}
+
+	chatJID, err := types.ParseJID(key)
+	if err != nil {
+		return len(read), nil
+	}
+
+	// Em grupos o recibo precisa do autor de cada mensagem
+	bySender := make(map[string][]types.MessageID)
+	for _, m := range read {
+		bySender[m.Sender] = append(bySender[m.Sender], m.ID)
+	}
+	for sender, ids := range bySender {
+		senderJID, _ := types.ParseJID(sender)
+		if err := s.client.Load().MarkRead(s.ctx, ids, now, chatJID, senderJID); err != nil {
+			slog.Warn("⚠️ Erro ao enviar recibo de leitura", "chat", key, "error", err)
+		}
+	}
+	return len(read), nil
+}
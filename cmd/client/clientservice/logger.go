@@ -0,0 +1,39 @@
+package clientservice
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// slogLogger adapta o log do whatsmeow para o slog, respeitando LOG_LEVEL e o
+// mascaramento configurado em pkg/logging.
+type slogLogger struct {
+	logger *slog.Logger
+	module string
+}
+
+// newWALogger cria o logger do whatsmeow para o módulo informado (Database, Client...).
+func newWALogger(module string) waLog.Logger {
+	return &slogLogger{logger: slog.Default().With("component", "whatsmeow", "module", module), module: module}
+}
+
+func (l *slogLogger) Errorf(msg string, args ...interface{}) { l.log(slog.LevelError, msg, args) }
+func (l *slogLogger) Warnf(msg string, args ...interface{})  { l.log(slog.LevelWarn, msg, args) }
+func (l *slogLogger) Infof(msg string, args ...interface{})  { l.log(slog.LevelInfo, msg, args) }
+func (l *slogLogger) Debugf(msg string, args ...interface{}) { l.log(slog.LevelDebug, msg, args) }
+
+func (l *slogLogger) Sub(module string) waLog.Logger {
+	module = l.module + "/" + module
+	return &slogLogger{logger: slog.Default().With("component", "whatsmeow", "module", module), module: module}
+}
+
+func (l *slogLogger) log(level slog.Level, msg string, args []interface{}) {
+	// Evita formatar mensagens de debug que serão descartadas
+	if !l.logger.Enabled(context.Background(), level) {
+		return
+	}
+	l.logger.Log(context.Background(), level, fmt.Sprintf(msg, args...))
+}
@@ -0,0 +1,99 @@
+package clientservice
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+var mediaClient = &http.Client{Timeout: 30 * time.Second}
+
+// SendMediaURL baixa o arquivo de mediaURL e o envia como imagem (image/*) ou documento.
+// caption acompanha a mídia; fileName vazio usa o nome do arquivo na URL.
+func (s *WhatsAppService) SendMediaURL(to, mediaURL, caption, fileName string) (SentMessage, error) {
+	if !s.IsConnected() {
+		return SentMessage{}, fmt.Errorf("cliente WhatsApp não conectado")
+	}
+
+	jid, err := s.ResolveRecipient(to)
+	if err != nil {
+		return SentMessage{}, err
+	}
+
+	data, mimetype, err := downloadMedia(mediaURL, s.cfg.MediaMaxMB)
+	if err != nil {
+		return SentMessage{}, err
+	}
+
+	if strings.HasPrefix(mimetype, "image/") {
+		uploaded, err := s.client.Load().Upload(s.ctx, data, whatsmeow.MediaImage)
+		if err != nil {
+			return SentMessage{}, fmt.Errorf("erro ao enviar mídia: %w", err)
+		}
+		return s.send(s.ctx, jid, "image", &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+			Mimetype:      proto.String(mimetype),
+			Caption:       proto.String(caption),
+		}})
+	}
+
+	if fileName == "" {
+		fileName = path.Base(strings.SplitN(mediaURL, "?", 2)[0])
+	}
+	uploaded, err := s.client.Load().Upload(s.ctx, data, whatsmeow.MediaDocument)
+	if err != nil {
+		return SentMessage{}, fmt.Errorf("erro ao enviar mídia: %w", err)
+	}
+	return s.send(s.ctx, jid, "document", &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{
+		URL:           proto.String(uploaded.URL),
+		DirectPath:    proto.String(uploaded.DirectPath),
+		MediaKey:      uploaded.MediaKey,
+		FileEncSHA256: uploaded.FileEncSHA256,
+		FileSHA256:    uploaded.FileSHA256,
+		FileLength:    proto.Uint64(uploaded.FileLength),
+		Mimetype:      proto.String(mimetype),
+		Title:         proto.String(fileName),
+		FileName:      proto.String(fileName),
+		Caption:       proto.String(caption),
+	}})
+}
+
+// downloadMedia baixa o arquivo (até maxMB) e descobre o mimetype (cabeçalho ou conteúdo).
+func downloadMedia(mediaURL string, maxMB int) ([]byte, string, error) {
+	maxSize := int64(maxMB) << 20
+	resp, err := mediaClient.Get(mediaURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("erro ao baixar %s: %w", mediaURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("erro ao baixar %s: status %d", mediaURL, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("erro ao baixar %s: %w", mediaURL, err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, "", fmt.Errorf("%w: %s passa de %d MB", ErrInvalidRequest, mediaURL, maxMB)
+	}
+
+	mimetype, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mimetype == "" || mimetype == "application/octet-stream" {
+		mimetype, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	return data, mimetype, nil
+}
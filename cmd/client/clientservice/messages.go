@@ -0,0 +1,124 @@
+package clientservice
+
+import (
+	"fmt"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// MessageRef aponta para uma mensagem já existente em um chat.
+type MessageRef struct {
+	Chat      string `json:"chat"`       // número, JID ou grupo onde a mensagem está
+	MessageID string `json:"message_id"` // ID retornado no envio ou recebido no evento
+	Sender    string `json:"sender"`     // autor da mensagem; obrigatório em grupos quando não é nossa
+	FromMe    bool   `json:"from_me"`    // a mensagem alvo foi enviada por este device
+}
+
+// resolveRef valida a referência e devolve o chat e o autor da mensagem alvo.
+func (s *WhatsAppService) resolveRef(ref MessageRef) (chat, author types.JID, err error) {
+	if !s.IsConnected() {
+		return chat, author, fmt.Errorf("cliente WhatsApp não conectado")
+	}
+	if ref.MessageID == "" {
+		return chat, author, fmt.Errorf("%w: message_id não informado", ErrInvalidRequest)
+	}
+
+	chat, err = s.ResolveRecipient(ref.Chat)
+	if err != nil {
+		return chat, author, err
+	}
+
+	switch {
+	case ref.FromMe:
+		id := s.client.Load().Store.ID
+		if id == nil {
+			return chat, author, fmt.Errorf("cliente WhatsApp sem sessão")
+		}
+		author = id.ToNonAD()
+	case ref.Sender != "":
+		author, err = s.ResolveRecipient(ref.Sender)
+		if err != nil {
+			return chat, author, err
+		}
+	case chat.Server == types.GroupServer:
+		return chat, author, fmt.Errorf("%w: informe sender ou from_me para mensagens de grupo", ErrInvalidRequest)
+	default:
+		// Conversa individual: a mensagem é do próprio contato
+		author = chat
+	}
+	return chat, author, nil
+}
+
+// Reply responde citando a mensagem alvo. quotedText é o conteúdo exibido na citação;
+// vazio usa o texto guardado no histórico.
+func (s *WhatsAppService) Reply(ref MessageRef, quotedText, message string, mentions ...string) (SentMessage, error) {
+	chat, author, err := s.resolveRef(ref)
+	if err != nil {
+		return SentMessage{}, err
+	}
+
+	if quotedText == "" {
+		if quoted, err := s.messages.Get(s.ctx, ref.MessageID); err == nil {
+			quotedText = quoted.Text
+		}
+	}
+
+	contextInfo := &waE2E.ContextInfo{}
+	if len(mentions) > 0 {
+		if contextInfo, err = s.mentionContext(mentions); err != nil {
+			return SentMessage{}, err
+		}
+	}
+	contextInfo.StanzaID = proto.String(ref.MessageID)
+	contextInfo.Participant = proto.String(author.String())
+	contextInfo.QuotedMessage = &waE2E.Message{Conversation: proto.String(quotedText)}
+
+	return s.send(s.ctx, chat, "text", &waE2E.Message{
+		ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+			Text:        proto.String(message),
+			ContextInfo: contextInfo,
+		},
+	})
+}
+
+// React reage à mensagem alvo com um emoji; emoji vazio remove a reação.
+func (s *WhatsAppService) React(ref MessageRef, emoji string) (SentMessage, error) {
+	chat, author, err := s.resolveRef(ref)
+	if err != nil {
+		return SentMessage{}, err
+	}
+	return s.send(s.ctx, chat, "reaction", s.client.Load().BuildReaction(chat, author, ref.MessageID, emoji))
+}
+
+// Edit substitui o texto de uma mensagem enviada por este device.
+func (s *WhatsAppService) Edit(ref MessageRef, message string) (SentMessage, error) {
+	ref.FromMe = true
+	chat, _, err := s.resolveRef(ref)
+	if err != nil {
+		return SentMessage{}, err
+	}
+
+	edit := s.client.Load().BuildEdit(chat, ref.MessageID, &waE2E.Message{Conversation: proto.String(message)})
+	return s.send(s.ctx, chat, "edit", edit)
+}
+
+// Revoke apaga a mensagem para todos. Mensagens de outros participantes só podem ser
+// apagadas por administradores do grupo.
+func (s *WhatsAppService) Revoke(ref MessageRef) (SentMessage, error) {
+	if ref.Sender == "" {
+		ref.FromMe = true
+	}
+
+	chat, author, err := s.resolveRef(ref)
+	if err != nil {
+		return SentMessage{}, err
+	}
+
+	if ref.FromMe {
+		// JID vazio = revogar a própria mensagem
+		author = types.EmptyJID
+	}
+	return s.send(s.ctx, chat, "revoke", s.client.Load().BuildRevoke(chat, author, ref.MessageID))
+}
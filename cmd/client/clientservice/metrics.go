@@ -0,0 +1,67 @@
+package clientservice
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var connectionStates = []string{StateConnecting, StateConnected, StateDisconnected, StateLoggedOut, StateReplaced, StateBanned}
+
+var (
+	messagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "whatsapp_messages_sent_total",
+		Help: "Mensagens enviadas com sucesso, por tipo.",
+	}, []string{"type"})
+
+	messagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "whatsapp_messages_failed_total",
+		Help: "Envios que falharam, por tipo.",
+	}, []string{"type"})
+
+	messagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "whatsapp_messages_received_total",
+		Help: "Mensagens recebidas, por tipo.",
+	}, []string{"type"})
+
+	webhookDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "whatsapp_webhook_dispatch_duration_seconds",
+		Help:    "Latência das chamadas de webhook (phrase = regras por frase, event = webhook de eventos), por resultado.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"webhook", "result"})
+
+	connectionState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "whatsapp_connection_state",
+		Help: "Estado atual da conexão com o WhatsApp (1 no estado ativo).",
+	}, []string{"state"})
+
+	reconnectAttempts = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "whatsapp_reconnect_attempts_total",
+		Help: "Tentativas de reconexão automática.",
+	})
+)
+
+// setConnectionStateMetric marca state como o estado ativo.
+func setConnectionStateMetric(state string) {
+	for _, st := range connectionStates {
+		value := 0.0
+		if st == state {
+			value = 1
+		}
+		connectionState.WithLabelValues(st).Set(value)
+	}
+}
+
+// observeWebhook registra a duração de uma chamada de webhook. result é "success",
+// "http_error" (status >= 300) ou "error" (sem resposta).
+func observeWebhook(webhook string, start time.Time, status int, err error) {
+	result := "success"
+	switch {
+	case err != nil:
+		result = "error"
+	case status >= 300:
+		result = "http_error"
+	}
+	webhookDuration.WithLabelValues(webhook, result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,179 @@
+package clientservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/phone"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// pairCodeTTL é o tempo que o WhatsApp mantém o socket de login aberto após conectar;
+// o código de pareamento deixa de valer quando os QR codes se esgotam.
+const pairCodeTTL = 160 * time.Second
+
+// ErrAlreadyPaired indica que o device já possui sessão e não precisa parear.
+var ErrAlreadyPaired = errors.New("device já pareado")
+
+// ErrPairingInProgress indica que outro login (QR ou código) já está em andamento.
+var ErrPairingInProgress = errors.New("pareamento já em andamento")
+
+// PairingEvent é enviado ao cliente durante o login (QR ou código de pareamento).
+type PairingEvent struct {
+	Event     string     `json:"event"`           // qr, pair_code, success, timeout, error
+	Image     string     `json:"image,omitempty"` // QR em data URL
+	Code      string     `json:"code,omitempty"`  // código de 8 caracteres para digitar no celular
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// StartPairing inicia o login de um device sem sessão. Por padrão emite QR codes; com
+// byCode gera um código de pareamento (PairPhone) para pairPhone, ou para o número do device.
+// Só um login roda por vez; os eventos param e o login é abandonado quando ctx é cancelado.
+func (s *WhatsAppService) StartPairing(ctx context.Context, byCode bool, pairPhone string) (<-chan PairingEvent, error) {
+	if s.HasID() {
+		return nil, ErrAlreadyPaired
+	}
+
+	if byCode {
+		if pairPhone == "" {
+			pairPhone = s.phoneNumber
+		}
+		normalized, err := phone.Normalize(pairPhone)
+		if err != nil {
+			return nil, err
+		}
+		pairPhone = normalized
+	}
+
+	if !s.pairing.CompareAndSwap(false, true) {
+		return nil, ErrPairingInProgress
+	}
+	// O canal QR da whatsmeow fecha quando pairCtx é cancelado
+	pairCtx, cancel := context.WithCancel(ctx)
+	cli, qrChan, err := s.openPairing(pairCtx)
+	if err != nil {
+		cancel()
+		s.pairing.Store(false)
+		return nil, err
+	}
+	connectedAt := time.Now()
+
+	out := make(chan PairingEvent, 4)
+	emit := func(evt PairingEvent) {
+		select {
+		case out <- evt:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		paired := false
+		defer func() {
+			cancel()
+			if !paired && !s.HasID() {
+				// Login abandonado (erro, timeout ou cliente saiu): fecha o socket de login
+				s.Disconnect()
+			}
+			s.pairing.Store(false)
+			close(out)
+		}()
+
+		codeSent := false
+		for {
+			var evt whatsmeow.QRChannelItem
+			select {
+			case item, ok := <-qrChan:
+				if !ok {
+					return
+				}
+				evt = item
+			case <-pairCtx.Done():
+				return
+			}
+
+			switch evt.Event {
+			case "code":
+				if !byCode {
+					dataURL, err := EncodeQRToDataURL(evt.Code, s.cfg.QRSize)
+					if err != nil {
+						slog.Error("Erro ao codificar QR", "error", err)
+						emit(PairingEvent{Event: "error", Error: "Erro ao gerar QR Code"})
+						continue
+					}
+					emit(PairingEvent{Event: "qr", Image: dataURL})
+					continue
+				}
+				if codeSent {
+					// O código continua válido enquanto o socket de login estiver aberto
+					continue
+				}
+
+				code, err := cli.PairPhone(s.ctx, pairPhone, true, whatsmeow.PairClientChrome, "Chrome (Linux)")
+				if err != nil {
+					slog.Error("❌ Erro ao gerar código de pareamento", "phone", pairPhone, "error", err)
+					emit(PairingEvent{Event: "error", Error: err.Error()})
+					return
+				}
+				codeSent = true
+				expiresAt := connectedAt.Add(pairCodeTTL)
+				slog.Info("🔢 Código de pareamento gerado", "phone", pairPhone)
+				emit(PairingEvent{Event: "pair_code", Code: code, ExpiresAt: &expiresAt})
+			case "success":
+				paired = true
+				emit(PairingEvent{Event: "success"})
+			case "timeout":
+				emit(PairingEvent{Event: "timeout"})
+			default:
+				msg := evt.Event
+				if evt.Error != nil {
+					msg = evt.Error.Error()
+				}
+				emit(PairingEvent{Event: "error", Error: msg})
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// openPairing desconecta o client (o canal QR precisa ser aberto antes de conectar,
+// senão a whatsmeow retorna "GetQRChannel must be called before connecting") e abre
+// o socket de login. Retorna o client do canal, usado no resto do login.
+func (s *WhatsAppService) openPairing(ctx context.Context) (*whatsmeow.Client, <-chan whatsmeow.QRChannelItem, error) {
+	if s.IsConnected() {
+		s.Disconnect()
+	}
+	cli := s.client.Load()
+	qrChan, err := cli.GetQRChannel(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.Connect(); err != nil {
+		return nil, nil, err
+	}
+	return cli, qrChan, nil
+}
+
+// handlePairEvent repassa o resultado do pareamento ao webhook de eventos.
+func (s *WhatsAppService) handlePairEvent(evt any) {
+	switch v := evt.(type) {
+	case *events.PairSuccess:
+		slog.Info("✅ Pareado com sucesso", "jid", v.ID.String(), "platform", v.Platform)
+		s.publishEvent(s.ctx, "pair_success", map[string]string{
+			"jid":      v.ID.String(),
+			"platform": v.Platform,
+		})
+		s.notifySession(SessionState{State: SessionPaired, JID: v.ID.String()})
+	case *events.PairError:
+		slog.Error("❌ Falha no pareamento", "jid", v.ID.String(), "error", v.Error)
+		s.publishEvent(s.ctx, "pair_error", map[string]string{
+			"jid":   v.ID.String(),
+			"error": fmt.Sprint(v.Error),
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package clientservice
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/logging"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// receiptStatus traduz o tipo de recibo para o status da mensagem enviada.
+var receiptStatus = map[types.ReceiptType]string{
+	types.ReceiptTypeDelivered:   StatusDelivered,
+	types.ReceiptTypeRead:        StatusRead,
+	types.ReceiptTypePlayed:      StatusPlayed,
+	types.ReceiptTypeServerError: StatusFailed,
+}
+
+// SetStatusWebhook define a URL que recebe as mudanças de status (vazio desativa).
+func (s *WhatsAppService) SetStatusWebhook(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statusWebhookURL = url
+}
+
+// StatusWebhook retorna a URL configurada para mudanças de status.
+func (s *WhatsAppService) StatusWebhook() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.statusWebhookURL
+}
+
+// GetMessage retorna a mensagem registrada com o ID informado.
+func (s *WhatsAppService) GetMessage(id string) (MessageRecord, error) {
+	return s.messages.Get(s.ctx, id)
+}
+
+// recordOutbound registra a mensagem enviada e notifica o status inicial.
+func (s *WhatsAppService) recordOutbound(ctx context.Context, m MessageRecord) {
+	if err := s.messages.Save(s.ctx, m); err != nil {
+		slog.WarnContext(ctx, "⚠️ Erro ao registrar mensagem", "message_id", m.ID, "error", err)
+		return
+	}
+	s.postEvent(ctx, s.StatusWebhook(), "message_status", m)
+}
+
+// handleReceipt avança o status das mensagens enviadas conforme os recibos chegam.
+func (s *WhatsAppService) handleReceipt(v *events.Receipt) {
+	status, ok := receiptStatus[v.Type]
+	if !ok || v.IsFromMe {
+		// Recibos de outros aparelhos da mesma conta (read-self, sender, etc.)
+		return
+	}
+
+	changed, err := s.messages.AdvanceStatus(s.ctx, v.MessageIDs, status, v.Timestamp)
+	if err != nil {
+		slog.Warn("⚠️ Erro ao atualizar status das mensagens", "message_ids", v.MessageIDs, "error", err)
+	}
+
+	for _, m := range changed {
+		// Recibos chegam depois; o request_id gravado liga o status à requisição de envio
+		ctx := logging.WithRequestID(s.ctx, m.RequestID)
+		s.stream(ctx, "message_status", m)
+		s.postEvent(ctx, s.StatusWebhook(), "message_status", m)
+	}
+}
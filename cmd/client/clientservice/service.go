@@ -1,21 +1,32 @@
 package clientservice
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	_ "github.com/mattn/go-sqlite3"
 	"io"
-	"log"
-	"net/http"
+	"log/slog"
+	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/simpplify-org/GO-simpzap/pkg/config"
+	"github.com/simpplify-org/GO-simpzap/pkg/eventhub"
+	"github.com/simpplify-org/GO-simpzap/pkg/logging"
+	"github.com/simpplify-org/GO-simpzap/pkg/phone"
+	"github.com/simpplify-org/GO-simpzap/pkg/tracing"
 	"github.com/skip2/go-qrcode"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/store"
 	"go.mau.fi/whatsmeow/store/sqlstore"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
@@ -23,6 +34,15 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// ErrNotOnWhatsApp indica que nenhuma variação do número possui conta no WhatsApp.
+var ErrNotOnWhatsApp = errors.New("número não está registrado no WhatsApp")
+
+// ErrInvalidRecipient indica um destinatário que não é número nem JID suportado.
+var ErrInvalidRecipient = errors.New("destinatário inválido")
+
+// ErrInvalidRequest indica parâmetros inválidos que não dependem do WhatsApp.
+var ErrInvalidRequest = errors.New("requisição inválida")
+
 // WebhookRule representa uma regra de webhook para uma frase específica.
 type WebhookRule struct {
 	Phrase      string
@@ -31,118 +51,312 @@ type WebhookRule struct {
 
 // WhatsAppService encapsula a lógica de conexão e interação com o WhatsApp.
 type WhatsAppService struct {
-	client      *whatsmeow.Client
-	ctx         context.Context
-	phoneNumber string
-	dbLog       waLog.Logger
-	clientLog   waLog.Logger
-	dbContainer *sqlstore.Container
-	webhooks    map[string][]WebhookRule // Mapeia número de telefone para regras de webhook
-	mu          sync.RWMutex             // Mutex para proteger o mapa de webhooks
+	client           atomic.Pointer[whatsmeow.Client] // Trocado em setClient; cada operação usa um único Load
+	handlerID        uint32                           // Handler de eventos registrado no client atual (clientMu)
+	clientMu         sync.Mutex                       // Serializa a troca do client e o ResetSession
+	ctx              context.Context
+	phoneNumber      string
+	cfg              config.Client
+	dbLog            waLog.Logger
+	clientLog        waLog.Logger
+	dbContainer      *sqlstore.Container
+	webhooks         map[string][]WebhookRule // Mapeia número de telefone para regras de webhook
+	mu               sync.RWMutex             // Mutex para proteger o mapa de webhooks e o webhook de eventos
+	eventWebhookURL  string                   // Recebe eventos do device (grupos, etc.)
+	statusWebhookURL string                   // Recebe mudanças de status das mensagens enviadas
+	masterURL        string                   // Master avisado quando a sessão muda
+	masterToken      string                   // Token do device para o master (MASTER_TOKEN)
+	messages         *MessageStore            // Histórico local de mensagens
+	events           *eventhub.Hub            // Stream de eventos em tempo real (/events)
+	sinks            *SinkManager             // Destinos extras dos eventos (arquivo, HTTP, Redis)
+	autoResponder    *AutoResponder           // Respostas automáticas e menus
+	jids             map[string]types.JID     // Cache número normalizado -> JID confirmado no WhatsApp
+	jidsMu           sync.RWMutex
+	chatQueues       map[string][]func() // Respostas automáticas aguardando, por chat
+	chatMu           sync.Mutex
+	conn             ConnectionStatus // Máquina de estados da conexão
+	wantConnected    bool             // Reconecta sozinho após quedas enquanto true
+	reconnecting     bool             // Há um loop de reconexão em andamento
+	connMu           sync.Mutex
+	pending          sync.WaitGroup // Webhooks e respostas automáticas ainda em andamento
+	draining         bool           // Shutdown chamado: pending não recebe novas tarefas
+	pendingMu        sync.Mutex
+	pairing          atomic.Bool // Há um login (QR ou código) em andamento
 }
 
-// NewWhatsAppService é o construtor para WhatsAppService.
-func NewWhatsAppService(ctx context.Context, phoneNumber string) (*WhatsAppService, error) {
-	dbLog := waLog.Stdout("Database", "DEBUG", true)
-	clientLog := waLog.Stdout("Client", "DEBUG", true)
+// NewWhatsAppService é o construtor para WhatsAppService. Os bancos e arquivos do
+// device ficam em cfg.DataDir.
+func NewWhatsAppService(ctx context.Context, cfg config.Client) (*WhatsAppService, error) {
+	dbLog := newWALogger("Database")
+	clientLog := newWALogger("Client")
+
+	phoneNumber := cfg.PhoneNumber
+	if normalized, err := phone.Normalize(phoneNumber); err == nil {
+		phoneNumber = normalized
+	}
 
-	container, err := sqlstore.New(ctx, "sqlite3", "file:device.db?_foreign_keys=on", dbLog)
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório de dados: %w", err)
+	}
+
+	container, err := sqlstore.New(ctx, "sqlite3", "file:"+cfg.Path("device.db")+"?_foreign_keys=on", dbLog)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao abrir sqlstore: %w", err)
 	}
 
+	messages, err := NewMessageStore(ctx, "file:"+cfg.Path("messages.db")+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+
+	autoResponder, err := NewAutoResponder(cfg.Path("autoresponder.json"))
+	if err != nil {
+		return nil, err
+	}
+
 	service := &WhatsAppService{
-		ctx:         ctx,
-		phoneNumber: phoneNumber,
-		dbLog:       dbLog,
-		clientLog:   clientLog,
-		dbContainer: container,
-		webhooks:    make(map[string][]WebhookRule),
+		ctx:           ctx,
+		phoneNumber:   phoneNumber,
+		cfg:           cfg,
+		dbLog:         dbLog,
+		clientLog:     clientLog,
+		dbContainer:   container,
+		webhooks:      make(map[string][]WebhookRule),
+		jids:          make(map[string]types.JID),
+		messages:      messages,
+		events:        eventhub.New(streamBufferSize, subscriberBufferSize),
+		sinks:         NewSinkManager(cfg.Path("spool"), cfg.Path("sinks.json")),
+		autoResponder: autoResponder,
+		chatQueues:    make(map[string][]func()),
+		conn:          ConnectionStatus{State: StateDisconnected, Since: time.Now()},
 	}
 
+	setConnectionStateMetric(StateDisconnected)
+
 	err = service.initClient()
 	if err != nil {
 		return nil, err
 	}
-
-	service.client.AddEventHandler(service.eventHandler)
+	go autoResponder.Run(ctx)
 
 	return service, nil
 }
 
 func (s *WhatsAppService) initClient() error {
-	jid, err := types.ParseJID(s.phoneNumber + types.DefaultUserServer)
-	if err != nil {
-		return fmt.Errorf("JID inválido: %w", err)
-	}
-
-	deviceStore, err := s.dbContainer.GetDevice(s.ctx, jid)
-	if err != nil {
-		return fmt.Errorf("erro ao obter device: %w", err)
+	var deviceStore *store.Device
+	// A sessão pode ter sido pareada com ou sem o nono dígito
+	for _, number := range phone.Variants(s.phoneNumber) {
+		var err error
+		deviceStore, err = s.dbContainer.GetDevice(s.ctx, types.NewJID(number, types.DefaultUserServer))
+		if err != nil {
+			return fmt.Errorf("erro ao obter device: %w", err)
+		}
+		if deviceStore != nil {
+			break
+		}
 	}
 	if deviceStore == nil {
 		// Cria um device novo vazio — não reutiliza sessão de outro número
 		deviceStore = s.dbContainer.NewDevice()
 	}
 
-	s.client = whatsmeow.NewClient(deviceStore, s.clientLog)
+	s.setClient(deviceStore)
 	return nil
 }
 
-// Connect estabelece a conexão com o WhatsApp.
-func (s *WhatsAppService) Connect() error {
-	return s.client.Connect()
+// IsConnected verifica se o cliente está conectado.
+func (s *WhatsAppService) IsConnected() bool {
+	return s.client.Load().IsConnected()
 }
 
-// Disconnect encerra a conexão com o WhatsApp.
-func (s *WhatsAppService) Disconnect() {
-	s.client.Disconnect()
+// HasID verifica se o cliente já possui uma ID de sessão.
+func (s *WhatsAppService) HasID() bool {
+	return s.client.Load().Store.ID != nil
 }
 
-// IsConnected verifica se o cliente está conectado.
-func (s *WhatsAppService) IsConnected() bool {
-	return s.client.IsConnected()
+// SentMessage identifica uma mensagem enviada; ID e Chat servem de alvo para
+// respostas, reações, edições e revogações.
+type SentMessage struct {
+	ID        string    `json:"id"`
+	Chat      string    `json:"chat"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
-// GetQRChannel retorna o canal QR para autenticação.
-func (s *WhatsAppService) GetQRChannel() (<-chan whatsmeow.QRChannelItem, error) {
-	return s.client.GetQRChannel(s.ctx)
+// SendMessage envia uma mensagem de texto para um número, JID completo ou grupo (@g.us).
+// Os números/JIDs em mentions são marcados na mensagem; o texto deve conter "@<número>"
+// para que o WhatsApp exiba a menção.
+func (s *WhatsAppService) SendMessage(to, message string, mentions ...string) (SentMessage, error) {
+	return s.SendMessageContext(s.ctx, to, message, mentions...)
 }
 
-// HasID verifica se o cliente já possui uma ID de sessão.
-func (s *WhatsAppService) HasID() bool {
-	return s.client.Store.ID != nil
+// SendMessageContext é o SendMessage de uma requisição: o request_id e o trace de ctx
+// ficam no histórico, nos logs e nos eventos de status da mensagem.
+func (s *WhatsAppService) SendMessageContext(ctx context.Context, to, message string, mentions ...string) (SentMessage, error) {
+	if !s.IsConnected() {
+		return SentMessage{}, fmt.Errorf("cliente WhatsApp não conectado")
+	}
+
+	jid, err := s.ResolveRecipient(to)
+	if err != nil {
+		return SentMessage{}, err
+	}
+
+	text := &waE2E.ExtendedTextMessage{
+		Text: proto.String(message),
+	}
+
+	if len(mentions) > 0 {
+		text.ContextInfo, err = s.mentionContext(mentions)
+		if err != nil {
+			return SentMessage{}, err
+		}
+	}
+
+	return s.send(ctx, jid, "text", &waE2E.Message{ExtendedTextMessage: text})
 }
 
-// SendMessage envia uma mensagem de texto para um número.
-func (s *WhatsAppService) SendMessage(number, message string) (whatsmeow.SendResponse, error) {
-	if !s.IsConnected() {
-		return whatsmeow.SendResponse{}, fmt.Errorf("cliente WhatsApp não conectado")
+// mentionContext resolve os números/JIDs mencionados para o ContextInfo.
+func (s *WhatsAppService) mentionContext(mentions []string) (*waE2E.ContextInfo, error) {
+	mentioned := make([]string, 0, len(mentions))
+	for _, m := range mentions {
+		mjid, err := s.ResolveRecipient(m)
+		if err != nil {
+			return nil, fmt.Errorf("menção inválida %s: %w", m, err)
+		}
+		mentioned = append(mentioned, mjid.String())
 	}
+	return &waE2E.ContextInfo{MentionedJID: mentioned}, nil
+}
 
-	jid := types.NewJID(number, types.DefaultUserServer)
-	msg := &waE2E.Message{
-		ExtendedTextMessage: &waE2E.ExtendedTextMessage{
-			Text: proto.String(message),
-		},
+// send é o ponto único de envio usado por todos os tipos de mensagem. O ID é gerado
+// antes do envio para que falhas também fiquem registradas no histórico.
+func (s *WhatsAppService) send(ctx context.Context, jid types.JID, kind string, msg *waE2E.Message) (SentMessage, error) {
+	cli := s.client.Load()
+	id := cli.GenerateMessageID()
+	ctx, span := tracing.Start(ctx, "whatsapp.send", trace.WithAttributes(
+		attribute.String("message.type", kind),
+		attribute.String("message.id", id),
+	))
+	// O envio usa o contexto do serviço: uma requisição cancelada não interrompe o envio pela metade
+	resp, err := cli.SendMessage(s.ctx, jid, msg, whatsmeow.SendRequestExtra{ID: id})
+	tracing.End(span, err)
+
+	now := time.Now()
+	record := MessageRecord{
+		ID:        id,
+		Chat:      jid.String(),
+		Direction: DirectionOutbound,
+		Type:      kind,
+		Text:      messageText(msg),
+		Timestamp: now,
+		Status:    StatusSent,
+		StatusAt:  now,
+		RequestID: logging.RequestID(ctx),
+	}
+	if err != nil {
+		messagesFailed.WithLabelValues(kind).Inc()
+		record.Status = StatusFailed
+		record.Error = err.Error()
+	} else {
+		messagesSent.WithLabelValues(kind).Inc()
 	}
+	if err == nil && !resp.Timestamp.IsZero() {
+		record.Timestamp = resp.Timestamp
+		record.StatusAt = resp.Timestamp
+	}
+	s.recordOutbound(ctx, record)
 
-	resp, err := s.client.SendMessage(s.ctx, jid, msg)
 	if err != nil {
-		return whatsmeow.SendResponse{}, fmt.Errorf("erro ao enviar mensagem para %s: %w", number, err)
+		return SentMessage{}, fmt.Errorf("erro ao enviar mensagem para %s: %w", jid, err)
 	}
 
-	return resp, nil
+	return SentMessage{
+		ID:        resp.ID,
+		Chat:      jid.String(),
+		Timestamp: resp.Timestamp,
+	}, nil
+}
+
+// ResolveRecipient aceita um JID completo ("...@s.whatsapp.net", "...@g.us", "...@lid")
+// ou um número de telefone, que passa pela normalização de ResolveJID.
+func (s *WhatsAppService) ResolveRecipient(to string) (types.JID, error) {
+	to = strings.TrimSpace(to)
+	if !strings.Contains(to, "@") {
+		return s.ResolveJID(to)
+	}
+
+	jid, err := types.ParseJID(to)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("%w: %v", ErrInvalidRecipient, err)
+	}
+
+	switch jid.Server {
+	case types.DefaultUserServer, types.GroupServer, types.HiddenUserServer:
+		if jid.User == "" {
+			return types.JID{}, fmt.Errorf("%w: %s", ErrInvalidRecipient, to)
+		}
+		return jid, nil
+	default:
+		return types.JID{}, fmt.Errorf("%w: servidor %q não suportado", ErrInvalidRecipient, jid.Server)
+	}
+}
+
+// ResolveJID normaliza o número e descobre com qual JID ele está registrado no WhatsApp.
+//
+// Para celulares brasileiros a conta pode existir com ou sem o nono dígito, então as
+// variações são consultadas via IsOnWhatsApp e o resultado fica em cache.
+func (s *WhatsAppService) ResolveJID(number string) (types.JID, error) {
+	normalized, err := phone.Normalize(number)
+	if err != nil {
+		return types.JID{}, err
+	}
+
+	variants := phone.Variants(normalized)
+	if len(variants) == 1 {
+		return types.NewJID(normalized, types.DefaultUserServer), nil
+	}
+
+	s.jidsMu.RLock()
+	jid, ok := s.jids[normalized]
+	s.jidsMu.RUnlock()
+	if ok {
+		return jid, nil
+	}
+
+	queries := make([]string, len(variants))
+	for i, v := range variants {
+		queries[i] = "+" + v
+	}
+
+	results, err := s.client.Load().IsOnWhatsApp(s.ctx, queries)
+	if err != nil {
+		// Sem como confirmar, segue com a forma canônica
+		slog.Warn("⚠️ Não foi possível consultar IsOnWhatsApp", "phone", normalized, "error", err)
+		return types.NewJID(normalized, types.DefaultUserServer), nil
+	}
+
+	for _, v := range variants {
+		for _, res := range results {
+			if res.IsIn && res.JID.User == v {
+				s.jidsMu.Lock()
+				s.jids[normalized] = res.JID
+				s.jidsMu.Unlock()
+				return res.JID, nil
+			}
+		}
+	}
+
+	return types.JID{}, fmt.Errorf("%w: %s", ErrNotOnWhatsApp, normalized)
 }
 
 // sendInternalMessage é uma função auxiliar para enviar mensagens de status internas.
-func (s *WhatsAppService) sendInternalMessage(number, message string) {
-	resp, err := s.SendMessage(number, message)
+func (s *WhatsAppService) sendInternalMessage(ctx context.Context, number, message string) {
+	resp, err := s.SendMessageContext(ctx, number, message)
 	if err != nil {
-		log.Printf("❌ Erro ao enviar mensagem interna para %s: %v\n", number, err)
+		slog.ErrorContext(ctx, "❌ Erro ao enviar mensagem interna", "phone", number, "error", err)
 		return
 	}
-	log.Printf("✅ Mensagem interna enviada para %s (ID: %s)\n", number, resp.ID)
+	slog.InfoContext(ctx, "✅ Mensagem interna enviada", "phone", number, "message_id", resp.ID)
 }
 
 // eventHandler manipula os eventos do WhatsApp.
@@ -150,41 +364,66 @@ func (s *WhatsAppService) eventHandler(evt interface{}) {
 	switch v := evt.(type) {
 	case *events.Message:
 		s.handleMessageEvent(v)
-	case *events.Connected:
-		log.Println("✅ WhatsApp conectado com sucesso!")
-	case *events.Disconnected:
-		log.Println("❌ WhatsApp desconectado!")
-	case *events.StreamReplaced:
-		log.Println("⚠️ Sessão substituída em outro dispositivo.")
+	case *events.Receipt:
+		s.handleReceipt(v)
+	case *events.GroupInfo:
+		s.handleGroupInfoEvent(v)
+	case *events.JoinedGroup:
+		s.publishEvent(s.ctx, "group_joined", newGroup(&v.GroupInfo))
+	case *events.PairSuccess, *events.PairError:
+		s.handlePairEvent(v)
+	case *events.Connected, *events.Disconnected, *events.ConnectFailure,
+		*events.StreamReplaced, *events.TemporaryBan, *events.ClientOutdated:
+		s.handleConnectionEvent(v)
 	case *events.LoggedOut:
-		log.Println("🚪 Logout realizado — sessão expirada.")
+		s.handleLoggedOut(v)
+	case *events.Presence, *events.ChatPresence:
+		s.handlePresenceEvent(v)
 	default:
-		// log.Printf("🌀 Evento: %+v\n", v) // Comentado para reduzir o ruído do log
+		// slog.Debug("🌀 Evento", "event", fmt.Sprintf("%T", v)) // Comentado para reduzir o ruído do log
 	}
 }
 
 // handleMessageEvent processa eventos de mensagem e dispara webhooks.
 func (s *WhatsAppService) handleMessageEvent(v *events.Message) {
 	number := v.Info.Sender.User
+	// O usuário do JID sempre traz o DDI; sem o "+", um número estrangeiro viraria brasileiro
+	if normalized, err := phone.Normalize("+" + number); err == nil {
+		number = normalized
+	}
 	text := v.Message.GetConversation()
 
-	fmt.Printf("[%s] %s\n", number, text)
+	slog.Debug("📥 Mensagem recebida", "phone", number, "message_id", v.Info.ID, "body", text)
+
+	s.recordInbound(v)
 
 	s.mu.RLock()
 	rules, ok := s.webhooks[number]
 	s.mu.RUnlock()
 
+	dispatched := false
 	if ok {
 		for _, rule := range rules {
 			if rule.Phrase == text {
-				go s.dispatchWebhook(rule, number, text)
+				dispatched = true
+				s.async(func() { s.dispatchWebhook(rule, number, text, v.Info.ID) })
 			}
 		}
 	}
+
+	// Mensagens que já acionaram um webhook por frase não recebem resposta automática
+	if !dispatched && !v.Info.IsFromMe {
+		s.enqueueChat(v.Info.Chat.String(), func() { s.autoRespond(v) })
+	}
 }
 
-// dispatchWebhook envia a requisição para o URL do webhook.
-func (s *WhatsAppService) dispatchWebhook(rule WebhookRule, number, text string) {
+// dispatchWebhook envia a requisição para o URL do webhook. Cada disparo ganha um
+// request_id próprio, enviado no X-Request-Id e usado nas respostas ao contato.
+func (s *WhatsAppService) dispatchWebhook(rule WebhookRule, number, text, messageID string) {
+	ctx := logging.WithRequestID(s.ctx, logging.NewRequestID())
+	ctx, span := tracing.Start(ctx, "webhook phrase", trace.WithAttributes(attribute.String("message.id", messageID)))
+	defer span.End()
+
 	body := map[string]string{
 		"number":  number,
 		"message": text,
@@ -192,23 +431,28 @@ func (s *WhatsAppService) dispatchWebhook(rule WebhookRule, number, text string)
 
 	payload, err := json.Marshal(body)
 	if err != nil {
-		log.Printf("Erro ao serializar payload do webhook: %v", err)
+		slog.ErrorContext(ctx, "Erro ao serializar payload do webhook", "error", err)
 		return
 	}
 
-	s.sendInternalMessage(number, "Solicitação recebida, aguarde...")
+	s.sendInternalMessage(ctx, number, "Solicitação recebida, aguarde...")
 
-	resp, err := http.Post(rule.CallbackURL, "application/json", bytes.NewBuffer(payload))
+	start := time.Now()
+	resp, err := postJSON(ctx, rule.CallbackURL, "", payload)
 	if err != nil {
-		log.Printf("Erro ao chamar webhook %s: %v", rule.CallbackURL, err)
-		s.sendInternalMessage(number, "Não foi possível se comunicar com o servidor intermediário: "+rule.CallbackURL)
+		span.RecordError(err)
+		observeWebhook("phrase", start, 0, err)
+		slog.ErrorContext(ctx, "Erro ao chamar webhook", "url", rule.CallbackURL, "phone", number, "message_id", messageID, "error", err)
+		s.sendInternalMessage(ctx, number, "Não foi possível se comunicar com o servidor intermediário: "+rule.CallbackURL)
 		return
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	observeWebhook("phrase", start, resp.StatusCode, nil)
 
 	responseBody, readErr := io.ReadAll(resp.Body)
 	if readErr != nil {
-		log.Printf("Aviso: Não foi possível ler o corpo da resposta do webhook %s: %v", rule.CallbackURL, readErr)
+		slog.WarnContext(ctx, "Não foi possível ler o corpo da resposta do webhook", "url", rule.CallbackURL, "error", readErr)
 	}
 
 	var jsonMap map[string]interface{}
@@ -223,11 +467,11 @@ func (s *WhatsAppService) dispatchWebhook(rule WebhookRule, number, text string)
 			responseBodyString = string(formattedJSON)
 		} else {
 			responseBodyString = string(responseBody)
-			log.Printf("Aviso: Erro ao re-codificar JSON formatado do webhook %s: %v", rule.CallbackURL, marshalErr)
+			slog.WarnContext(ctx, "Erro ao re-codificar JSON formatado do webhook", "url", rule.CallbackURL, "error", marshalErr)
 		}
 	} else {
 		responseBodyString = string(responseBody)
-		log.Printf("Aviso: Corpo da resposta do webhook %s não parece ser JSON válido: %v", rule.CallbackURL, unmarshalErr)
+		slog.DebugContext(ctx, "Corpo da resposta do webhook não parece ser JSON válido", "url", rule.CallbackURL, "error", unmarshalErr)
 	}
 
 	message := fmt.Sprintf("Solicitação enviada para o servidor!\n Status HTTP: %d", resp.StatusCode)
@@ -236,18 +480,24 @@ func (s *WhatsAppService) dispatchWebhook(rule WebhookRule, number, text string)
 		message += fmt.Sprintf("\nResposta do Servidor:\n%s", responseBodyString)
 	}
 
-	s.sendInternalMessage(number, message)
-	log.Printf("Webhook %s disparado! Status: %d. Resposta: %s", rule.CallbackURL, resp.StatusCode, responseBodyString)
+	s.sendInternalMessage(ctx, number, message)
+	slog.InfoContext(ctx, "Webhook disparado", "url", rule.CallbackURL, "phone", number, "status", resp.StatusCode, "body", responseBodyString)
 }
 
 // RegisterWebhook adiciona uma nova regra de webhook.
-func (s *WhatsAppService) RegisterWebhook(number, phrase, callbackURL string) {
+func (s *WhatsAppService) RegisterWebhook(number, phrase, callbackURL string) error {
+	number, err := phone.Normalize(number)
+	if err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.webhooks[number] = append(s.webhooks[number], WebhookRule{
 		Phrase:      phrase,
 		CallbackURL: callbackURL,
 	})
+	return nil
 }
 
 // ListWebhooks retorna o mapa de webhooks.
@@ -262,8 +512,30 @@ func (s *WhatsAppService) ListWebhooks() map[string][]WebhookRule {
 	return webhooksCopy
 }
 
+// ReplaceWebhooks troca todas as regras pelas informadas (usado pelo master para
+// manter o child igual às regras gravadas no banco).
+func (s *WhatsAppService) ReplaceWebhooks(rules map[string][]WebhookRule) error {
+	updated := make(map[string][]WebhookRule, len(rules))
+	for number, list := range rules {
+		normalized, err := phone.Normalize(number)
+		if err != nil {
+			return err
+		}
+		updated[normalized] = append(updated[normalized], list...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhooks = updated
+	return nil
+}
+
 // DeleteWebhook remove um webhook específico baseado no número, frase e URL.
 func (s *WhatsAppService) DeleteWebhook(number, phrase, callbackURL string) bool {
+	if normalized, err := phone.Normalize(number); err == nil {
+		number = normalized
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -295,9 +567,9 @@ func (s *WhatsAppService) DeleteWebhook(number, phrase, callbackURL string) bool
 	return removed
 }
 
-// EncodeQRToDataURL converte o código QR em uma URL de dados base64.
-func EncodeQRToDataURL(qrCode string) (string, error) {
-	img, err := qrcode.Encode(qrCode, qrcode.Medium, 180)
+// EncodeQRToDataURL converte o código QR em uma URL de dados base64 (PNG de size pixels).
+func EncodeQRToDataURL(qrCode string, size int) (string, error) {
+	img, err := qrcode.Encode(qrCode, qrcode.Medium, size)
 	if err != nil {
 		return "", fmt.Errorf("erro ao codificar QR Code: %w", err)
 	}
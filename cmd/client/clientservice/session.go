@@ -0,0 +1,137 @@
+package clientservice
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Estados de sessão reportados ao master.
+const (
+	SessionPaired    = "paired"
+	SessionLoggedOut = "logged_out"
+)
+
+// SessionState é o payload dos eventos de sessão (webhook de eventos e master).
+type SessionState struct {
+	State  string `json:"state"`
+	Reason string `json:"reason,omitempty"`
+	JID    string `json:"jid,omitempty"`
+}
+
+// SetMasterURL define a URL base do master, avisado quando a sessão muda (vazio
+// desativa), e o token que o master deu ao device para esses avisos.
+func (s *WhatsAppService) SetMasterURL(url, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.masterURL = url
+	s.masterToken = token
+}
+
+// notifySession publica a mudança de sessão no webhook de eventos e no master.
+func (s *WhatsAppService) notifySession(state SessionState) {
+	s.publishEvent(s.ctx, "session", state)
+
+	s.mu.RLock()
+	masterURL, masterToken := s.masterURL, s.masterToken
+	s.mu.RUnlock()
+	if masterURL != "" {
+		s.postEventAuth(s.ctx, fmt.Sprintf("%s/devices/%s/session", masterURL, s.phoneNumber), masterToken, "session", state)
+	}
+}
+
+// setClient cria o client whatsmeow para o device, substituindo o anterior.
+func (s *WhatsAppService) setClient(deviceStore *store.Device) {
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+	s.swapClient(deviceStore)
+}
+
+// swapClient publica o client novo antes de desligar o anterior: quem pegar o client
+// durante a troca recebe o novo (ainda desconectado), nunca um já encerrado pela
+// metade. Exige s.clientMu.
+func (s *WhatsAppService) swapClient(deviceStore *store.Device) {
+	cli := whatsmeow.NewClient(deviceStore, s.clientLog)
+	// A reconexão fica a cargo do reconnectLoop, com backoff e estados próprios
+	cli.EnableAutoReconnect = false
+	handlerID := cli.AddEventHandler(s.eventHandler)
+
+	old := s.client.Swap(cli)
+	oldHandlerID := s.handlerID
+	s.handlerID = handlerID
+	if old != nil {
+		old.RemoveEventHandler(oldHandlerID)
+		old.Disconnect()
+	}
+}
+
+// Logout desvincula o device no WhatsApp e apaga a sessão local. Se o client não
+// estiver logado (sem conexão ou sessão já morta), apenas a sessão local é apagada
+// e unlinked volta false — o aparelho pode continuar listando o dispositivo.
+func (s *WhatsAppService) Logout(ctx context.Context) (unlinked bool, err error) {
+	cli := s.client.Load()
+	if cli.Store.ID != nil && cli.IsLoggedIn() {
+		if err := cli.Logout(ctx); err != nil {
+			slog.Warn("⚠️ Falha no logout remoto, apagando apenas a sessão local", "error", err)
+		} else {
+			unlinked = true
+		}
+	}
+	return unlinked, s.resetSession(ctx, "logout", cli)
+}
+
+// ResetSession desconecta, apaga o device do armazenamento local e prepara um device
+// novo, pronto para um novo pareamento (QR ou código).
+func (s *WhatsAppService) ResetSession(ctx context.Context, reason string) error {
+	return s.resetSession(ctx, reason, nil)
+}
+
+// resetSession faz o ResetSession se o client atual ainda for expected (nil: qualquer
+// um). Um logout remoto que chega depois de /reset ou /logout já ter trocado o client
+// não apaga a sessão nova.
+func (s *WhatsAppService) resetSession(ctx context.Context, reason string, expected *whatsmeow.Client) error {
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+
+	cli := s.client.Load()
+	if expected != nil && cli != expected {
+		slog.Info("Sessão já trocada, reset ignorado", "reason", reason)
+		return nil
+	}
+	s.Disconnect()
+
+	if cli.Store.ID != nil {
+		if err := cli.Store.Delete(ctx); err != nil {
+			return fmt.Errorf("erro ao apagar sessão local: %w", err)
+		}
+	}
+
+	s.swapClient(s.dbContainer.NewDevice())
+
+	s.jidsMu.Lock()
+	s.jids = make(map[string]types.JID)
+	s.jidsMu.Unlock()
+
+	slog.Info("🧹 Sessão local apagada — aguardando novo pareamento", "reason", reason)
+	s.setState(StateLoggedOut, reason)
+	s.notifySession(SessionState{State: SessionLoggedOut, Reason: reason})
+	return nil
+}
+
+// handleLoggedOut limpa o estado quando o device é desvinculado remotamente
+// (pelo celular, por banimento ou sessão expirada).
+func (s *WhatsAppService) handleLoggedOut(v *events.LoggedOut) {
+	slog.Warn("🚪 Logout remoto — limpando sessão", "reason", v.Reason.String())
+	// O handler roda dentro do client; a troca do client precisa acontecer fora dele
+	cli := s.client.Load()
+	go func() {
+		if err := s.resetSession(s.ctx, v.Reason.String(), cli); err != nil {
+			slog.Error("❌ Erro ao limpar sessão após logout", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,190 @@
+package clientservice
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/eventhub"
+)
+
+// redisStreamMaxLen limita o tamanho do stream (aproximado, MAXLEN ~).
+const redisStreamMaxLen = 100000
+
+// RedisStreamSink publica cada evento com XADD em um Redis Stream, falando RESP
+// direto no socket (qualquer servidor compatível com Redis serve, inclusive um local de testes).
+type RedisStreamSink struct {
+	addr     string
+	password string
+	db       int
+	stream   string
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewRedisStreamSink interpreta redis://[:senha@]host:porta/stream[?db=N].
+func NewRedisStreamSink(u *url.URL) (*RedisStreamSink, error) {
+	stream := strings.Trim(u.Path, "/")
+	if stream == "" {
+		stream = "whatsapp-events"
+	}
+
+	sink := &RedisStreamSink{addr: u.Host, stream: stream}
+	if !strings.Contains(sink.addr, ":") {
+		sink.addr += ":6379"
+	}
+	if pass, ok := u.User.Password(); ok {
+		sink.password = pass
+	}
+	if db := u.Query().Get("db"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("%w: db inválido %q", ErrInvalidRequest, db)
+		}
+		sink.db = n
+	}
+	return sink, nil
+}
+
+func (r *RedisStreamSink) Write(ctx context.Context, events []eventhub.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.connect(ctx); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		r.conn.SetDeadline(deadline)
+	}
+
+	// Pipeline: envia todos os XADD e depois lê as respostas
+	var buf []byte
+	for _, evt := range events {
+		payload, err := json.Marshal(evt)
+		if err != nil {
+			return err
+		}
+		buf = appendCommand(buf, "XADD", r.stream, "MAXLEN", "~", strconv.Itoa(redisStreamMaxLen), "*",
+			"type", evt.Type, "device", evt.Device, "event", string(payload))
+	}
+	if _, err := r.conn.Write(buf); err != nil {
+		r.reset()
+		return err
+	}
+	for range events {
+		if _, err := r.readReply(); err != nil {
+			r.reset()
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RedisStreamSink) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reset()
+	return nil
+}
+
+// connect abre a conexão (com AUTH e SELECT) se ainda não houver uma.
+func (r *RedisStreamSink) connect(ctx context.Context) error {
+	if r.conn != nil {
+		return nil
+	}
+
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return err
+	}
+	r.conn = conn
+	r.r = bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	var setup [][]string
+	if r.password != "" {
+		setup = append(setup, []string{"AUTH", r.password})
+	}
+	if r.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(r.db)})
+	}
+	for _, cmd := range setup {
+		if _, err := conn.Write(appendCommand(nil, cmd...)); err != nil {
+			r.reset()
+			return err
+		}
+		if _, err := r.readReply(); err != nil {
+			r.reset()
+			return fmt.Errorf("redis %s: %w", cmd[0], err)
+		}
+	}
+	return nil
+}
+
+func (r *RedisStreamSink) reset() {
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+		r.r = nil
+	}
+}
+
+// appendCommand codifica o comando como array RESP de bulk strings.
+func appendCommand(buf []byte, args ...string) []byte {
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+// readReply lê uma resposta RESP; respostas de erro (-ERR ...) viram error.
+func (r *RedisStreamSink) readReply() (string, error) {
+	line, err := r.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", errors.New("resposta redis vazia")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", errors.New(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("resposta redis inválida %q", line)
+		}
+		if n < 0 {
+			return "", nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r.r, data); err != nil {
+			return "", err
+		}
+		return string(data[:n]), nil
+	}
+	return "", fmt.Errorf("resposta redis inesperada %q", line)
+}
@@ -0,0 +1,276 @@
+package clientservice
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/eventhub"
+)
+
+// fakeRedis é um servidor RESP mínimo: AUTH, SELECT e XADD. Com failXADD ligado,
+// o segundo XADD de cada pipeline responde -ERR, como um Redis sem memória.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+	failXADD atomic.Bool
+	conns    atomic.Int32
+
+	mu      sync.Mutex
+	entries []map[string]string // campos de cada XADD aceito
+	db      string
+	streams []string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{ln: ln, password: password}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeRedis) url(password, stream string, db int) string {
+	u := url.URL{Scheme: "redis", Host: f.ln.Addr().String(), Path: "/" + stream}
+	if password != "" {
+		u.User = url.UserPassword("", password)
+	}
+	if db != 0 {
+		u.RawQuery = "db=" + strconv.Itoa(db)
+	}
+	return u.String()
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		f.conns.Add(1)
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	seq := 0 // XADDs desta conexão
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[1] != f.password {
+				reply = "-WRONGPASS invalid username-password pair\r\n"
+				break
+			}
+			authed = true
+			reply = "+OK\r\n"
+		case "SELECT":
+			f.mu.Lock()
+			f.db = args[1]
+			f.mu.Unlock()
+			reply = "+OK\r\n"
+		case "XADD":
+			seq++
+			switch {
+			case !authed:
+				reply = "-NOAUTH Authentication required.\r\n"
+			case f.failXADD.Load() && seq%2 == 0:
+				reply = "-ERR OOM command not allowed when used memory > 'maxmemory'\r\n"
+			default:
+				// XADD stream MAXLEN ~ N * campo valor ...
+				fields := map[string]string{}
+				for i := 6; i+1 < len(args); i += 2 {
+					fields[args[i]] = args[i+1]
+				}
+				f.mu.Lock()
+				f.entries = append(f.entries, fields)
+				f.streams = append(f.streams, args[1])
+				id := fmt.Sprintf("%d-0", len(f.entries))
+				f.mu.Unlock()
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(id), id)
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) received() []map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]map[string]string(nil), f.entries...)
+}
+
+// readCommand lê um array RESP de bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+	if err != nil || line[0] != '*' {
+		return nil, fmt.Errorf("comando inválido %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func redisSink(t *testing.T, rawURL string) *RedisStreamSink {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink, err := NewRedisStreamSink(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sink.Close() })
+	return sink
+}
+
+func testEvents(from, n int) []eventhub.Event {
+	events := make([]eventhub.Event, n)
+	for i := range events {
+		events[i] = eventhub.Event{ID: uint64(from + i), Type: "message", Device: "5511999999999"}
+	}
+	return events
+}
+
+func TestRedisSinkWritesBatch(t *testing.T) {
+	srv := newFakeRedis(t, "segredo")
+	sink := redisSink(t, srv.url("segredo", "eventos", 2))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sink.Write(ctx, testEvents(1, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(ctx, testEvents(4, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := srv.received()
+	if len(got) != 5 {
+		t.Fatalf("XADDs recebidos = %d, quer 5", len(got))
+	}
+	for i, fields := range got {
+		var evt eventhub.Event
+		if err := json.Unmarshal([]byte(fields["event"]), &evt); err != nil {
+			t.Fatalf("entrada %d: event inválido: %v", i, err)
+		}
+		if evt.ID != uint64(i+1) || fields["type"] != "message" || fields["device"] != "5511999999999" {
+			t.Errorf("entrada %d = %v", i, fields)
+		}
+	}
+	srv.mu.Lock()
+	stream, db := srv.streams[0], srv.db
+	srv.mu.Unlock()
+	if stream != "eventos" || db != "2" {
+		t.Errorf("stream %q db %q, quer eventos e 2", stream, db)
+	}
+	if n := srv.conns.Load(); n != 1 {
+		t.Errorf("conexões = %d, quer 1 (reaproveitada entre lotes)", n)
+	}
+}
+
+func TestRedisSinkAuthFailure(t *testing.T) {
+	srv := newFakeRedis(t, "segredo")
+	sink := redisSink(t, srv.url("errada", "eventos", 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := sink.Write(ctx, testEvents(1, 1))
+	if err == nil || !strings.Contains(err.Error(), "AUTH") || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Fatalf("erro = %v, quer falha de AUTH", err)
+	}
+	if len(srv.received()) != 0 {
+		t.Fatal("XADD aceito sem autenticação")
+	}
+
+	// A conexão recusada não é reaproveitada: a próxima tentativa autentica de novo
+	sink.Write(ctx, testEvents(2, 1))
+	if n := srv.conns.Load(); n != 2 {
+		t.Errorf("conexões = %d, quer 2", n)
+	}
+}
+
+func TestRedisSinkErrorSpoolsAndReplays(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	srv.failXADD.Store(true)
+
+	dir := t.TempDir()
+	m := NewSinkManager(filepath.Join(dir, "spool"), filepath.Join(dir, "sinks.json"))
+	defer m.Close()
+	if err := m.Add(srv.url("", "eventos", 0), false); err != nil {
+		t.Fatal(err)
+	}
+
+	// O -ERR no meio do pipeline derruba o lote inteiro para o spool
+	for _, evt := range testEvents(1, 3) {
+		m.Dispatch(evt)
+	}
+	waitFor(t, 5*time.Second, func() bool { return m.List()[0].Spooled == 3 })
+	if st := m.List()[0]; !strings.Contains(st.LastError, "OOM") {
+		t.Errorf("last_error = %q, quer o erro do redis", st.LastError)
+	}
+
+	// Com o redis de volta, o spool é reenviado numa conexão nova
+	srv.failXADD.Store(false)
+	waitFor(t, 2*sinkRetryInterval, func() bool { return m.List()[0].Spooled == 0 })
+
+	ids := map[uint64]bool{}
+	for _, fields := range srv.received() {
+		var evt eventhub.Event
+		json.Unmarshal([]byte(fields["event"]), &evt)
+		ids[evt.ID] = true
+	}
+	for id := uint64(1); id <= 3; id++ {
+		if !ids[id] {
+			t.Errorf("evento %d não chegou ao redis", id)
+		}
+	}
+	if n := srv.conns.Load(); n < 2 {
+		t.Errorf("conexões = %d, quer reconexão após o erro", n)
+	}
+}
@@ -0,0 +1,593 @@
+package clientservice
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/eventhub"
+)
+
+const (
+	sinkBatchSize     = 100             // eventos por escrita no destino
+	sinkFlushInterval = time.Second     // espera máxima para completar um lote
+	sinkQueueSize     = 4096            // eventos em memória; com a fila cheia os novos são descartados
+	sinkRetryInterval = 5 * time.Second // frequência das tentativas de reenviar o spool
+)
+
+// ErrSinkExists indica que já existe um sink com a mesma URL.
+var ErrSinkExists = errors.New("sink já configurado")
+
+// EventSink é um destino dos eventos do device (arquivo, HTTP, broker). Write recebe
+// lotes e só deve retornar nil quando todos os eventos foram aceitos pelo destino;
+// em caso de erro o lote inteiro vai para o spool e é reenviado depois (at-least-once).
+type EventSink interface {
+	Write(ctx context.Context, events []eventhub.Event) error
+	Close() error
+}
+
+// NewEventSink cria o sink a partir da URL:
+//
+//	file:///data/events.jsonl            arquivo JSONL (um evento por linha)
+//	https://api.exemplo.com/eventos      POST com lotes de eventos
+//	redis://:senha@host:6379/stream      XADD em um Redis Stream
+func NewEventSink(rawURL string) (EventSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: url de sink inválida: %v", ErrInvalidRequest, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		path := u.Path
+		if u.Opaque != "" {
+			path = u.Opaque // file:events.jsonl (caminho relativo)
+		}
+		if path == "" {
+			return nil, fmt.Errorf("%w: sink file sem caminho", ErrInvalidRequest)
+		}
+		return NewFileSink(path)
+	case "http", "https":
+		return NewHTTPBatchSink(rawURL), nil
+	case "redis":
+		return NewRedisStreamSink(u)
+	}
+	return nil, fmt.Errorf("%w: tipo de sink não suportado %q", ErrInvalidRequest, u.Scheme)
+}
+
+// FileSink acrescenta os eventos a um arquivo JSONL.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir %s: %w", path, err)
+	}
+	return &FileSink{file: f}, nil
+}
+
+func (f *FileSink) Write(_ context.Context, events []eventhub.Event) error {
+	buf, err := encodeJSONL(events)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.file.Write(buf); err != nil {
+		return err
+	}
+	return f.file.Sync()
+}
+
+func (f *FileSink) Close() error {
+	return f.file.Close()
+}
+
+// HTTPBatchSink envia cada lote em um POST {"device", "events": [...]}; qualquer
+// resposta fora de 2xx é tratada como falha.
+type HTTPBatchSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPBatchSink(url string) *HTTPBatchSink {
+	return &HTTPBatchSink{url: url, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (h *HTTPBatchSink) Write(ctx context.Context, events []eventhub.Event) error {
+	body, err := json.Marshal(map[string]any{
+		"device": events[0].Device,
+		"events": events,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sink http respondeu %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (h *HTTPBatchSink) Close() error { return nil }
+
+// SinkStatus descreve um sink configurado (GET /sinks).
+type SinkStatus struct {
+	URL       string     `json:"url"` // senha mascarada
+	Delivered int64      `json:"delivered"`
+	Spooled   int        `json:"spooled"` // eventos aguardando reenvio
+	Dropped   int64      `json:"dropped"` // eventos descartados com a fila cheia
+	LastError string     `json:"last_error,omitempty"`
+	LastErrAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// sinkWorker agrupa os eventos de um sink em lotes e cuida do spool em disco.
+type sinkWorker struct {
+	url       string
+	sink      EventSink
+	queue     chan eventhub.Event
+	spoolPath string
+	spoolMu   sync.Mutex // protege o arquivo de spool
+	statMu    sync.Mutex
+	status    SinkStatus
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// SinkManager distribui os eventos do device entre os sinks configurados.
+type SinkManager struct {
+	mu       sync.RWMutex
+	workers  map[string]*sinkWorker // key: URL do sink
+	spoolDir string
+	listMu   sync.Mutex // protege saved e o arquivo listPath
+	listPath string     // sinks adicionados pela API, recarregados no boot
+	saved    []string
+}
+
+func NewSinkManager(spoolDir, listPath string) *SinkManager {
+	return &SinkManager{
+		workers:  make(map[string]*sinkWorker),
+		spoolDir: spoolDir,
+		listPath: listPath,
+	}
+}
+
+// Load reconfigura os sinks gravados por execuções anteriores (adicionados pela API),
+// retomando o spool de cada um.
+func (m *SinkManager) Load() error {
+	data, err := os.ReadFile(m.listPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao ler %s: %w", m.listPath, err)
+	}
+
+	var urls []string
+	if err := json.Unmarshal(data, &urls); err != nil {
+		return fmt.Errorf("lista de sinks inválida em %s: %w", m.listPath, err)
+	}
+
+	m.listMu.Lock()
+	m.saved = urls
+	m.listMu.Unlock()
+
+	for _, rawURL := range urls {
+		if err := m.add(rawURL); err != nil && !errors.Is(err, ErrSinkExists) {
+			slog.Warn("⚠️ Sink gravado ignorado", "url", redactURL(rawURL), "error", err)
+		}
+	}
+	return nil
+}
+
+// Add configura um novo sink. O spool de uma execução anterior com a mesma URL é reenviado.
+// Com persist, a URL é gravada e o sink volta sozinho quando o container reinicia.
+func (m *SinkManager) Add(rawURL string, persist bool) error {
+	if err := m.add(rawURL); err != nil {
+		return err
+	}
+	if !persist {
+		return nil
+	}
+
+	m.listMu.Lock()
+	defer m.listMu.Unlock()
+	if slices.Contains(m.saved, rawURL) {
+		return nil
+	}
+	if err := m.writeList(append(slices.Clone(m.saved), rawURL)); err != nil {
+		// Sem gravar, o sink sumiria no restart deixando o spool órfão: desfaz
+		m.closeWorker(rawURL)
+		return err
+	}
+	return nil
+}
+
+func (m *SinkManager) add(rawURL string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.workers[rawURL]; ok {
+		return ErrSinkExists
+	}
+
+	sink, err := NewEventSink(rawURL)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.spoolDir, 0o755); err != nil {
+		sink.Close()
+		return fmt.Errorf("erro ao criar diretório de spool: %w", err)
+	}
+
+	sum := sha1.Sum([]byte(rawURL))
+	w := &sinkWorker{
+		url:       rawURL,
+		sink:      sink,
+		queue:     make(chan eventhub.Event, sinkQueueSize),
+		spoolPath: filepath.Join(m.spoolDir, hex.EncodeToString(sum[:8])+".jsonl"),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	w.status.URL = redactURL(rawURL)
+	w.status.Spooled = w.countSpool()
+
+	m.workers[rawURL] = w
+	go w.run()
+	slog.Info("📤 Sink de eventos configurado", "url", w.status.URL)
+	return nil
+}
+
+// Remove desliga o sink, entregando (ou mandando para o spool) o que estiver na fila,
+// e o tira da lista gravada.
+func (m *SinkManager) Remove(rawURL string) bool {
+	ok := m.closeWorker(rawURL)
+
+	m.listMu.Lock()
+	defer m.listMu.Unlock()
+	if i := slices.Index(m.saved, rawURL); i >= 0 {
+		// Também vale para um sink gravado que não subiu no boot (URL que deixou de ser válida)
+		ok = true
+		if err := m.writeList(slices.Delete(slices.Clone(m.saved), i, i+1)); err != nil {
+			slog.Warn("⚠️ Erro ao gravar a lista de sinks", "error", err)
+		}
+	}
+	return ok
+}
+
+func (m *SinkManager) closeWorker(rawURL string) bool {
+	m.mu.Lock()
+	w, ok := m.workers[rawURL]
+	delete(m.workers, rawURL)
+	m.mu.Unlock()
+
+	if ok {
+		w.close()
+	}
+	return ok
+}
+
+// writeList grava a lista de sinks persistidos; exige listMu.
+func (m *SinkManager) writeList(urls []string) error {
+	data, err := json.MarshalIndent(urls, "", "  ")
+	if err != nil {
+		return err
+	}
+	// 0600: as URLs podem carregar senhas (redis://:senha@...)
+	if err := os.WriteFile(m.listPath+".tmp", data, 0o600); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %w", m.listPath, err)
+	}
+	if err := os.Rename(m.listPath+".tmp", m.listPath); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %w", m.listPath, err)
+	}
+	m.saved = urls
+	return nil
+}
+
+// List retorna o estado de cada sink.
+func (m *SinkManager) List() []SinkStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]SinkStatus, 0, len(m.workers))
+	for _, w := range m.workers {
+		w.statMu.Lock()
+		list = append(list, w.status)
+		w.statMu.Unlock()
+	}
+	return list
+}
+
+// Dispatch enfileira o evento em todos os sinks sem bloquear; com a fila cheia (destino
+// lento ou reenviando o spool) o evento é descartado e contado em dropped. Nunca faz I/O:
+// roda no caminho dos eventos do WhatsApp.
+func (m *SinkManager) Dispatch(evt eventhub.Event) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, w := range m.workers {
+		select {
+		case w.queue <- evt:
+		default:
+			w.drop()
+		}
+	}
+}
+
+// Close desliga todos os sinks.
+func (m *SinkManager) Close() {
+	m.mu.Lock()
+	workers := m.workers
+	m.workers = make(map[string]*sinkWorker)
+	m.mu.Unlock()
+
+	for _, w := range workers {
+		w.close()
+	}
+}
+
+func (w *sinkWorker) run() {
+	defer close(w.done)
+
+	flush := time.NewTicker(sinkFlushInterval)
+	defer flush.Stop()
+	retry := time.NewTicker(sinkRetryInterval)
+	defer retry.Stop()
+
+	w.replay()
+
+	batch := make([]eventhub.Event, 0, sinkBatchSize)
+	for {
+		select {
+		case evt := <-w.queue:
+			batch = append(batch, evt)
+			if len(batch) >= sinkBatchSize {
+				w.deliver(batch)
+				batch = make([]eventhub.Event, 0, sinkBatchSize)
+			}
+		case <-flush.C:
+			if len(batch) > 0 {
+				w.deliver(batch)
+				batch = make([]eventhub.Event, 0, sinkBatchSize)
+			}
+		case <-retry.C:
+			w.replay()
+		case <-w.stop:
+			// Esvazia a fila antes de sair
+			for {
+				select {
+				case evt := <-w.queue:
+					batch = append(batch, evt)
+					continue
+				default:
+				}
+				break
+			}
+			if len(batch) > 0 {
+				w.deliver(batch)
+			}
+			return
+		}
+	}
+}
+
+func (w *sinkWorker) close() {
+	close(w.stop)
+	<-w.done
+	if err := w.sink.Close(); err != nil {
+		slog.Warn("⚠️ Erro ao fechar sink", "url", w.status.URL, "error", err)
+	}
+}
+
+// drop conta um evento descartado; o aviso sai no primeiro e a cada fila inteira perdida.
+func (w *sinkWorker) drop() {
+	w.statMu.Lock()
+	w.status.Dropped++
+	dropped := w.status.Dropped
+	w.statMu.Unlock()
+
+	if dropped%sinkQueueSize == 1 {
+		slog.Warn("⚠️ Fila do sink cheia; eventos descartados", "url", w.status.URL, "dropped", dropped)
+	}
+}
+
+// deliver tenta entregar o lote; em caso de falha ele vai para o spool.
+func (w *sinkWorker) deliver(batch []eventhub.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := w.sink.Write(ctx, batch); err != nil {
+		w.spool(batch, err)
+		return
+	}
+	w.statMu.Lock()
+	w.status.Delivered += int64(len(batch))
+	w.statMu.Unlock()
+}
+
+// spool guarda o lote no disco para reenvio.
+func (w *sinkWorker) spool(batch []eventhub.Event, cause error) {
+	buf, err := encodeJSONL(batch)
+	if err == nil {
+		w.spoolMu.Lock()
+		err = appendFile(w.spoolPath, buf)
+		w.spoolMu.Unlock()
+	}
+	if err != nil {
+		slog.Error("❌ Eventos perdidos, erro no spool", "url", w.status.URL, "events", len(batch), "error", err)
+		return
+	}
+
+	now := time.Now()
+	w.statMu.Lock()
+	w.status.Spooled += len(batch)
+	w.status.LastError = cause.Error()
+	w.status.LastErrAt = &now
+	w.statMu.Unlock()
+	slog.Warn("⚠️ Sink falhou; eventos no spool", "url", w.status.URL, "events", len(batch), "error", cause)
+}
+
+// replay reenvia o spool em lotes; o que não for entregue permanece no arquivo.
+func (w *sinkWorker) replay() {
+	w.spoolMu.Lock()
+	defer w.spoolMu.Unlock()
+
+	events, err := readJSONL(w.spoolPath)
+	if err != nil || len(events) == 0 {
+		return
+	}
+
+	sent := 0
+	for sent < len(events) {
+		end := min(sent+sinkBatchSize, len(events))
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err = w.sink.Write(ctx, events[sent:end])
+		cancel()
+		if err != nil {
+			break
+		}
+		sent = end
+	}
+
+	if sent == len(events) {
+		os.Remove(w.spoolPath)
+	} else if sent > 0 {
+		buf, encErr := encodeJSONL(events[sent:])
+		if encErr == nil {
+			encErr = os.WriteFile(w.spoolPath+".tmp", buf, 0o644)
+		}
+		if encErr == nil {
+			encErr = os.Rename(w.spoolPath+".tmp", w.spoolPath)
+		}
+		if encErr != nil {
+			// Melhor reenviar duplicado do que perder: mantém o spool original
+			slog.Error("⚠️ Erro ao regravar spool", "url", w.status.URL, "error", encErr)
+			sent = 0
+		}
+	}
+
+	w.statMu.Lock()
+	w.status.Delivered += int64(sent)
+	w.status.Spooled = len(events) - sent
+	w.statMu.Unlock()
+	if sent > 0 {
+		slog.Info("📤 Eventos do spool reenviados", "url", w.status.URL, "events", sent)
+	}
+}
+
+func (w *sinkWorker) countSpool() int {
+	events, _ := readJSONL(w.spoolPath)
+	return len(events)
+}
+
+func encodeJSONL(events []eventhub.Event) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, evt := range events {
+		if err := enc.Encode(evt); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func readJSONL(path string) ([]eventhub.Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []eventhub.Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var evt eventhub.Event
+		if err := json.Unmarshal(scanner.Bytes(), &evt); err != nil {
+			continue // linha truncada por queda no meio da escrita
+		}
+		events = append(events, evt)
+	}
+	return events, scanner.Err()
+}
+
+func appendFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// redactURL mascara a senha da URL para exibição.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
+
+// ParseSinkList separa a lista de URLs de EVENT_SINKS (separadas por vírgula ou espaço).
+func ParseSinkList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' })
+}
+
+// AddEventSink configura um sink para os eventos do device; com persist ele é gravado
+// em DATA_DIR e reconfigurado no próximo boot (sinks da API). Os de EVENT_SINKS não são gravados.
+func (s *WhatsAppService) AddEventSink(url string, persist bool) error {
+	return s.sinks.Add(url, persist)
+}
+
+// LoadEventSinks reconfigura os sinks adicionados pela API em execuções anteriores.
+func (s *WhatsAppService) LoadEventSinks() error {
+	return s.sinks.Load()
+}
+
+// RemoveEventSink remove o sink com a URL informada.
+func (s *WhatsAppService) RemoveEventSink(url string) bool {
+	return s.sinks.Remove(url)
+}
+
+// EventSinks lista os sinks configurados.
+func (s *WhatsAppService) EventSinks() []SinkStatus {
+	return s.sinks.List()
+}
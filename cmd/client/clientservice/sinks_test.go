@@ -0,0 +1,155 @@
+package clientservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/eventhub"
+)
+
+// collector é um destino HTTP de teste que guarda os IDs recebidos; enquanto fail
+// estiver ligado responde 500.
+type collector struct {
+	mu   sync.Mutex
+	ids  []uint64
+	fail atomic.Bool
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if c.fail.Load() {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var body struct {
+		Events []eventhub.Event `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	for _, evt := range body.Events {
+		c.ids = append(c.ids, evt.ID)
+	}
+	c.mu.Unlock()
+}
+
+func (c *collector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.ids)
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condição não atingida a tempo")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestSinkDeliversAndReplaysSpool(t *testing.T) {
+	dest := &collector{}
+	srv := httptest.NewServer(dest)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	m := NewSinkManager(filepath.Join(dir, "spool"), filepath.Join(dir, "sinks.json"))
+	defer m.Close()
+	if err := m.Add(srv.URL, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Destino fora do ar: o lote vai para o spool
+	dest.fail.Store(true)
+	for i := 1; i <= 3; i++ {
+		m.Dispatch(eventhub.Event{ID: uint64(i), Type: "message", Device: "5511999999999"})
+	}
+	waitFor(t, 5*time.Second, func() bool { return m.List()[0].Spooled == 3 })
+
+	// Destino de volta: o spool é reenviado no próximo ciclo
+	dest.fail.Store(false)
+	waitFor(t, 2*sinkRetryInterval, func() bool { return dest.count() == 3 })
+
+	st := m.List()[0]
+	if st.Spooled != 0 || st.Delivered != 3 {
+		t.Fatalf("status = %+v, quer spooled 0 e delivered 3", st)
+	}
+}
+
+func TestSinkDispatchDoesNotBlock(t *testing.T) {
+	// Destino que segura toda requisição: o worker fica parado no primeiro lote
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	m := NewSinkManager(filepath.Join(dir, "spool"), filepath.Join(dir, "sinks.json"))
+	defer m.Close()
+	defer close(release)
+	if err := m.Add(srv.URL, false); err != nil {
+		t.Fatal(err)
+	}
+
+	total := sinkQueueSize + sinkBatchSize*2 + 50
+	start := time.Now()
+	for i := 1; i <= total; i++ {
+		m.Dispatch(eventhub.Event{ID: uint64(i), Type: "message"})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Dispatch bloqueou por %s", elapsed)
+	}
+	waitFor(t, time.Second, func() bool { return m.List()[0].Dropped > 0 })
+}
+
+func TestSinkListPersisted(t *testing.T) {
+	dir := t.TempDir()
+	spool := filepath.Join(dir, "spool")
+	list := filepath.Join(dir, "sinks.json")
+	apiSink := "file://" + filepath.Join(dir, "api.jsonl")
+	envSink := "file://" + filepath.Join(dir, "env.jsonl")
+
+	m := NewSinkManager(spool, list)
+	if err := m.Add(envSink, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(apiSink, true); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	// Novo boot: só o sink da API volta
+	m = NewSinkManager(spool, list)
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	got := m.List()
+	if len(got) != 1 || got[0].URL != apiSink {
+		t.Fatalf("sinks após o restart = %+v, quer só %s", got, apiSink)
+	}
+
+	if !m.Remove(apiSink) {
+		t.Fatal("Remove não encontrou o sink gravado")
+	}
+	m.Close()
+
+	m = NewSinkManager(spool, list)
+	defer m.Close()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.List(); len(got) != 0 {
+		t.Fatalf("sink removido voltou após o restart: %+v", got)
+	}
+}
@@ -0,0 +1,472 @@
+package clientservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+)
+
+// Status de uma mensagem enviada, em ordem de progresso.
+const (
+	StatusFailed    = "failed"
+	StatusSent      = "sent" // confirmada pelo servidor (server ack)
+	StatusDelivered = "delivered"
+	StatusRead      = "read"
+	StatusPlayed    = "played"
+
+	// StatusReceived marca mensagens recebidas ainda não lidas; ao marcar o chat como lido viram StatusRead.
+	StatusReceived = "received"
+)
+
+const (
+	DirectionOutbound = "outbound"
+	DirectionInbound  = "inbound"
+)
+
+var statusRank = map[string]int{
+	StatusFailed:    0,
+	StatusSent:      1,
+	StatusDelivered: 2,
+	StatusRead:      3,
+	StatusPlayed:    4,
+}
+
+// ErrMessageNotFound indica que a mensagem não está no histórico local.
+var ErrMessageNotFound = errors.New("mensagem não encontrada")
+
+// MessageRecord é uma mensagem registrada no histórico local.
+type MessageRecord struct {
+	ID        string    `json:"id"`
+	Chat      string    `json:"chat"`
+	Sender    string    `json:"sender,omitempty"`
+	Direction string    `json:"direction"`
+	Type      string    `json:"type"`
+	Text      string    `json:"text,omitempty"`
+	Media     string    `json:"media,omitempty"` // direct path da mídia no WhatsApp
+	Timestamp time.Time `json:"timestamp"`
+	Status    string    `json:"status"`
+	StatusAt  time.Time `json:"status_at"`
+	Error     string    `json:"error,omitempty"`
+	RequestID string    `json:"request_id,omitempty"` // X-Request-Id da requisição que enviou a mensagem
+}
+
+// migrations são aplicadas em ordem; PRAGMA user_version guarda quantas já rodaram.
+var migrations = []string{
+	`CREATE TABLE messages (
+		id        TEXT NOT NULL,
+		chat      TEXT NOT NULL,
+		sender    TEXT NOT NULL DEFAULT '',
+		direction TEXT NOT NULL,
+		type      TEXT NOT NULL,
+		text      TEXT NOT NULL DEFAULT '',
+		timestamp INTEGER NOT NULL,
+		status    TEXT NOT NULL,
+		status_at INTEGER NOT NULL,
+		error     TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (chat, id)
+	);
+	CREATE INDEX messages_id_idx ON messages (id);`,
+
+	`ALTER TABLE messages ADD COLUMN media TEXT NOT NULL DEFAULT '';
+	CREATE INDEX messages_chat_ts_idx ON messages (chat, timestamp);
+	CREATE VIRTUAL TABLE messages_fts USING fts4(text, tokenize=unicode61 "remove_diacritics=1");
+	INSERT INTO messages_fts (docid, text) SELECT rowid, text FROM messages WHERE text != '';
+	CREATE TRIGGER messages_fts_ai AFTER INSERT ON messages WHEN new.text != '' BEGIN
+		INSERT INTO messages_fts (docid, text) VALUES (new.rowid, new.text);
+	END;
+	CREATE TRIGGER messages_fts_au AFTER UPDATE OF text ON messages BEGIN
+		DELETE FROM messages_fts WHERE docid = old.rowid;
+		INSERT INTO messages_fts (docid, text) SELECT new.rowid, new.text WHERE new.text != '';
+	END;
+	CREATE TRIGGER messages_fts_ad AFTER DELETE ON messages BEGIN
+		DELETE FROM messages_fts WHERE docid = old.rowid;
+	END;`,
+
+	`ALTER TABLE messages ADD COLUMN request_id TEXT NOT NULL DEFAULT '';`,
+}
+
+const messageColumns = "id, chat, sender, direction, type, text, media, timestamp, status, status_at, error, request_id"
+
+// MessageStore guarda o histórico de mensagens do device em sqlite.
+type MessageStore struct {
+	db *sql.DB
+}
+
+// NewMessageStore abre o banco e aplica as migrations pendentes.
+func NewMessageStore(ctx context.Context, dsn string) (*MessageStore, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir banco de mensagens: %w", err)
+	}
+
+	ms := &MessageStore{db: db}
+	if err := ms.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return ms, nil
+}
+
+func (ms *MessageStore) migrate(ctx context.Context) error {
+	var version int
+	if err := ms.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("erro ao ler versão do banco de mensagens: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := ms.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("erro na migration %d do banco de mensagens: %w", i+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close fecha o banco.
+func (ms *MessageStore) Close() error {
+	return ms.db.Close()
+}
+
+// Save insere ou atualiza uma mensagem. O UPSERT mantém o rowid usado pelo índice de busca.
+func (ms *MessageStore) Save(ctx context.Context, m MessageRecord) error {
+	_, err := ms.db.ExecContext(ctx, `
+		INSERT INTO messages (`+messageColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (chat, id) DO UPDATE SET
+			sender = excluded.sender, direction = excluded.direction, type = excluded.type,
+			text = excluded.text, media = excluded.media, timestamp = excluded.timestamp,
+			status = excluded.status, status_at = excluded.status_at, error = excluded.error,
+			request_id = excluded.request_id`,
+		m.ID, m.Chat, m.Sender, m.Direction, m.Type, m.Text, m.Media,
+		m.Timestamp.UnixMilli(), m.Status, m.StatusAt.UnixMilli(), m.Error, m.RequestID)
+	return err
+}
+
+// SaveIfAbsent insere a mensagem só se ela ainda não existir (eventos repetidos não sobrescrevem status).
+func (ms *MessageStore) SaveIfAbsent(ctx context.Context, m MessageRecord) error {
+	_, err := ms.db.ExecContext(ctx, `
+		INSERT INTO messages (`+messageColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (chat, id) DO NOTHING`,
+		m.ID, m.Chat, m.Sender, m.Direction, m.Type, m.Text, m.Media,
+		m.Timestamp.UnixMilli(), m.Status, m.StatusAt.UnixMilli(), m.Error, m.RequestID)
+	return err
+}
+
+// Get busca uma mensagem pelo ID.
+func (ms *MessageStore) Get(ctx context.Context, id string) (MessageRecord, error) {
+	row := ms.db.QueryRowContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages WHERE id = ? ORDER BY timestamp DESC LIMIT 1`, id)
+
+	m, err := scanMessage(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return MessageRecord{}, ErrMessageNotFound
+	}
+	return m, err
+}
+
+// ChatSummary é um item da caixa de entrada: última mensagem e quantas não foram lidas.
+type ChatSummary struct {
+	Chat        string        `json:"chat"`
+	LastMessage MessageRecord `json:"last_message"`
+	Unread      int           `json:"unread"`
+}
+
+// ListChats lista os chats ordenados pela mensagem mais recente.
+func (ms *MessageStore) ListChats(ctx context.Context, limit, offset int) ([]ChatSummary, error) {
+	// No sqlite, colunas fora do agregado vêm da linha que tem o MAX(timestamp)
+	rows, err := ms.db.QueryContext(ctx, `
+		SELECT `+messageColumns+`, MAX(timestamp),
+			(SELECT COUNT(*) FROM messages u
+			 WHERE u.chat = m.chat AND u.direction = ? AND u.status = ?)
+		FROM messages m
+		GROUP BY chat
+		ORDER BY MAX(timestamp) DESC
+		LIMIT ? OFFSET ?`,
+		DirectionInbound, StatusReceived, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chats := make([]ChatSummary, 0)
+	for rows.Next() {
+		var c ChatSummary
+		var last int64
+		c.LastMessage, err = scanMessage(rows, &last, &c.Unread)
+		if err != nil {
+			return nil, err
+		}
+		c.Chat = c.LastMessage.Chat
+		chats = append(chats, c)
+	}
+	return chats, rows.Err()
+}
+
+// MessageCursor é a posição na paginação de um chat. O timestamp das mensagens
+// recebidas tem resolução de segundos, então o rowid desempata as do mesmo instante.
+type MessageCursor struct {
+	Timestamp time.Time
+	RowID     int64 // 0: só o timestamp, exclusivo (cursor antigo)
+}
+
+// String codifica o cursor como "RFC3339Nano,rowid", o formato de next_before.
+func (c MessageCursor) String() string {
+	return c.Timestamp.UTC().Format(time.RFC3339Nano) + "," + strconv.FormatInt(c.RowID, 10)
+}
+
+// ParseMessageCursor lê o next_before; aceita também só o timestamp em RFC3339.
+func ParseMessageCursor(v string) (MessageCursor, error) {
+	ts, rowID, found := strings.Cut(v, ",")
+	var c MessageCursor
+	var err error
+	if c.Timestamp, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+		return MessageCursor{}, err
+	}
+	if found {
+		if c.RowID, err = strconv.ParseInt(rowID, 10, 64); err != nil || c.RowID <= 0 {
+			return MessageCursor{}, fmt.Errorf("rowid inválido %q", rowID)
+		}
+	}
+	return c, nil
+}
+
+// ListMessages pagina as mensagens de um chat da mais recente para a mais antiga,
+// começando depois do cursor before (zero = agora). Retorna também o cursor da
+// próxima página (zero quando não há mensagens).
+func (ms *MessageStore) ListMessages(ctx context.Context, chat string, before MessageCursor, limit int) ([]MessageRecord, MessageCursor, error) {
+	if before.Timestamp.IsZero() {
+		before.Timestamp = time.Now().Add(time.Minute)
+	}
+
+	rows, err := ms.db.QueryContext(ctx, `
+		SELECT `+messageColumns+`, rowid
+		FROM messages
+		WHERE chat = ? AND (timestamp < ? OR (timestamp = ? AND rowid < ?))
+		ORDER BY timestamp DESC, rowid DESC
+		LIMIT ?`,
+		chat, before.Timestamp.UnixMilli(), before.Timestamp.UnixMilli(), before.RowID, limit)
+	if err != nil {
+		return nil, MessageCursor{}, err
+	}
+	defer rows.Close()
+
+	messages := make([]MessageRecord, 0)
+	var next MessageCursor
+	for rows.Next() {
+		m, err := scanMessage(rows, &next.RowID)
+		if err != nil {
+			return nil, MessageCursor{}, err
+		}
+		next.Timestamp = m.Timestamp
+		messages = append(messages, m)
+	}
+	return messages, next, rows.Err()
+}
+
+// Search faz busca textual (prefixo de cada termo) opcionalmente restrita a um chat.
+func (ms *MessageStore) Search(ctx context.Context, query, chat string, limit int) ([]MessageRecord, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, fmt.Errorf("%w: a busca não tem termos", ErrInvalidRequest)
+	}
+
+	q := `
+		SELECT ` + prefixColumns("m", messageColumns) + `
+		FROM messages_fts f
+		JOIN messages m ON m.rowid = f.docid
+		WHERE messages_fts MATCH ?`
+	args := []any{match}
+	if chat != "" {
+		q += " AND m.chat = ?"
+		args = append(args, chat)
+	}
+	q += " ORDER BY m.timestamp DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := ms.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+// MarkChatRead marca as mensagens recebidas do chat como lidas e devolve as que mudaram.
+func (ms *MessageStore) MarkChatRead(ctx context.Context, chat string, at time.Time) ([]MessageRecord, error) {
+	rows, err := ms.db.QueryContext(ctx, `
+		UPDATE messages SET status = ?, status_at = ?
+		WHERE chat = ? AND direction = ? AND status = ?
+		RETURNING `+messageColumns,
+		StatusRead, at.UnixMilli(), chat, DirectionInbound, StatusReceived)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+// ftsQuery transforma o texto livre em uma consulta FTS segura: cada termo vira "termo*".
+func ftsQuery(query string) string {
+	var terms []string
+	for t := range strings.FieldsSeq(query) {
+		t = strings.ReplaceAll(t, `"`, "")
+		t = strings.TrimRight(t, "*")
+		if t != "" {
+			terms = append(terms, `"`+t+`*"`)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+func prefixColumns(alias, columns string) string {
+	cols := strings.Split(columns, ", ")
+	for i, c := range cols {
+		cols[i] = alias + "." + c
+	}
+	return strings.Join(cols, ", ")
+}
+
+// AdvanceStatus atualiza o status das mensagens enviadas somente se for um avanço
+// (ex: "read" não volta para "delivered" quando os recibos chegam fora de ordem).
+// Retorna as mensagens que mudaram.
+func (ms *MessageStore) AdvanceStatus(ctx context.Context, ids []string, status string, at time.Time) ([]MessageRecord, error) {
+	var changed []MessageRecord
+	for _, id := range ids {
+		m, err := ms.Get(ctx, id)
+		if errors.Is(err, ErrMessageNotFound) {
+			continue
+		}
+		if err != nil {
+			return changed, err
+		}
+		if m.Direction != DirectionOutbound || !canAdvance(m.Status, status) {
+			continue
+		}
+
+		if _, err := ms.db.ExecContext(ctx,
+			"UPDATE messages SET status = ?, status_at = ? WHERE chat = ? AND id = ?",
+			status, at.UnixMilli(), m.Chat, m.ID); err != nil {
+			return changed, err
+		}
+
+		m.Status = status
+		m.StatusAt = at
+		changed = append(changed, m)
+	}
+	return changed, nil
+}
+
+// canAdvance só permite avançar o status; falha reportada pelo servidor só vale antes da entrega.
+func canAdvance(from, to string) bool {
+	if to == StatusFailed {
+		return from == StatusSent
+	}
+	return statusRank[to] > statusRank[from]
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanMessage lê as colunas de messageColumns; extra recebe colunas adicionais da consulta.
+func scanMessage(row rowScanner, extra ...any) (MessageRecord, error) {
+	var m MessageRecord
+	var ts, statusAt int64
+	dest := []any{&m.ID, &m.Chat, &m.Sender, &m.Direction, &m.Type, &m.Text, &m.Media, &ts, &m.Status, &statusAt, &m.Error, &m.RequestID}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return MessageRecord{}, err
+	}
+	m.Timestamp = time.UnixMilli(ts)
+	m.StatusAt = time.UnixMilli(statusAt)
+	return m, nil
+}
+
+func scanMessages(rows *sql.Rows) ([]MessageRecord, error) {
+	defer rows.Close()
+
+	messages := make([]MessageRecord, 0)
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// messageText extrai o texto (ou legenda) de uma mensagem.
+func messageText(msg *waE2E.Message) string {
+	switch {
+	case msg.GetConversation() != "":
+		return msg.GetConversation()
+	case msg.GetExtendedTextMessage() != nil:
+		return msg.GetExtendedTextMessage().GetText()
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage().GetCaption()
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage().GetCaption()
+	case msg.GetDocumentMessage() != nil:
+		return msg.GetDocumentMessage().GetCaption()
+	case msg.GetReactionMessage() != nil:
+		return msg.GetReactionMessage().GetText()
+	}
+	return ""
+}
+
+// messageType classifica a mensagem para o histórico ("" = não registrar, ex: protocolo).
+func messageType(msg *waE2E.Message) string {
+	switch {
+	case msg.GetConversation() != "", msg.GetExtendedTextMessage() != nil:
+		return "text"
+	case msg.GetImageMessage() != nil:
+		return "image"
+	case msg.GetVideoMessage() != nil:
+		return "video"
+	case msg.GetAudioMessage() != nil:
+		return "audio"
+	case msg.GetDocumentMessage() != nil:
+		return "document"
+	case msg.GetStickerMessage() != nil:
+		return "sticker"
+	case msg.GetReactionMessage() != nil:
+		return "reaction"
+	case msg.GetProtocolMessage() != nil:
+		return ""
+	}
+	return "other"
+}
+
+// mediaRef devolve o direct path da mídia, usado para baixá-la depois.
+func mediaRef(msg *waE2E.Message) string {
+	switch {
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage().GetDirectPath()
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage().GetDirectPath()
+	case msg.GetAudioMessage() != nil:
+		return msg.GetAudioMessage().GetDirectPath()
+	case msg.GetDocumentMessage() != nil:
+		return msg.GetDocumentMessage().GetDirectPath()
+	case msg.GetStickerMessage() != nil:
+		return msg.GetStickerMessage().GetDirectPath()
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package clientservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/eventhub"
+	"github.com/simpplify-org/GO-simpzap/pkg/logging"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Tamanhos do histórico em memória do stream e da fila de cada assinante.
+const (
+	streamBufferSize     = 1000
+	subscriberBufferSize = 256
+)
+
+// Events retorna o hub que alimenta o stream de eventos do device (/events).
+func (s *WhatsAppService) Events() *eventhub.Hub {
+	return s.events
+}
+
+// stream publica o evento para os assinantes de /events e para os sinks configurados.
+func (s *WhatsAppService) stream(ctx context.Context, eventType string, data any) {
+	evt := s.events.Publish(eventhub.Event{
+		Type:      eventType,
+		Device:    s.phoneNumber,
+		Timestamp: time.Now(),
+		Data:      data,
+		RequestID: logging.RequestID(ctx),
+	})
+	s.sinks.Dispatch(evt)
+}
+
+// Presence é o payload dos eventos "presence" (online/offline de um contato) e
+// "chat_presence" (digitando/gravando em um chat).
+type Presence struct {
+	JID      string     `json:"jid"`
+	Chat     string     `json:"chat,omitempty"`
+	State    string     `json:"state"`           // available, unavailable, composing, paused
+	Media    string     `json:"media,omitempty"` // "audio" quando está gravando
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+}
+
+// handlePresenceEvent repassa mudanças de presença para o stream de /events.
+func (s *WhatsAppService) handlePresenceEvent(evt any) {
+	switch v := evt.(type) {
+	case *events.Presence:
+		p := Presence{JID: v.From.ToNonAD().String(), State: "available"}
+		if v.Unavailable {
+			p.State = "unavailable"
+		}
+		if !v.LastSeen.IsZero() {
+			p.LastSeen = &v.LastSeen
+		}
+		s.stream(s.ctx, "presence", p)
+	case *events.ChatPresence:
+		s.stream(s.ctx, "chat_presence", Presence{
+			JID:   v.Sender.ToNonAD().String(),
+			Chat:  v.Chat.String(),
+			State: string(v.State),
+			Media: string(v.Media),
+		})
+	}
+}
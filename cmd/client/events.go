@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/simpplify-org/GO-simpzap/cmd/client/clientservice"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/eventhub"
+)
+
+// handleEvents - GET /events — stream de eventos do device via WebSocket ou SSE.
+//
+// ?types=message,message_status filtra os tipos; ?last_event_id= (ou o header
+// Last-Event-ID, enviado automaticamente pelo EventSource) retoma após o último evento recebido.
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	filter, after := eventhub.ParseRequest(r)
+	service.Events().Serve(w, r, filter, after)
+}
+
+// handleEventSinks - GET/POST/DELETE /sinks — lista, adiciona ou remove destinos dos eventos ({"url": "..."})
+func handleEventSinks(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		URL string `json:"url"`
+	}
+	if r.Method == http.MethodPost || r.Method == http.MethodDelete {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
+			http.Error(w, "payload inválido, envie {\"url\": \"...\"}", http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, service.EventSinks())
+	case http.MethodPost:
+		err := service.AddEventSink(req.URL, true)
+		switch {
+		case errors.Is(err, clientservice.ErrSinkExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case err != nil:
+			http.Error(w, err.Error(), sendErrorStatus(err))
+		default:
+			writeJSON(w, http.StatusCreated, map[string]string{"status": "created"})
+		}
+	case http.MethodDelete:
+		if !service.RemoveEventSink(req.URL) {
+			http.Error(w, "sink não encontrado", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+	default:
+		http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+	}
+}
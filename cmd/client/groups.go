@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// handleListGroups - GET /groups — lista os grupos com nome e participantes
+func handleListGroups(w http.ResponseWriter, r *http.Request) {
+	if service == nil || !service.IsConnected() {
+		http.Error(w, "Cliente WhatsApp não conectado", http.StatusServiceUnavailable)
+		return
+	}
+
+	groups, err := service.ListGroups()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+// handleGetGroup - GET /groups/info?group=<id>@g.us — detalhes de um grupo
+func handleGetGroup(w http.ResponseWriter, r *http.Request) {
+	if service == nil || !service.IsConnected() {
+		http.Error(w, "Cliente WhatsApp não conectado", http.StatusServiceUnavailable)
+		return
+	}
+
+	group, err := service.GetGroup(r.URL.Query().Get("group"))
+	if err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+// handleCreateGroup - POST /groups/create — cria um grupo
+func handleCreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name         string   `json:"name"`
+		Participants []string `json:"participants"`
+	}
+	if !decodeConnectedRequest(w, r, &req) {
+		return
+	}
+
+	group, err := service.CreateGroup(req.Name, req.Participants)
+	if err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+
+	slog.Info("👥 Grupo criado", "group", group.JID, "name", group.Name)
+	writeJSON(w, http.StatusCreated, group)
+}
+
+// handleSetGroupName - POST /groups/name — altera o nome do grupo
+func handleSetGroupName(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Group string `json:"group"`
+		Name  string `json:"name"`
+	}
+	if !decodeConnectedRequest(w, r, &req) {
+		return
+	}
+
+	if err := service.SetGroupName(req.Group, req.Name); err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
+
+// handleSetGroupDescription - POST /groups/description — altera a descrição do grupo
+func handleSetGroupDescription(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Group       string `json:"group"`
+		Description string `json:"description"`
+	}
+	if !decodeConnectedRequest(w, r, &req) {
+		return
+	}
+
+	if err := service.SetGroupDescription(req.Group, req.Description); err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
+
+// handleSetGroupPicture - POST /groups/picture — altera a foto do grupo (JPEG em base64 ou data URL)
+func handleSetGroupPicture(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Group string `json:"group"`
+		Image string `json:"image"`
+	}
+	if !decodeConnectedRequest(w, r, &req) {
+		return
+	}
+
+	encoded := req.Image
+	if i := strings.Index(encoded, ","); strings.HasPrefix(encoded, "data:") && i >= 0 {
+		encoded = encoded[i+1:]
+	}
+	image, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(image) == 0 {
+		http.Error(w, "imagem inválida, envie um JPEG em base64", http.StatusBadRequest)
+		return
+	}
+
+	pictureID, err := service.SetGroupPicture(req.Group, image)
+	if err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "updated", "picture_id": pictureID})
+}
+
+// handleUpdateGroupParticipants - POST /groups/participants — add, remove, promote ou demote
+func handleUpdateGroupParticipants(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Group        string   `json:"group"`
+		Action       string   `json:"action"`
+		Participants []string `json:"participants"`
+	}
+	if !decodeConnectedRequest(w, r, &req) {
+		return
+	}
+	if len(req.Participants) == 0 {
+		http.Error(w, "Nenhum participante informado", http.StatusBadRequest)
+		return
+	}
+
+	results, err := service.UpdateGroupParticipants(req.Group, req.Action, req.Participants)
+	if err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "results": results})
+}
+
+// handleGroupInviteLink - GET /groups/invite?group=...&reset=true — obtém (ou gera novo) link de convite
+func handleGroupInviteLink(w http.ResponseWriter, r *http.Request) {
+	if service == nil || !service.IsConnected() {
+		http.Error(w, "Cliente WhatsApp não conectado", http.StatusServiceUnavailable)
+		return
+	}
+
+	reset := r.URL.Query().Get("reset") == "true"
+	link, err := service.GetGroupInviteLink(r.URL.Query().Get("group"), reset)
+	if err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"link": link})
+}
+
+// handleRevokeGroupInviteLink - POST /groups/invite/revoke — invalida o link de convite atual
+func handleRevokeGroupInviteLink(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Group string `json:"group"`
+	}
+	if !decodeConnectedRequest(w, r, &req) {
+		return
+	}
+
+	if err := service.RevokeGroupInviteLink(req.Group); err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
+
+// handleJoinGroup - POST /groups/join — entra em um grupo pelo link de convite
+func handleJoinGroup(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Link string `json:"link"`
+	}
+	if !decodeConnectedRequest(w, r, &req) {
+		return
+	}
+
+	jid, err := service.JoinGroup(req.Link)
+	if err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "joined", "group": jid})
+}
+
+// handleLeaveGroup - POST /groups/leave — sai do grupo
+func handleLeaveGroup(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Group string `json:"group"`
+	}
+	if !decodeConnectedRequest(w, r, &req) {
+		return
+	}
+
+	if err := service.LeaveGroup(req.Group); err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "left"})
+}
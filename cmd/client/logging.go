@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/tracing"
+)
+
+// requestLogMiddleware registra cada requisição com método, rota, status e latência.
+// Roda depois de tracing.Middleware, que coloca o request_id e o trace no contexto.
+func requestLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := tracing.NewStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		status := rec.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		case r.URL.Path == "/health" || r.URL.Path == "/metrics":
+			// Chamadas periódicas de health check e scrape só aparecem em debug
+			level = slog.LevelDebug
+		}
+		slog.LogAttrs(r.Context(), level, "Requisição",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int64("latency_ms", time.Since(start).Milliseconds()),
+		)
+	})
+}
@@ -3,33 +3,40 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/simpplify-org/GO-simpzap/cmd/client/clientservice"
+	"github.com/simpplify-org/GO-simpzap/pkg/config"
+	"github.com/simpplify-org/GO-simpzap/pkg/logging"
+	"github.com/simpplify-org/GO-simpzap/pkg/phone"
+	"github.com/simpplify-org/GO-simpzap/pkg/tracing"
 
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var (
-	ctx         = context.Background()
-	phoneNumber = os.Getenv("PHONE_NUMBER")
-	service     *clientservice.WhatsAppService
+	ctx     = context.Background()
+	cfg     config.Client
+	service *clientservice.WhatsAppService
 )
 
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
-// handleConnectWS → mantém o socket aberto para exibir QR e status
+// handleConnectWS → mantém o socket aberto para exibir QR (ou código de pareamento) e status
 func handleConnectWS(w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println("❌ Erro ao iniciar WebSocket:", err)
+		slog.Warn("❌ Erro ao iniciar WebSocket", "error", err)
 		return
 	}
 	defer conn.Close()
@@ -40,59 +47,149 @@ func handleConnectWS(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !service.HasID() {
-		// Garante que o client está desconectado antes de abrir o canal QR
-		// (evita o erro "GetQRChannel must be called before connecting")
-		if service.IsConnected() {
-			service.Disconnect()
-		}
-
-		qrChan, err := service.GetQRChannel()
-		if err != nil {
+		streamPairing(conn, r)
+	} else {
+		if err := service.Connect(); err != nil {
 			conn.WriteJSON(map[string]string{"error": err.Error()})
 			return
 		}
+		conn.WriteJSON(map[string]string{"event": "reconnected"})
+	}
+}
+
+// handleRepairWS → desvincula a sessão atual e já inicia um novo pareamento no mesmo socket
+func handleRepairWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("❌ Erro ao iniciar WebSocket", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	if service == nil {
+		conn.WriteJSON(map[string]string{"error": "Serviço WhatsApp não inicializado"})
+		return
+	}
+
+	if _, err := service.Logout(r.Context()); err != nil {
+		conn.WriteJSON(map[string]string{"error": err.Error()})
+		return
+	}
+	streamPairing(conn, r)
+}
+
+// streamPairing repassa os eventos do pareamento (QR ou código) para o WebSocket
+func streamPairing(conn *websocket.Conn, r *http.Request) {
+	// ?method=code troca o QR pelo código de pareamento (para quando o celular é a própria tela)
+	byCode := r.URL.Query().Get("method") == "code"
+	events, err := service.StartPairing(r.Context(), byCode, r.URL.Query().Get("phone"))
+	if err != nil {
+		conn.WriteJSON(map[string]string{"error": err.Error()})
+		return
+	}
 
-		go func() {
-			// mantém o client conectado
-			if err := service.Connect(); err != nil {
-				conn.WriteJSON(map[string]string{"error": err.Error()})
-				return
-			}
-		}()
-
-		for evt := range qrChan {
-			switch evt.Event {
-			case "code":
-				dataURL, err := clientservice.EncodeQRToDataURL(evt.Code)
-				if err != nil {
-					log.Printf("Erro ao codificar QR: %v", err)
-					conn.WriteJSON(map[string]string{"error": "Erro ao gerar QR Code"})
-					return
-				}
-				conn.WriteJSON(map[string]any{
-					"event": "qr",
-					"image": dataURL,
-				})
-			case "success":
-				conn.WriteJSON(map[string]any{"event": "success"})
-			case "timeout":
-				conn.WriteJSON(map[string]any{"event": "timeout"})
-			}
+	for evt := range events {
+		if err := conn.WriteJSON(evt); err != nil {
+			return
 		}
-	} else {
-		if err := service.Connect(); err != nil {
-			conn.WriteJSON(map[string]string{"error": err.Error()})
+	}
+}
+
+// handleLogout - POST /logout — desvincula o número no WhatsApp e apaga a sessão local
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	unlinked, err := service.Logout(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "logged_out", "unlinked": unlinked})
+}
+
+// handleResetSession - POST /reset — apaga apenas a sessão local (para sessões mortas/corrompidas)
+func handleResetSession(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := service.ResetSession(r.Context(), "reset"); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "reset"})
+}
+
+// handleConnectCode - POST /connect/code — inicia o login por código de pareamento e retorna o código
+func handleConnectCode(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Phone string `json:"phone"` // opcional; padrão é o número do device
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
 			return
 		}
-		conn.WriteJSON(map[string]string{"event": "reconnected"})
 	}
+
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	// O fluxo continua após a resposta, então não pode depender do contexto da requisição
+	events, err := service.StartPairing(ctx, true, req.Phone)
+	if errors.Is(err, clientservice.ErrAlreadyPaired) || errors.Is(err, clientservice.ErrPairingInProgress) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+
+	evt, ok := <-events
+	go func() {
+		// Consome o restante do fluxo (success/timeout), que também chega pelo webhook de eventos
+		for evt := range events {
+			slog.Info("🔢 Pareamento por código", "event", evt.Event, "error", evt.Error)
+		}
+	}()
+
+	switch {
+	case !ok:
+		http.Error(w, "fluxo de pareamento encerrado", http.StatusInternalServerError)
+	case evt.Event == "pair_code":
+		writeJSON(w, http.StatusOK, evt)
+	default:
+		writeJSON(w, http.StatusBadGateway, evt)
+	}
+}
+
+// handleStatus - GET /status — estado da conexão (connecting, connected, disconnected, logged_out, replaced, banned)
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusOK, service.Status())
+}
+
+// handleConfig - GET /config — configuração efetiva do child, sem segredos
+func handleConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, cfg.Redacted())
 }
 
 // handleSendMessage - POST /send — envia para um número
 func handleSendMessage(w http.ResponseWriter, r *http.Request) {
 	type SendRequest struct {
-		Number  string `json:"number"`
-		Message string `json:"message"`
+		Number   string   `json:"number"` // número, JID ou grupo (@g.us)
+		Message  string   `json:"message"`
+		Mentions []string `json:"mentions"`
 	}
 
 	var req SendRequest
@@ -106,31 +203,66 @@ func handleSendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("📤 Enviando mensagem para %s...\n", req.Number)
+	slog.DebugContext(r.Context(), "📤 Enviando mensagem", "to", req.Number, "body", req.Message)
 
-	resp, err := service.SendMessage(req.Number, req.Message)
+	resp, err := service.SendMessageContext(r.Context(), req.Number, req.Message, req.Mentions...)
 	if err != nil {
-		log.Printf("❌ Erro ao enviar para %s: %v\n", req.Number, err)
-		http.Error(w, fmt.Sprintf("Erro ao enviar: %v", err), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "❌ Erro ao enviar mensagem", "to", req.Number, "error", err)
+		http.Error(w, fmt.Sprintf("Erro ao enviar: %v", err), sendErrorStatus(err))
 		return
 	}
 
-	log.Printf("✅ Mensagem enviada para %s (ID: %s)\n", req.Number, resp.ID)
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "ok",
-		"id":     resp.ID,
+	slog.InfoContext(r.Context(), "✅ Mensagem enviada", "to", req.Number, "message_id", resp.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":    "ok",
+		"id":        resp.ID,
+		"chat":      resp.Chat,
+		"timestamp": resp.Timestamp,
 	})
 }
 
+// decodeConnectedRequest lê o JSON e garante que o cliente está conectado
+func decodeConnectedRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return false
+	}
+	if service == nil || !service.IsConnected() {
+		http.Error(w, "Cliente WhatsApp não conectado", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// sendErrorStatus diferencia destinatário inválido/inexistente de falha no envio
+func sendErrorStatus(err error) int {
+	if errors.Is(err, phone.ErrInvalid) ||
+		errors.Is(err, clientservice.ErrNotOnWhatsApp) ||
+		errors.Is(err, clientservice.ErrInvalidRecipient) ||
+		errors.Is(err, clientservice.ErrInvalidRequest) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 // handleSendManyMessages - POST /send/many — envia mesma mensagem para vários números
 func handleSendManyMessages(w http.ResponseWriter, r *http.Request) {
 	type SendManyRequest struct {
-		Numbers []string `json:"numbers"`
-		Message string   `json:"message"`
+		Numbers  []string `json:"numbers"`
+		Message  string   `json:"message"`
+		Mentions []string `json:"mentions"`
 	}
 	type SendResult struct {
 		Number string `json:"number"`
 		ID     string `json:"id,omitempty"`
+		Chat   string `json:"chat,omitempty"`
 		Error  string `json:"error,omitempty"`
 	}
 
@@ -152,17 +284,17 @@ func handleSendManyMessages(w http.ResponseWriter, r *http.Request) {
 
 	results := make([]SendResult, 0, len(req.Numbers))
 	for _, number := range req.Numbers {
-		log.Printf("📤 Enviando mensagem para %s...\n", number)
+		slog.DebugContext(r.Context(), "📤 Enviando mensagem", "to", number, "body", req.Message)
 
-		resp, err := service.SendMessage(number, req.Message)
+		resp, err := service.SendMessageContext(r.Context(), number, req.Message, req.Mentions...)
 		if err != nil {
-			log.Printf("❌ Erro ao enviar para %s: %v\n", number, err)
+			slog.ErrorContext(r.Context(), "❌ Erro ao enviar mensagem", "to", number, "error", err)
 			results = append(results, SendResult{Number: number, Error: err.Error()})
 			continue
 		}
 
-		log.Printf("✅ Enviado para %s (ID: %s)\n", number, resp.ID)
-		results = append(results, SendResult{Number: number, ID: resp.ID})
+		slog.InfoContext(r.Context(), "✅ Mensagem enviada", "to", number, "message_id", resp.ID)
+		results = append(results, SendResult{Number: number, ID: resp.ID, Chat: resp.Chat})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -191,7 +323,10 @@ func handleRegisterWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	service.RegisterWebhook(req.Number, req.Phrase, req.CallbackURL)
+	if err := service.RegisterWebhook(req.Number, req.Phrase, req.CallbackURL); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	json.NewEncoder(w).Encode(map[string]string{
 		"status": "registered",
@@ -235,12 +370,91 @@ func handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleSyncWebhooks - PUT /webhook/sync — substitui todas as regras (enviado pelo master)
+func handleSyncWebhooks(w http.ResponseWriter, r *http.Request) {
+	var req []struct {
+		Number      string `json:"number"`
+		Phrase      string `json:"phrase"`
+		CallbackURL string `json:"callback_url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "payload inválido", http.StatusBadRequest)
+		return
+	}
+
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	rules := make(map[string][]clientservice.WebhookRule)
+	for _, rule := range req {
+		rules[rule.Number] = append(rules[rule.Number], clientservice.WebhookRule{
+			Phrase:      rule.Phrase,
+			CallbackURL: rule.CallbackURL,
+		})
+	}
+
+	if err := service.ReplaceWebhooks(rules); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"status": "synced", "rules": len(req)})
+}
+
+// handleEventWebhook - GET/POST /webhook/events — consulta ou define o webhook que recebe os eventos do device
+func handleEventWebhook(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var req struct {
+			URL string `json:"url"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "payload inválido", http.StatusBadRequest)
+			return
+		}
+		service.SetEventWebhook(req.URL)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"url": service.EventWebhook(),
+	})
+}
+
+// handleStatusWebhook - GET/POST /webhook/status — consulta ou define o webhook de status das mensagens enviadas
+func handleStatusWebhook(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var req struct {
+			URL string `json:"url"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "payload inválido", http.StatusBadRequest)
+			return
+		}
+		service.SetStatusWebhook(req.URL)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"url": service.StatusWebhook()})
+}
+
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Libera a origem (para produção, você pode trocar "*" por "http://localhost:3000")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, X-Request-Id")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-Id")
 
 		// Se for uma requisição OPTIONS (Preflight do navegador), retorna 200 OK e para por aqui
 		if r.Method == "OPTIONS" {
@@ -255,32 +469,98 @@ func corsMiddleware(next http.Handler) http.Handler {
 
 // main com logs e shutdown gracioso
 func main() {
+	logging.Setup("client")
+
 	var err error
-	service, err = clientservice.NewWhatsAppService(ctx, phoneNumber)
+	cfg, err = config.LoadClient()
+	if err != nil {
+		slog.Error("Configuração inválida", "error", err)
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, "client")
 	if err != nil {
-		log.Fatalf("Erro ao inicializar o serviço client WhatsApp: %v", err)
+		slog.Error("Erro ao configurar tracing", "error", err)
+		os.Exit(1)
+	}
+
+	service, err = clientservice.NewWhatsAppService(ctx, cfg)
+	if err != nil {
+		slog.Error("Erro ao inicializar o serviço client WhatsApp", "error", err)
+		os.Exit(1)
+	}
+	service.SetEventWebhook(cfg.EventWebhookURL)
+	service.SetStatusWebhook(cfg.StatusWebhookURL)
+	service.SetMasterURL(cfg.MasterURL, cfg.MasterToken)
+	for _, url := range clientservice.ParseSinkList(cfg.EventSinks) {
+		if err := service.AddEventSink(url, false); err != nil {
+			slog.Warn("⚠️ Sink de eventos ignorado", "url", url, "error", err)
+		}
+	}
+	if err := service.LoadEventSinks(); err != nil {
+		slog.Warn("⚠️ Sinks gravados não carregados", "error", err)
+	}
+	if cfg.Features.AutoConnect {
+		service.AutoConnect()
 	}
 
 	http.HandleFunc("/connect/ws", handleConnectWS)
+	http.HandleFunc("POST /connect/code", handleConnectCode)
+	http.HandleFunc("/repair/ws", handleRepairWS)
+	http.HandleFunc("POST /logout", handleLogout)
+	http.HandleFunc("POST /reset", handleResetSession)
 	http.HandleFunc("/send", handleSendMessage)
 	http.HandleFunc("/send/many", handleSendManyMessages)
+	http.HandleFunc("/send/reply", handleReply)
+	http.HandleFunc("/send/reaction", handleReact)
+	http.HandleFunc("/send/edit", handleEdit)
+	http.HandleFunc("/send/revoke", handleRevoke)
+	http.HandleFunc("/groups", handleListGroups)
+	http.HandleFunc("/groups/info", handleGetGroup)
+	http.HandleFunc("/groups/create", handleCreateGroup)
+	http.HandleFunc("/groups/name", handleSetGroupName)
+	http.HandleFunc("/groups/description", handleSetGroupDescription)
+	http.HandleFunc("/groups/picture", handleSetGroupPicture)
+	http.HandleFunc("/groups/participants", handleUpdateGroupParticipants)
+	http.HandleFunc("/groups/invite", handleGroupInviteLink)
+	http.HandleFunc("/groups/invite/revoke", handleRevokeGroupInviteLink)
+	http.HandleFunc("/groups/join", handleJoinGroup)
+	http.HandleFunc("/groups/leave", handleLeaveGroup)
+	http.HandleFunc("/webhook/events", handleEventWebhook)
+	http.HandleFunc("/webhook/status", handleStatusWebhook)
+	http.HandleFunc("GET /messages/{id}", handleGetMessage)
+	http.HandleFunc("GET /messages/search", handleSearchMessages)
+	http.HandleFunc("GET /chats", handleListChats)
+	http.HandleFunc("GET /chats/{chat}/messages", handleListChatMessages)
+	http.HandleFunc("POST /chats/{chat}/read", handleMarkChatRead)
 	http.HandleFunc("/webhook/register", handleRegisterWebhook)
 	http.HandleFunc("/webhook/list", handleListWebhooks)
 	http.HandleFunc("/webhook/delete", handleDeleteWebhook)
+	http.HandleFunc("PUT /webhook/sync", handleSyncWebhooks)
+	http.HandleFunc("GET /status", handleStatus)
+	http.HandleFunc("GET /config", handleConfig)
+	http.HandleFunc("GET /events", handleEvents)
+	http.HandleFunc("/sinks", handleEventSinks)
+	http.HandleFunc("/autoresponder", handleAutoResponder)
+	http.HandleFunc("/autoresponder/sessions", handleAutoResponderSessions)
+	if cfg.Features.Metrics {
+		http.Handle("GET /metrics", promhttp.Handler())
+	}
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprintln(w, "ok")
 	})
 
 	server := &http.Server{
-		Addr:    ":8080",
-		Handler: corsMiddleware(http.DefaultServeMux),
+		Addr:    cfg.ListenAddr,
+		Handler: tracing.Middleware(requestLogMiddleware(corsMiddleware(http.DefaultServeMux))),
 	}
 
 	go func() {
-		log.Println("🚀 Servidor HTTP iniciado em http://localhost:8080")
+		slog.Info("🚀 Servidor HTTP iniciado", "addr", server.Addr, "device", cfg.PhoneNumber)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Erro no servidor: %v", err)
+			slog.Error("Erro no servidor", "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -289,8 +569,29 @@ func main() {
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c
 
-	log.Println("🧹 Encerrando cliente WhatsApp...")
+	slog.Info("🧹 Encerrando cliente WhatsApp...")
+	if service != nil {
+		// Fecha o /events antes: a assinatura SSE do master seguraria o server.Shutdown até o prazo
+		server.RegisterOnShutdown(service.Events().Shutdown)
+	}
+
+	// Para de aceitar requisições e espera as que estão em andamento (envios via API)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Std())
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Requisições interrompidas no encerramento", "error", err)
+	}
+
+	// Webhooks e respostas automáticas em andamento têm prazo próprio
 	if service != nil {
-		service.Disconnect()
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Std())
+		defer cancelDrain()
+		service.Shutdown(drainCtx)
+	}
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Warn("Erro ao enviar spans pendentes", "error", err)
 	}
 }
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/simpplify-org/GO-simpzap/cmd/client/clientservice"
+)
+
+// writeSent responde com o ID da mensagem gerada, que pode ser alvo de novas operações
+func writeSent(w http.ResponseWriter, action string, sent clientservice.SentMessage, err error) {
+	if err != nil {
+		slog.Error("❌ Erro ao executar ação", "action", action, "error", err)
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+
+	slog.Info("✅ Ação enviada", "action", action, "chat", sent.Chat, "message_id", sent.ID)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":    "ok",
+		"id":        sent.ID,
+		"chat":      sent.Chat,
+		"timestamp": sent.Timestamp,
+	})
+}
+
+// handleReply - POST /send/reply — responde citando uma mensagem
+func handleReply(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		clientservice.MessageRef
+		QuotedText string   `json:"quoted_text"`
+		Message    string   `json:"message"`
+		Mentions   []string `json:"mentions"`
+	}
+	if !decodeConnectedRequest(w, r, &req) {
+		return
+	}
+
+	sent, err := service.Reply(req.MessageRef, req.QuotedText, req.Message, req.Mentions...)
+	writeSent(w, "Resposta", sent, err)
+}
+
+// handleReact - POST /send/reaction — reage a uma mensagem (emoji vazio remove a reação)
+func handleReact(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		clientservice.MessageRef
+		Emoji string `json:"emoji"`
+	}
+	if !decodeConnectedRequest(w, r, &req) {
+		return
+	}
+
+	sent, err := service.React(req.MessageRef, req.Emoji)
+	writeSent(w, "Reação", sent, err)
+}
+
+// handleEdit - POST /send/edit — edita uma mensagem enviada por este número
+func handleEdit(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		clientservice.MessageRef
+		Message string `json:"message"`
+	}
+	if !decodeConnectedRequest(w, r, &req) {
+		return
+	}
+
+	sent, err := service.Edit(req.MessageRef, req.Message)
+	writeSent(w, "Edição", sent, err)
+}
+
+// handleRevoke - POST /send/revoke — apaga uma mensagem para todos
+func handleRevoke(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		clientservice.MessageRef
+	}
+	if !decodeConnectedRequest(w, r, &req) {
+		return
+	}
+
+	sent, err := service.Revoke(req.MessageRef)
+	writeSent(w, "Revogação", sent, err)
+}
+
+// handleGetMessage - GET /messages/{id} — status de entrega/leitura de uma mensagem
+func handleGetMessage(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	msg, err := service.GetMessage(r.PathValue("id"))
+	if errors.Is(err, clientservice.ErrMessageNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, msg)
+}
+
+// queryInt lê um inteiro da query string com valor padrão e teto
+func queryInt(r *http.Request, name string, def, max int) int {
+	v, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil || v <= 0 {
+		return def
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// handleListChats - GET /chats?limit=&offset= — caixa de entrada com última mensagem e não lidas
+func handleListChats(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	chats, err := service.ListChats(queryInt(r, "limit", 50, 500), max(offset, 0))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, chats)
+}
+
+// handleListChatMessages - GET /chats/{chat}/messages?before=&limit= — pagina as mensagens de um chat
+func handleListChatMessages(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	var before clientservice.MessageCursor
+	if v := r.URL.Query().Get("before"); v != "" {
+		var err error
+		if before, err = clientservice.ParseMessageCursor(v); err != nil {
+			http.Error(w, "before deve ser o next_before da página anterior (ou um timestamp RFC3339)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	messages, next, err := service.ListChatMessages(r.PathValue("chat"), before, queryInt(r, "limit", 50, 500))
+	if err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+
+	resp := map[string]any{"messages": messages}
+	if len(messages) > 0 {
+		// cursor para a próxima página: timestamp e rowid da última mensagem
+		resp["next_before"] = next.String()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleMarkChatRead - POST /chats/{chat}/read — marca o chat como lido
+func handleMarkChatRead(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	count, err := service.MarkChatRead(r.PathValue("chat"))
+	if err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "read": count})
+}
+
+// handleSearchMessages - GET /messages/search?q=&chat=&limit= — busca textual no histórico
+func handleSearchMessages(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		http.Error(w, "Serviço WhatsApp não inicializado", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	if q.Get("q") == "" {
+		http.Error(w, "Parâmetro q obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	messages, err := service.SearchMessages(q.Get("q"), q.Get("chat"), queryInt(r, "limit", 50, 500))
+	if err != nil {
+		http.Error(w, err.Error(), sendErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, messages)
+}
@@ -2,12 +2,26 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	_ "embed"
-	"log"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	_ "time/tzdata" // quota.timezone funciona mesmo em imagens sem zoneinfo
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	_ "github.com/lib/pq"
 	"github.com/simpplify-org/GO-simpzap/app"
+	"github.com/simpplify-org/GO-simpzap/pkg/config"
+	"github.com/simpplify-org/GO-simpzap/pkg/logging"
+	"github.com/simpplify-org/GO-simpzap/pkg/tracing"
 )
 
 //go:embed qr.html
@@ -15,25 +29,143 @@ var dashHTML []byte
 
 func main() {
 	ctx := context.Background()
+	logging.Setup("master")
 
-	//TODO conectar ao banco de dados
-	//TODO conecta ao repositorio com a conexao do banco
+	cfg, err := config.LoadMaster()
+	if err != nil {
+		fatal("Configuração inválida", err)
+	}
 
-	svc := app.NewWhatsAppService(ctx) //add adiciona o repositorio do webhook
+	shutdownTracing, err := tracing.Setup(ctx, "master")
+	if err != nil {
+		fatal("Erro ao configurar tracing", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Banco é opcional: sem ele o master funciona, mas a outbox (whats_webhook) fica desligada
+	var repo *app.WebhookRepository
+	dsn := cfg.Database.DSN()
+	if dsn != "" {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			fatal("Erro ao abrir banco", err)
+		}
+		if err := db.PingContext(ctx); err != nil {
+			fatal("Erro ao conectar no banco", err)
+		}
+		defer db.Close()
+		repo = app.NewWebhookRepository(db)
+	} else {
+		slog.Warn("DATABASE_URL/DB_HOST não definidos, outbox desativada")
+	}
+
+	svc := app.NewWhatsAppService(ctx, cfg, repo)
+	svc.StartOutbox(dsn)
 	h := app.NewWhatsAppHandler(svc)
 	h.DashHTML = dashHTML
 
 	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
 	e.Use(middleware.Recover())
-	e.Use(middleware.Logger())
+	e.Use(echo.WrapMiddleware(tracing.Middleware))
+	e.Use(requestLogger())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept},
+		AllowOrigins:  []string{"*"},
+		AllowHeaders:  []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderXRequestID, app.HeaderAPIKey},
+		ExposeHeaders: []string{echo.HeaderXRequestID, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", echo.HeaderRetryAfter},
 	}))
 
 	h.RegisterRoutes(e)
 
-	addr := ":8080"
-	log.Printf("[MAIN] Servidor iniciado em %s", addr)
-	e.Logger.Fatal(e.Start(addr))
+	go func() {
+		slog.Info("Servidor iniciado", "addr", cfg.ListenAddr)
+		if err := e.Start(cfg.ListenAddr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("Servidor encerrado", err)
+		}
+	}()
+
+	stopCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-stopCtx.Done()
+	shutdown(svc, e, cfg.ShutdownTimeout.Std())
+}
+
+// shutdown encerra o master sem cortar o que está em andamento: para de aceitar
+// conexões, espera as requisições e proxies até o prazo, fecha os streams
+// (/events e os WebSockets repassados aos childs) e para os workers.
+func shutdown(svc *app.WhatsAppService, e *echo.Echo, timeout time.Duration) {
+	slog.Info("🧹 Encerrando master...", "timeout", timeout.String())
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// O http.Server não acompanha conexões depois do Hijack nem encerra streams
+	// longos; eles são fechados em paralelo com o Shutdown do servidor
+	svc.Events.Hub().Shutdown()
+	var streams sync.WaitGroup
+	streams.Go(func() {
+		if err := svc.Zap.Shutdown(ctx); err != nil {
+			slog.Warn("Streams do proxy fechados à força", "error", err)
+		}
+	})
+
+	if err := e.Shutdown(ctx); err != nil {
+		slog.Warn("Requisições interrompidas no encerramento", "error", err)
+	}
+	streams.Wait()
+
+	if err := svc.Shutdown(ctx); err != nil {
+		slog.Warn("Workers não terminaram no prazo", "error", err)
+	}
+	slog.Info("Master encerrado")
+}
+
+// requestLogger registra cada requisição; o request_id e o trace vêm do contexto
+// preenchido por tracing.Middleware.
+func requestLogger() echo.MiddlewareFunc {
+	return middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+		LogStatus:   true,
+		LogMethod:   true,
+		LogURIPath:  true,
+		LogLatency:  true,
+		LogError:    true,
+		HandleError: true,
+		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			level := slog.LevelInfo
+			switch {
+			case v.Status >= 500:
+				level = slog.LevelError
+			case v.Status >= 400:
+				level = slog.LevelWarn
+			}
+			attrs := []slog.Attr{
+				slog.String("method", v.Method),
+				slog.String("path", v.URIPath),
+				slog.Int("status", v.Status),
+				slog.Int64("latency_ms", v.Latency.Milliseconds()),
+			}
+			if device := deviceFromPath(v.URIPath); device != "" {
+				attrs = append(attrs, slog.String("device", device))
+			}
+			if v.Error != nil {
+				attrs = append(attrs, slog.Any("error", v.Error))
+			}
+			slog.LogAttrs(c.Request().Context(), level, "Requisição", attrs...)
+			return nil
+		},
+	})
+}
+
+// deviceFromPath extrai o número de /device/{number}/... e /devices/{number}/...
+func deviceFromPath(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) >= 2 && (parts[0] == "device" || parts[0] == "devices") {
+		return parts[1]
+	}
+	return ""
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,17 @@
+-- Limites do proxy /device/{number}/... (token bucket) gerenciados pelo master (/ratelimits).
+-- subject '*' é o padrão do escopo; per_minute 0 tira o limite daquele assunto.
+CREATE TABLE whats_rate_limit (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(10) NOT NULL,   -- device, tenant ou key (X-API-Key)
+    subject TEXT NOT NULL,        -- número do device, tenant, API key ou '*'
+    budget VARCHAR(10) NOT NULL,  -- send (/send e /send/*) ou read (demais rotas)
+    per_minute DOUBLE PRECISION NOT NULL,
+    burst INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT "CK_whats_rate_limit_scope" CHECK (scope IN ('device', 'tenant', 'key')),
+    CONSTRAINT "CK_whats_rate_limit_budget" CHECK (budget IN ('send', 'read')),
+    CONSTRAINT "CK_whats_rate_limit_values" CHECK (per_minute >= 0 AND burst > 0)
+);
+
+CREATE UNIQUE INDEX "UQ_whats_rate_limit" ON whats_rate_limit (scope, subject, budget);
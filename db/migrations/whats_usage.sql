@@ -0,0 +1,29 @@
+-- Uso de mensagens enviadas, agregado por dia (no fuso de QUOTA_TIMEZONE), tenant,
+-- device e tipo. O master soma aqui o que contou em memória a cada USAGE_FLUSH_INTERVAL.
+CREATE TABLE whats_usage_daily (
+    day DATE NOT NULL,
+    tenant VARCHAR(100) NOT NULL DEFAULT '', -- '' para devices sem tenant
+    device VARCHAR(20) NOT NULL,
+    type VARCHAR(20) NOT NULL,               -- text, reply, reaction, edit ou revoke
+    count BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (day, tenant, device, type)
+);
+
+CREATE INDEX "IX_whats_usage_daily_tenant" ON whats_usage_daily (tenant, day);
+
+-- Cotas de mensagens por tenant ou device (/quotas). NULL usa o padrão da configuração;
+-- 0 tira a cota daquele assunto.
+CREATE TABLE whats_quota (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(10) NOT NULL,  -- tenant ou device
+    subject TEXT NOT NULL,       -- tenant ou número do device
+    daily BIGINT,
+    monthly BIGINT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT "CK_whats_quota_scope" CHECK (scope IN ('tenant', 'device')),
+    CONSTRAINT "CK_whats_quota_values" CHECK (COALESCE(daily, 0) >= 0 AND COALESCE(monthly, 0) >= 0)
+);
+
+CREATE UNIQUE INDEX "UQ_whats_quota" ON whats_quota (scope, subject);
@@ -0,0 +1,27 @@
+-- Outbox: cada linha em whats_webhook é uma mensagem a ser enviada pelo device "number" para "recipient".
+-- O INSERT precisa preencher recipient: o payload do NOTIFY (FUN_notify_whatsapp_webhook) não o
+-- inclui e só serve para acordar o master, que lê a linha; sem destinatário ela falha na hora.
+ALTER TABLE whats_webhook
+    ADD COLUMN recipient VARCHAR(20),
+    ADD COLUMN status VARCHAR(20),
+    ADD COLUMN attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN error TEXT,
+    ADD COLUMN message_id TEXT,
+    ADD COLUMN locked_at TIMESTAMP,
+    ADD COLUMN next_attempt_at TIMESTAMP DEFAULT NOW(),
+    ADD COLUMN processed_at TIMESTAMP;
+
+-- Linhas anteriores à outbox não têm destinatário e não devem ser enviadas (nem gerar callbacks de falha)
+UPDATE whats_webhook SET status = 'legacy', processed_at = created_at;
+
+ALTER TABLE whats_webhook
+    ALTER COLUMN status SET DEFAULT 'pending',
+    ALTER COLUMN status SET NOT NULL;
+
+-- O resultado do envio é avisado em callback_url quando informado
+ALTER TABLE whats_webhook ALTER COLUMN callback_url DROP NOT NULL;
+
+CREATE INDEX "IDX_whats_webhook_pending" ON whats_webhook (next_attempt_at)
+    WHERE status IN ('pending', 'failed');
+
+CREATE INDEX "IDX_whats_webhook_number_status" ON whats_webhook (number, status);
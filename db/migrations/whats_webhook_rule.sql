@@ -0,0 +1,12 @@
+-- Regras de webhook por frase gerenciadas pelo master (/webhooks) e enviadas para o child do device.
+CREATE TABLE whats_webhook_rule (
+    id SERIAL PRIMARY KEY,
+    device VARCHAR(20) NOT NULL,
+    number VARCHAR(20) NOT NULL,
+    phrase TEXT NOT NULL,
+    callback_url TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX "UQ_whats_webhook_rule" ON whats_webhook_rule (device, number, phrase, callback_url);
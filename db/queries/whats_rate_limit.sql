@@ -0,0 +1,14 @@
+-- name: UpsertRateLimit :one
+INSERT INTO whats_rate_limit (scope, subject, budget, per_minute, burst)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (scope, subject, budget)
+DO UPDATE SET per_minute = EXCLUDED.per_minute, burst = EXCLUDED.burst, updated_at = NOW()
+RETURNING id, scope, subject, budget, per_minute, burst, created_at, updated_at;
+
+-- name: ListRateLimits :many
+SELECT id, scope, subject, budget, per_minute, burst, created_at, updated_at
+FROM whats_rate_limit
+ORDER BY scope, subject, budget;
+
+-- name: DeleteRateLimit :execrows
+DELETE FROM whats_rate_limit WHERE id = $1;
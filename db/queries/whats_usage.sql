@@ -0,0 +1,38 @@
+-- name: AddUsage :exec
+INSERT INTO whats_usage_daily (day, tenant, device, type, count)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (day, tenant, device, type)
+DO UPDATE SET count = whats_usage_daily.count + EXCLUDED.count, updated_at = NOW();
+
+-- name: UsageTotals :many
+-- Uso do mês corrente ($1 = primeiro dia) e do dia ($2) por tenant e device.
+SELECT tenant, device,
+       COALESCE(SUM(count) FILTER (WHERE day = $2), 0)::BIGINT AS daily,
+       SUM(count)::BIGINT AS monthly
+FROM whats_usage_daily
+WHERE day >= $1
+GROUP BY tenant, device;
+
+-- name: ListUsage :many
+SELECT day, tenant, device, type, count
+FROM whats_usage_daily
+WHERE day BETWEEN $1 AND $2
+  AND ($3 = '' OR tenant = $3)
+  AND ($4 = '' OR device = $4)
+  AND ($5 = '' OR type = $5)
+ORDER BY day, tenant, device, type;
+
+-- name: UpsertQuota :one
+INSERT INTO whats_quota (scope, subject, daily, monthly)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (scope, subject)
+DO UPDATE SET daily = EXCLUDED.daily, monthly = EXCLUDED.monthly, updated_at = NOW()
+RETURNING id, scope, subject, daily, monthly, created_at, updated_at;
+
+-- name: ListQuotas :many
+SELECT id, scope, subject, daily, monthly, created_at, updated_at
+FROM whats_quota
+ORDER BY scope, subject;
+
+-- name: DeleteQuota :execrows
+DELETE FROM whats_quota WHERE id = $1;
@@ -1 +1,49 @@
 -- create whast webhook
+
+-- name: ClaimOutbox :many
+-- Reserva as próximas linhas a enviar (pendentes, falhas com retry vencido ou
+-- travadas em processing por um master que caiu).
+UPDATE whats_webhook
+SET status = 'processing', attempts = attempts + 1, locked_at = NOW()
+WHERE id IN (
+    SELECT id FROM whats_webhook
+    WHERE (status IN ('pending', 'failed') AND next_attempt_at <= NOW() AND attempts < $1)
+       OR (status = 'processing' AND locked_at < NOW() - INTERVAL '5 minutes')
+    ORDER BY id
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, number, COALESCE(recipient, ''), message, COALESCE(callback_url, ''), attempts;
+
+-- name: MarkOutboxSent :exec
+UPDATE whats_webhook
+SET status = 'sent', message_id = $2, error = NULL, locked_at = NULL, processed_at = NOW()
+WHERE id = $1;
+
+-- name: MarkOutboxFailed :exec
+-- next_attempt_at nulo encerra as tentativas.
+UPDATE whats_webhook
+SET status = 'failed', error = $2, locked_at = NULL, next_attempt_at = $3, processed_at = NOW()
+WHERE id = $1;
+
+-- name: RetryOutbox :execrows
+UPDATE whats_webhook
+SET status = 'pending', attempts = 0, error = NULL, next_attempt_at = NOW()
+WHERE id = $1 AND status = 'failed';
+
+-- name: GetOutbox :one
+SELECT id, number, COALESCE(recipient, ''), message, COALESCE(callback_url, ''), status, attempts,
+       COALESCE(error, ''), COALESCE(message_id, ''), created_at, next_attempt_at, processed_at
+FROM whats_webhook
+WHERE id = $1;
+
+-- name: CountOutboxByStatus :many
+SELECT status, COUNT(*) FROM whats_webhook GROUP BY status;
+
+-- name: ListOutbox :many
+SELECT id, number, COALESCE(recipient, ''), message, COALESCE(callback_url, ''), status, attempts,
+       COALESCE(error, ''), COALESCE(message_id, ''), created_at, next_attempt_at, processed_at
+FROM whats_webhook
+WHERE ($1 = '' OR status = $1) AND ($2 = '' OR number = $2)
+ORDER BY id DESC
+LIMIT $3;
@@ -0,0 +1,27 @@
+-- name: CreateWebhookRule :one
+INSERT INTO whats_webhook_rule (device, number, phrase, callback_url)
+VALUES ($1, $2, $3, $4)
+RETURNING id, device, number, phrase, callback_url, created_at, updated_at;
+
+-- name: UpdateWebhookRule :one
+UPDATE whats_webhook_rule
+SET device = $2, number = $3, phrase = $4, callback_url = $5, updated_at = NOW()
+WHERE id = $1
+RETURNING id, device, number, phrase, callback_url, created_at, updated_at;
+
+-- name: GetWebhookRule :one
+SELECT id, device, number, phrase, callback_url, created_at, updated_at
+FROM whats_webhook_rule
+WHERE id = $1;
+
+-- name: DeleteWebhookRule :one
+DELETE FROM whats_webhook_rule WHERE id = $1 RETURNING device;
+
+-- name: ListWebhookRules :many
+SELECT id, device, number, phrase, callback_url, created_at, updated_at
+FROM whats_webhook_rule
+WHERE ($1 = '' OR device = $1)
+ORDER BY device, id;
+
+-- name: ListWebhookRuleDevices :many
+SELECT DISTINCT device FROM whats_webhook_rule ORDER BY device;
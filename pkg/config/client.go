@@ -0,0 +1,95 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Client é a configuração de um child (um device por processo).
+type Client struct {
+	ListenAddr       string         `yaml:"listen_addr" json:"listen_addr" env:"LISTEN_ADDR"`
+	PhoneNumber      string         `yaml:"phone_number" json:"phone_number" env:"PHONE_NUMBER"`
+	MasterURL        string         `yaml:"master_url" json:"master_url,omitempty" env:"MASTER_URL"`
+	MasterToken      string         `yaml:"master_token" json:"master_token,omitempty" env:"MASTER_TOKEN" secret:"true"` // dado pelo master ao criar o container
+	DataDir          string         `yaml:"data_dir" json:"data_dir" env:"DATA_DIR"`                                     // sessão, histórico, respostas automáticas e spool
+	EventWebhookURL  string         `yaml:"event_webhook_url" json:"event_webhook_url,omitempty" env:"EVENT_WEBHOOK_URL" secret:"url"`
+	StatusWebhookURL string         `yaml:"status_webhook_url" json:"status_webhook_url,omitempty" env:"STATUS_WEBHOOK_URL" secret:"url"`
+	EventSinks       string         `yaml:"event_sinks" json:"event_sinks,omitempty" env:"EVENT_SINKS" secret:"url"` // URLs separadas por vírgula
+	QRSize           int            `yaml:"qr_size" json:"qr_size" env:"QR_SIZE"`                                    // lado do PNG do QR Code, em pixels
+	MediaMaxMB       int            `yaml:"media_max_mb" json:"media_max_mb" env:"MEDIA_MAX_MB"`                     // maior arquivo aceito em media_url
+	ShutdownTimeout  Duration       `yaml:"shutdown_timeout" json:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`         // prazo para esvaziar envios e sinks; menor que docker.stop_timeout do master
+	Features         ClientFeatures `yaml:"features" json:"features"`
+}
+
+// ClientFeatures liga e desliga partes opcionais do child.
+type ClientFeatures struct {
+	AutoConnect   bool `yaml:"auto_connect" json:"auto_connect" env:"AUTO_CONNECT"`              // reconecta a sessão salva ao subir
+	AutoResponder bool `yaml:"auto_responder" json:"auto_responder" env:"FEATURE_AUTORESPONDER"` // respostas automáticas e menus
+	Metrics       bool `yaml:"metrics" json:"metrics" env:"FEATURE_METRICS"`                     // /metrics
+}
+
+// DefaultClient retorna a configuração usada quando nada é informado.
+func DefaultClient() Client {
+	return Client{
+		ListenAddr:      ":8080",
+		DataDir:         ".",
+		QRSize:          180,
+		MediaMaxMB:      16,
+		ShutdownTimeout: Duration(10 * time.Second),
+		Features:        ClientFeatures{AutoConnect: true, AutoResponder: true, Metrics: true},
+	}
+}
+
+// LoadClient carrega e valida a configuração do child.
+func LoadClient() (Client, error) {
+	cfg := DefaultClient()
+	if err := load(&cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// Validate confere os valores antes de o child subir.
+func (c Client) Validate() error {
+	var errs []error
+	if err := validateAddr("listen_addr", c.ListenAddr); err != nil {
+		errs = append(errs, err)
+	}
+	for name, raw := range map[string]string{
+		"master_url":         c.MasterURL,
+		"event_webhook_url":  c.EventWebhookURL,
+		"status_webhook_url": c.StatusWebhookURL,
+	} {
+		if err := validateURL(name, raw); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if strings.TrimSpace(c.DataDir) == "" {
+		errs = append(errs, errors.New("data_dir não pode ser vazio"))
+	}
+	if c.QRSize < 64 || c.QRSize > 2048 {
+		errs = append(errs, fmt.Errorf("qr_size deve ficar entre 64 e 2048: %d", c.QRSize))
+	}
+	if c.MediaMaxMB <= 0 {
+		errs = append(errs, errors.New("media_max_mb deve ser maior que zero"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout deve ser maior que zero"))
+	}
+	return errors.Join(errs...)
+}
+
+// Path retorna o caminho de um arquivo dentro de DataDir.
+func (c Client) Path(name string) string {
+	return filepath.Join(c.DataDir, name)
+}
+
+// Redacted retorna uma cópia sem senhas e tokens, para exibir em /config.
+func (c Client) Redacted() Client {
+	redact(reflect.ValueOf(&c).Elem())
+	return c
+}
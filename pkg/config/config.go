@@ -0,0 +1,276 @@
+// Package config carrega a configuração tipada do master e dos childs.
+//
+// A ordem de precedência é: valores padrão, arquivo YAML indicado em CONFIG_FILE
+// (opcional) e, por último, as variáveis de ambiente. Cada campo declara sua
+// variável na tag env; campos com a tag secret são ocultados em Redacted.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv é a variável com o caminho do arquivo YAML de configuração.
+const FileEnv = "CONFIG_FILE"
+
+const redactedValue = "***"
+
+// Duration aceita "15s", "2m" etc. no YAML e nas variáveis, e aparece assim em /config.
+type Duration time.Duration
+
+// Std converte para time.Duration.
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(strings.TrimSpace(string(b)))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Limit é um token bucket escrito como "30/m:10": 30 requisições por minuto (também
+// /s e /h) com rajada de 10. Sem a rajada ela é igual à quantidade; "0" ou vazio não limita.
+type Limit struct {
+	PerMinute float64
+	Burst     int
+}
+
+// Unlimited informa se o limite está desligado.
+func (l Limit) Unlimited() bool {
+	return l.PerMinute <= 0
+}
+
+func (l Limit) MarshalText() ([]byte, error) {
+	if l.Unlimited() {
+		return []byte("0"), nil
+	}
+	count, unit := l.PerMinute, "/m"
+	if count != math.Trunc(count) && count*60 == math.Trunc(count*60) {
+		count, unit = count*60, "/h" // 100/h e não 1.6666666666666667/m
+	}
+	return []byte(strconv.FormatFloat(count, 'f', -1, 64) + unit + ":" + strconv.Itoa(l.Burst)), nil
+}
+
+func (l *Limit) UnmarshalText(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	if s == "" || s == "0" {
+		*l = Limit{}
+		return nil
+	}
+
+	spec, burst, hasBurst := strings.Cut(s, ":")
+	count, unit, ok := strings.Cut(spec, "/")
+	if !ok {
+		return fmt.Errorf("limite inválido %q, use por exemplo 30/m:10", s)
+	}
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("limite inválido %q: quantidade deve ser maior que zero", s)
+	}
+
+	var perMinute float64
+	switch unit {
+	case "s":
+		perMinute = n * 60
+	case "m":
+		perMinute = n
+	case "h":
+		perMinute = n / 60
+	default:
+		return fmt.Errorf("limite inválido %q: unidade deve ser s, m ou h", s)
+	}
+
+	size := int(math.Ceil(n))
+	if hasBurst {
+		if size, err = strconv.Atoi(burst); err != nil || size <= 0 {
+			return fmt.Errorf("limite inválido %q: rajada deve ser maior que zero", s)
+		}
+	}
+	*l = Limit{PerMinute: perMinute, Burst: size}
+	return nil
+}
+
+// load preenche cfg (já com os padrões) com o arquivo de CONFIG_FILE e o ambiente.
+func load(cfg any) error {
+	if path := os.Getenv(FileEnv); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("erro ao abrir %s: %w", path, err)
+		}
+		defer f.Close()
+
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true) // chave desconhecida costuma ser erro de digitação
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("erro ao ler %s: %w", path, err)
+		}
+	}
+	return applyEnv(reflect.ValueOf(cfg).Elem())
+}
+
+var textUnmarshalerType = reflect.TypeFor[interface{ UnmarshalText([]byte) error }]()
+
+// applyEnv sobrescreve os campos que têm tag env com as variáveis definidas.
+func applyEnv(v reflect.Value) error {
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		key := field.Tag.Get("env")
+
+		if key == "" {
+			if value.Kind() == reflect.Struct {
+				if err := applyEnv(value); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			continue
+		}
+
+		// Variável vazia (DB_HOST= no .env) vale como não definida
+		raw := strings.TrimSpace(os.Getenv(key))
+		if raw == "" {
+			continue
+		}
+		if err := setValue(value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(interface{ UnmarshalText([]byte) error }).UnmarshalText([]byte(raw))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("tipo %s não suportado", v.Type())
+		}
+		// Listas vêm separadas por vírgula (ex: DEVICE_KNOWN_NUMBERS=5511...,5521...)
+		var items []string
+		for item := range strings.SplitSeq(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("tipo %s não suportado", v.Kind())
+	}
+	return nil
+}
+
+// redact oculta os campos com tag secret: "true" troca o valor inteiro, "url" só a
+// senha e os parâmetros da URL (listas separadas por vírgula também são aceitas).
+func redact(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		switch {
+		case value.Kind() == reflect.Struct:
+			redact(value)
+		case value.Kind() != reflect.String || value.String() == "":
+		case field.Tag.Get("secret") == "true":
+			value.SetString(redactedValue)
+		case field.Tag.Get("secret") == "url":
+			value.SetString(redactURLs(value.String()))
+		}
+	}
+}
+
+func redactURLs(s string) string {
+	parts := strings.Split(s, ",")
+	for i, part := range parts {
+		u, err := url.Parse(strings.TrimSpace(part))
+		if err != nil || u.Host == "" {
+			continue
+		}
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+		if u.RawQuery != "" {
+			// Tokens costumam ir na query (?token=...); só as chaves ficam visíveis
+			keys := slices.Sorted(maps.Keys(u.Query()))
+			for j, key := range keys {
+				keys[j] = url.QueryEscape(key) + "=" + redactedValue
+			}
+			u.RawQuery = strings.Join(keys, "&")
+		}
+		// url.String escapa os asteriscos da senha
+		parts[i] = strings.ReplaceAll(u.String(), url.PathEscape(redactedValue), redactedValue)
+	}
+	return strings.Join(parts, ",")
+}
+
+func validateAddr(name, addr string) error {
+	if port(addr) == 0 {
+		return fmt.Errorf("%s inválido: %q (use :8080 ou host:8080)", name, addr)
+	}
+	return nil
+}
+
+// port extrai a porta de um endereço host:porta (0 se inválido).
+func port(addr string) int {
+	_, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(p)
+	if err != nil || n <= 0 || n > 65535 {
+		return 0
+	}
+	return n
+}
+
+func validateURL(name, raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%s inválida: %q", name, raw)
+	}
+	return nil
+}
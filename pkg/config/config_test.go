@@ -0,0 +1,57 @@
+package config
+
+import "testing"
+
+func TestLimitUnmarshalText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Limit
+	}{
+		{"0", Limit{}},
+		{"", Limit{}},
+		{" 30/m:10 ", Limit{PerMinute: 30, Burst: 10}},
+		{"2/s:5", Limit{PerMinute: 120, Burst: 5}},
+		{"120/h:3", Limit{PerMinute: 2, Burst: 3}},
+		{"30/m", Limit{PerMinute: 30, Burst: 30}},  // sem rajada: a própria quantidade
+		{"1.5/m", Limit{PerMinute: 1.5, Burst: 2}}, // rajada arredondada para cima
+		{"100/h", Limit{PerMinute: 100.0 / 60, Burst: 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var got Limit
+			if err := got.UnmarshalText([]byte(tt.in)); err != nil {
+				t.Fatalf("erro inesperado: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("= %+v, quer %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitUnmarshalTextInvalid(t *testing.T) {
+	for _, in := range []string{"30", "30/d:10", "abc/m", "-1/m", "0/m:5", "30/m:0", "30/m:-2", "30/m:x"} {
+		t.Run(in, func(t *testing.T) {
+			var got Limit
+			if err := got.UnmarshalText([]byte(in)); err == nil {
+				t.Errorf("aceitou %q como %+v", in, got)
+			}
+		})
+	}
+}
+
+func TestLimitMarshalTextRoundTrip(t *testing.T) {
+	for _, in := range []string{"0", "30/m:10", "100/h:100", "120/m:5"} {
+		var l Limit
+		if err := l.UnmarshalText([]byte(in)); err != nil {
+			t.Fatalf("%s: %v", in, err)
+		}
+		out, err := l.MarshalText()
+		if err != nil {
+			t.Fatalf("%s: %v", in, err)
+		}
+		if string(out) != in {
+			t.Errorf("%s virou %s", in, out)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/phone"
+)
+
+// Master é a configuração do master (API, Docker e outbox).
+type Master struct {
+	ListenAddr      string         `yaml:"listen_addr" json:"listen_addr" env:"LISTEN_ADDR"`
+	MasterURL       string         `yaml:"master_url" json:"master_url" env:"MASTER_URL"`                                  // como os childs alcançam o master; vazio usa a bridge do Docker
+	DeviceSecret    string         `yaml:"device_secret" json:"device_secret,omitempty" env:"DEVICE_SECRET" secret:"true"` // assina o token de cada child (MASTER_TOKEN)
+	Database        Database       `yaml:"database" json:"database"`
+	Docker          Docker         `yaml:"docker" json:"docker"`
+	Proxy           Proxy          `yaml:"proxy" json:"proxy"`
+	Idle            Idle           `yaml:"idle" json:"idle"`
+	Devices         Devices        `yaml:"devices" json:"devices"`
+	RateLimit       RateLimit      `yaml:"rate_limit" json:"rate_limit"`
+	Quota           Quota          `yaml:"quota" json:"quota"`
+	Outbox          Outbox         `yaml:"outbox" json:"outbox"`
+	Features        MasterFeatures `yaml:"features" json:"features"`
+	ShutdownTimeout Duration       `yaml:"shutdown_timeout" json:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"` // prazo para as requisições e proxies em andamento no SIGTERM
+}
+
+// Database é a conexão com o Postgres: DATABASE_URL ou as variáveis DB_* do makefile.
+// Sem nenhuma das duas o master roda sem banco (outbox e regras de webhook desligadas).
+type Database struct {
+	URL      string `yaml:"url" json:"url,omitempty" env:"DATABASE_URL" secret:"url"`
+	Host     string `yaml:"host" json:"host,omitempty" env:"DB_HOST"`
+	Port     int    `yaml:"port" json:"port" env:"DB_PORT"`
+	User     string `yaml:"user" json:"user,omitempty" env:"DB_USER"`
+	Password string `yaml:"password" json:"password,omitempty" env:"DB_PASSWORD" secret:"true"`
+	Name     string `yaml:"name" json:"name,omitempty" env:"DB_DATABASE"`
+	SSLMode  string `yaml:"sslmode" json:"sslmode" env:"DB_SSLMODE"`
+}
+
+// Docker controla os containers dos childs.
+type Docker struct {
+	Image         string   `yaml:"image" json:"image" env:"CHILD_IMAGE"`
+	Tag           string   `yaml:"tag" json:"tag" env:"CHILD_IMAGE_TAG"`
+	InternalPort  int      `yaml:"internal_port" json:"internal_port" env:"CHILD_PORT"`            // porta do child dentro do container
+	NetworkMode   string   `yaml:"network_mode" json:"network_mode,omitempty" env:"CHILD_NETWORK"` // bridge (padrão) ou o nome de uma rede
+	BridgeHost    string   `yaml:"bridge_host" json:"bridge_host,omitempty" env:"DOCKER_BRIDGE_HOST"`
+	HealthTimeout Duration `yaml:"health_timeout" json:"health_timeout" env:"CHILD_HEALTH_TIMEOUT"`
+	StopTimeout   Duration `yaml:"stop_timeout" json:"stop_timeout" env:"CHILD_STOP_TIMEOUT"` // tempo do child para esvaziar envios antes do SIGKILL
+}
+
+// Proxy ajusta as conexões do proxy /device/{number}/... com os childs.
+type Proxy struct {
+	MaxIdleConnsPerHost   int      `yaml:"max_idle_conns_per_host" json:"max_idle_conns_per_host" env:"PROXY_MAX_IDLE_CONNS"` // conexões keep-alive mantidas por child
+	IdleConnTimeout       Duration `yaml:"idle_conn_timeout" json:"idle_conn_timeout" env:"PROXY_IDLE_CONN_TIMEOUT"`
+	DialTimeout           Duration `yaml:"dial_timeout" json:"dial_timeout" env:"PROXY_DIAL_TIMEOUT"`
+	ResponseHeaderTimeout Duration `yaml:"response_header_timeout" json:"response_header_timeout" env:"PROXY_RESPONSE_HEADER_TIMEOUT"` // inclui o download de mídia nos envios
+}
+
+// Idle controla o scale-to-zero: o container de um device sem uso é parado e volta
+// a subir na próxima requisição. A sessão fica no volume do device.
+type Idle struct {
+	Timeout       Duration `yaml:"timeout" json:"timeout" env:"IDLE_TIMEOUT"` // 0 desliga; cada device pode sobrescrever em /create
+	CheckInterval Duration `yaml:"check_interval" json:"check_interval" env:"IDLE_CHECK_INTERVAL"`
+	WakeTimeout   Duration `yaml:"wake_timeout" json:"wake_timeout" env:"IDLE_WAKE_TIMEOUT"` // quanto a requisição espera o child conectar
+}
+
+// Políticas de criação automática de devices pelo proxy /device/{number}/...
+const (
+	AutoCreateOff   = "off"   // só devices já existentes; os outros recebem 404
+	AutoCreateKnown = "known" // também cria os números de devices.known
+	AutoCreateOn    = "on"    // cria um container para qualquer número válido
+)
+
+// Devices controla quais devices podem ser criados e quantos.
+type Devices struct {
+	AutoCreate   string   `yaml:"auto_create" json:"auto_create" env:"DEVICE_AUTO_CREATE"`
+	Known        []string `yaml:"known" json:"known,omitempty" env:"DEVICE_KNOWN_NUMBERS"`          // números pré-cadastrados para auto_create=known
+	MaxPerTenant int      `yaml:"max_per_tenant" json:"max_per_tenant" env:"DEVICE_MAX_PER_TENANT"` // 0 sem limite; devices sem tenant contam juntos
+}
+
+// RateLimit são os limites padrão do proxy /device/{number}/..., em token bucket. As
+// regras da tabela whats_rate_limit sobrescrevem estes valores; sem banco, só eles valem.
+type RateLimit struct {
+	Enabled        bool     `yaml:"enabled" json:"enabled" env:"RATE_LIMIT_ENABLED"`
+	ReloadInterval Duration `yaml:"reload_interval" json:"reload_interval" env:"RATE_LIMIT_RELOAD_INTERVAL"` // releitura das regras do banco
+	DeviceSend     Limit    `yaml:"device_send" json:"device_send" env:"RATE_LIMIT_DEVICE_SEND"`             // /send e /send/* de cada device
+	DeviceRead     Limit    `yaml:"device_read" json:"device_read" env:"RATE_LIMIT_DEVICE_READ"`             // demais rotas de cada device
+	ClientSend     Limit    `yaml:"client_send" json:"client_send" env:"RATE_LIMIT_CLIENT_SEND"`             // por tenant do device (API keys só têm as regras gravadas)
+	ClientRead     Limit    `yaml:"client_read" json:"client_read" env:"RATE_LIMIT_CLIENT_READ"`
+}
+
+// Quota são as cotas padrão de mensagens enviadas (0 sem cota). As regras da tabela
+// whats_quota sobrescrevem estes valores por tenant ou device.
+type Quota struct {
+	Enabled       bool     `yaml:"enabled" json:"enabled" env:"QUOTA_ENABLED"`
+	TenantDaily   int64    `yaml:"tenant_daily" json:"tenant_daily" env:"QUOTA_TENANT_DAILY"`
+	TenantMonthly int64    `yaml:"tenant_monthly" json:"tenant_monthly" env:"QUOTA_TENANT_MONTHLY"`
+	DeviceDaily   int64    `yaml:"device_daily" json:"device_daily" env:"QUOTA_DEVICE_DAILY"`
+	DeviceMonthly int64    `yaml:"device_monthly" json:"device_monthly" env:"QUOTA_DEVICE_MONTHLY"`
+	Timezone      string   `yaml:"timezone" json:"timezone" env:"QUOTA_TIMEZONE"`                   // fuso em que o dia e o mês viram
+	FlushInterval Duration `yaml:"flush_interval" json:"flush_interval" env:"USAGE_FLUSH_INTERVAL"` // gravação do uso em whats_usage_daily
+}
+
+// Location é o fuso de Timezone (já validado).
+func (q Quota) Location() *time.Location {
+	loc, err := time.LoadLocation(q.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Outbox controla o envio das linhas de whats_webhook.
+type Outbox struct {
+	Enabled        bool     `yaml:"enabled" json:"enabled" env:"OUTBOX_ENABLED"`
+	BatchSize      int      `yaml:"batch_size" json:"batch_size" env:"OUTBOX_BATCH_SIZE"`
+	MaxAttempts    int      `yaml:"max_attempts" json:"max_attempts" env:"OUTBOX_MAX_ATTEMPTS"`
+	PollInterval   Duration `yaml:"poll_interval" json:"poll_interval" env:"OUTBOX_POLL_INTERVAL"`
+	RequestTimeout Duration `yaml:"request_timeout" json:"request_timeout" env:"OUTBOX_REQUEST_TIMEOUT"`
+}
+
+// MasterFeatures liga e desliga partes opcionais do master.
+type MasterFeatures struct {
+	Dashboard   bool `yaml:"dashboard" json:"dashboard" env:"FEATURE_DASHBOARD"`          // /dash
+	Metrics     bool `yaml:"metrics" json:"metrics" env:"FEATURE_METRICS"`                // /metrics
+	WebhookSync bool `yaml:"webhook_sync" json:"webhook_sync" env:"FEATURE_WEBHOOK_SYNC"` // reenvio das regras quando o child (re)conecta
+}
+
+// DefaultMaster retorna a configuração usada quando nada é informado.
+func DefaultMaster() Master {
+	return Master{
+		ListenAddr: ":8080",
+		Database:   Database{Port: 5432, SSLMode: "disable"},
+		Docker: Docker{
+			Image:         "zap-client",
+			Tag:           "latest",
+			InternalPort:  8080,
+			HealthTimeout: Duration(15 * time.Second),
+			StopTimeout:   Duration(15 * time.Second),
+		},
+		Proxy: Proxy{
+			MaxIdleConnsPerHost:   32,
+			IdleConnTimeout:       Duration(90 * time.Second),
+			DialTimeout:           Duration(5 * time.Second),
+			ResponseHeaderTimeout: Duration(60 * time.Second),
+		},
+		Idle: Idle{
+			CheckInterval: Duration(time.Minute),
+			WakeTimeout:   Duration(60 * time.Second),
+		},
+		Devices: Devices{AutoCreate: AutoCreateKnown},
+		RateLimit: RateLimit{
+			Enabled:        true,
+			ReloadInterval: Duration(time.Minute),
+			DeviceSend:     Limit{PerMinute: 30, Burst: 10},
+			DeviceRead:     Limit{PerMinute: 600, Burst: 60},
+		},
+		Quota: Quota{
+			Enabled:       true,
+			Timezone:      "UTC",
+			FlushInterval: Duration(30 * time.Second),
+		},
+		Outbox: Outbox{
+			Enabled:        true,
+			BatchSize:      50,
+			MaxAttempts:    5,
+			PollInterval:   Duration(30 * time.Second),
+			RequestTimeout: Duration(60 * time.Second),
+		},
+		Features:        MasterFeatures{Dashboard: true, Metrics: true, WebhookSync: true},
+		ShutdownTimeout: Duration(30 * time.Second),
+	}
+}
+
+// LoadMaster carrega e valida a configuração do master.
+func LoadMaster() (Master, error) {
+	cfg := DefaultMaster()
+	if err := load(&cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// Validate confere os valores antes de o master subir.
+func (c Master) Validate() error {
+	var errs []error
+	if err := validateAddr("listen_addr", c.ListenAddr); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateURL("master_url", c.MasterURL); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateURL("database.url", c.Database.URL); err != nil {
+		errs = append(errs, err)
+	}
+	if len(c.DeviceSecret) < 16 {
+		// Um segredo gerado a cada subida invalidaria o MASTER_TOKEN dos containers já criados
+		errs = append(errs, errors.New("device_secret (DEVICE_SECRET) é obrigatório, com pelo menos 16 caracteres"))
+	}
+	if c.Docker.Image == "" {
+		errs = append(errs, errors.New("docker.image não pode ser vazio"))
+	}
+	if c.Docker.InternalPort <= 0 || c.Docker.InternalPort > 65535 {
+		errs = append(errs, fmt.Errorf("docker.internal_port inválido: %d", c.Docker.InternalPort))
+	}
+	switch c.Docker.NetworkMode {
+	case "host", "none":
+		// O master alcança os childs pela porta publicada, que não existe nesses modos
+		errs = append(errs, fmt.Errorf("docker.network_mode %q não é suportado", c.Docker.NetworkMode))
+	}
+	if c.Docker.HealthTimeout <= 0 {
+		errs = append(errs, errors.New("docker.health_timeout deve ser maior que zero"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout deve ser maior que zero"))
+	}
+	if c.Proxy.MaxIdleConnsPerHost <= 0 {
+		errs = append(errs, errors.New("proxy.max_idle_conns_per_host deve ser maior que zero"))
+	}
+	if c.Proxy.IdleConnTimeout <= 0 || c.Proxy.DialTimeout <= 0 || c.Proxy.ResponseHeaderTimeout <= 0 {
+		errs = append(errs, errors.New("os timeouts de proxy devem ser maiores que zero"))
+	}
+	if c.Idle.Timeout < 0 {
+		errs = append(errs, errors.New("idle.timeout não pode ser negativo"))
+	}
+	if c.Idle.CheckInterval <= 0 || c.Idle.WakeTimeout <= 0 {
+		errs = append(errs, errors.New("idle.check_interval e idle.wake_timeout devem ser maiores que zero"))
+	}
+	switch c.Devices.AutoCreate {
+	case AutoCreateOff, AutoCreateKnown, AutoCreateOn:
+	default:
+		errs = append(errs, fmt.Errorf("devices.auto_create inválido: %q (use off, known ou on)", c.Devices.AutoCreate))
+	}
+	for _, number := range c.Devices.Known {
+		if _, err := phone.Normalize(number); err != nil {
+			errs = append(errs, fmt.Errorf("devices.known: %w", err))
+		}
+	}
+	if c.Devices.MaxPerTenant < 0 {
+		errs = append(errs, errors.New("devices.max_per_tenant não pode ser negativo"))
+	}
+	if c.RateLimit.ReloadInterval <= 0 {
+		errs = append(errs, errors.New("rate_limit.reload_interval deve ser maior que zero"))
+	}
+	if c.Quota.TenantDaily < 0 || c.Quota.TenantMonthly < 0 || c.Quota.DeviceDaily < 0 || c.Quota.DeviceMonthly < 0 {
+		errs = append(errs, errors.New("as cotas não podem ser negativas (0 sem cota)"))
+	}
+	if _, err := time.LoadLocation(c.Quota.Timezone); err != nil {
+		errs = append(errs, fmt.Errorf("quota.timezone inválido: %w", err))
+	}
+	if c.Quota.FlushInterval <= 0 {
+		errs = append(errs, errors.New("quota.flush_interval deve ser maior que zero"))
+	}
+	if c.Outbox.BatchSize <= 0 {
+		errs = append(errs, errors.New("outbox.batch_size deve ser maior que zero"))
+	}
+	if c.Outbox.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("outbox.max_attempts deve ser maior que zero"))
+	}
+	if c.Outbox.PollInterval <= 0 || c.Outbox.RequestTimeout <= 0 {
+		errs = append(errs, errors.New("outbox.poll_interval e outbox.request_timeout devem ser maiores que zero"))
+	}
+	return errors.Join(errs...)
+}
+
+// Port é a porta em que o master escuta.
+func (c Master) Port() int {
+	return port(c.ListenAddr)
+}
+
+// Redacted retorna uma cópia sem senhas e tokens, para exibir em /config.
+func (c Master) Redacted() Master {
+	redact(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+// DSN monta a conexão com o Postgres; vazio quando o banco não foi configurado.
+func (d Database) DSN() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	if d.Host == "" {
+		return ""
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     fmt.Sprintf("%s:%d", d.Host, d.Port),
+		Path:     d.Name,
+		RawQuery: "sslmode=" + d.SSLMode,
+	}
+	return u.String()
+}
+
+// ImageRef é a imagem com a tag (image:tag); uma tag ou digest já presente em image é mantida.
+func (d Docker) ImageRef() string {
+	name := d.Image[strings.LastIndex(d.Image, "/")+1:]
+	if strings.ContainsAny(name, ":@") || d.Tag == "" {
+		return d.Image
+	}
+	return d.Image + ":" + d.Tag
+}
@@ -0,0 +1,192 @@
+package eventhub
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// keepAlive é o intervalo dos pings que mantêm o stream vivo atrás de proxies.
+const keepAlive = 25 * time.Second
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// ParseRequest lê os filtros (?types=, ?device=, ?tenant=, separados por vírgula) e o
+// último ID recebido (?last_event_id= ou o header Last-Event-ID, enviado pelo EventSource).
+func ParseRequest(r *http.Request) (Filter, uint64) {
+	q := r.URL.Query()
+	filter := Filter{
+		Types:   splitList(q.Get("types")),
+		Devices: splitList(q.Get("device")),
+		Tenants: splitList(q.Get("tenant")),
+	}
+
+	lastID := q.Get("last_event_id")
+	if lastID == "" {
+		lastID = r.Header.Get("Last-Event-ID")
+	}
+	after, _ := strconv.ParseUint(lastID, 10, 64)
+	return filter, after
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// Serve atende um cliente do stream via WebSocket (mensagens JSON) ou SSE, conforme a requisição.
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, filter Filter, after uint64) {
+	if websocket.IsWebSocketUpgrade(r) {
+		h.serveWS(w, r, filter, after)
+	} else {
+		h.serveSSE(w, r, filter, after)
+	}
+}
+
+// subscribe assina o hub e devolve os eventos a reenviar antes dos novos.
+func (h *Hub) subscribe(filter Filter, after uint64) (*Subscription, []Event) {
+	sub, backlog, complete := h.Subscribe(filter, after)
+	if !complete {
+		// Parte dos eventos já saiu do histórico: avisa para o cliente ressincronizar pelas rotas REST
+		lost := Event{
+			Type:      "events_lost",
+			Timestamp: time.Now(),
+			Data:      map[string]uint64{"last_event_id": after},
+		}
+		backlog = append([]Event{lost}, backlog...)
+	}
+	return sub, backlog
+}
+
+// serveWS envia cada evento como uma mensagem JSON no WebSocket
+func (h *Hub) serveWS(w http.ResponseWriter, r *http.Request, filter Filter, after uint64) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("❌ Erro ao iniciar WebSocket de eventos", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	sub, backlog := h.subscribe(filter, after)
+	defer sub.Close()
+
+	// O cliente não envia nada; a leitura só serve para detectar o fechamento
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, evt := range backlog {
+		if err := conn.WriteJSON(evt); err != nil {
+			return
+		}
+	}
+
+	ping := time.NewTicker(keepAlive)
+	defer ping.Stop()
+
+	for {
+		select {
+		case evt, ok := <-sub.C:
+			if !ok {
+				msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "cliente lento, reconecte com last_event_id")
+				if h.Closed() {
+					msg = websocket.FormatCloseMessage(websocket.CloseServiceRestart, "servidor reiniciando, reconecte com last_event_id")
+				}
+				conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+				return
+			}
+			if err := conn.WriteJSON(evt); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// serveSSE envia os eventos no formato text/event-stream (id, event, data)
+func (h *Hub) serveSSE(w http.ResponseWriter, r *http.Request, filter Filter, after uint64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming não suportado", http.StatusInternalServerError)
+		return
+	}
+
+	sub, backlog := h.subscribe(filter, after)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for _, evt := range backlog {
+		if err := writeSSE(w, evt); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ping := time.NewTicker(keepAlive)
+	defer ping.Stop()
+
+	for {
+		select {
+		case evt, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := writeSSE(w, evt); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeSSE escreve um evento SSE; eventos sem ID (avisos) não alteram o Last-Event-ID do cliente
+func writeSSE(w http.ResponseWriter, evt Event) error {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	if evt.ID > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", evt.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Type, payload)
+	return err
+}
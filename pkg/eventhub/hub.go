@@ -0,0 +1,187 @@
+package eventhub
+
+import (
+	"sync"
+	"time"
+)
+
+// Event é um evento de device entregue aos clientes de /events. O ID é sequencial
+// por hub e serve para retomar o stream (Last-Event-ID); a numeração começa no instante
+// em que o processo subiu (em microssegundos), então continua crescendo após um restart.
+type Event struct {
+	ID        uint64    `json:"id"`
+	Type      string    `json:"type"`
+	Device    string    `json:"device"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// Filter seleciona os eventos de um assinante; listas vazias aceitam qualquer valor.
+type Filter struct {
+	Types   []string
+	Devices []string
+	Tenants []string
+}
+
+// Hub distribui eventos para os assinantes e guarda os últimos para quem reconecta.
+type Hub struct {
+	mu      sync.Mutex
+	nextID  uint64
+	ring    []Event // buffer circular; ring[(start+i)%len] é o i-ésimo mais antigo
+	start   int
+	size    int
+	subs    map[*Subscription]struct{}
+	subSize int
+	closed  bool // Shutdown chamado: não aceita novos assinantes
+}
+
+// Subscription é um assinante do Hub. C é fechado quando o assinante é removido,
+// inclusive por não acompanhar o ritmo dos eventos.
+type Subscription struct {
+	C       chan Event
+	types   map[string]bool
+	devices map[string]bool
+	tenants map[string]bool
+	hub     *Hub
+}
+
+// New cria um hub que guarda até history eventos, com fila de subBuffer eventos por assinante.
+func New(history, subBuffer int) *Hub {
+	return &Hub{
+		nextID:  uint64(time.Now().UnixMicro()),
+		ring:    make([]Event, history),
+		subs:    make(map[*Subscription]struct{}),
+		subSize: subBuffer,
+	}
+}
+
+// Publish numera o evento, guarda no histórico e entrega aos assinantes interessados.
+func (h *Hub) Publish(evt Event) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	evt.ID = h.nextID
+
+	h.ring[(h.start+h.size)%len(h.ring)] = evt
+	if h.size < len(h.ring) {
+		h.size++
+	} else {
+		h.start = (h.start + 1) % len(h.ring)
+	}
+
+	for sub := range h.subs {
+		if !sub.wants(evt) {
+			continue
+		}
+		select {
+		case sub.C <- evt:
+		default:
+			// Assinante lento: desconecta para não travar os demais; ele retoma pelo último ID
+			h.remove(sub)
+		}
+	}
+	return evt
+}
+
+// Subscribe registra um assinante. Com after > 0, devolve também os eventos do
+// histórico com ID maior que after; complete é false quando parte deles já saiu do histórico
+// ou quando after não pertence a este hub (maior que o último ID publicado).
+func (h *Hub) Subscribe(filter Filter, after uint64) (sub *Subscription, backlog []Event, complete bool) {
+	sub = &Subscription{
+		C:       make(chan Event, h.subSize),
+		types:   toSet(filter.Types),
+		devices: toSet(filter.Devices),
+		tenants: toSet(filter.Tenants),
+		hub:     h,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(sub.C)
+		return sub, nil, true
+	}
+
+	complete = true
+	if after > 0 {
+		oldest := h.nextID + 1
+		if h.size > 0 {
+			oldest = h.ring[h.start].ID
+		}
+		switch {
+		case after > h.nextID:
+			// ID de outro processo (relógio voltou ou numeração antiga): reenvia todo o histórico
+			complete = false
+			after = 0
+		case after+1 < oldest:
+			// Parte do intervalo saiu do histórico ou é anterior ao boot deste processo
+			complete = false
+		}
+		for i := 0; i < h.size; i++ {
+			evt := h.ring[(h.start+i)%len(h.ring)]
+			if evt.ID > after && sub.wants(evt) {
+				backlog = append(backlog, evt)
+			}
+		}
+	}
+
+	h.subs[sub] = struct{}{}
+	return sub, backlog, complete
+}
+
+// Shutdown desliga todos os assinantes (os clientes recebem o aviso de reinício) e
+// recusa os novos. Usado no encerramento do processo.
+func (h *Hub) Shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+}
+
+// Closed informa se Shutdown já foi chamado.
+func (h *Hub) Closed() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.closed
+}
+
+// Close remove o assinante do hub.
+func (sub *Subscription) Close() {
+	sub.hub.mu.Lock()
+	defer sub.hub.mu.Unlock()
+	sub.hub.remove(sub)
+}
+
+// remove desliga o assinante; exige h.mu.
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.C)
+	}
+}
+
+func (sub *Subscription) wants(evt Event) bool {
+	return match(sub.types, evt.Type) && match(sub.devices, evt.Device) && match(sub.tenants, evt.Tenant)
+}
+
+func match(set map[string]bool, v string) bool {
+	return set == nil || set[v]
+}
+
+func toSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
@@ -0,0 +1,155 @@
+// Package logging configura o log estruturado (JSON via log/slog) do master e dos childs.
+//
+// Variáveis de ambiente:
+//
+//	LOG_LEVEL        debug, info (padrão), warn ou error
+//	LOG_FORMAT       json (padrão) ou text
+//	LOG_MASK_PHONES  mascara números de telefone e JIDs (padrão true)
+//	LOG_MASK_BODIES  oculta o conteúdo das mensagens (padrão true)
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EnvVars são as variáveis repassadas do master para os childs.
+var EnvVars = []string{"LOG_LEVEL", "LOG_FORMAT", "LOG_MASK_PHONES", "LOG_MASK_BODIES"}
+
+// Chaves de atributos mascaradas; use-as ao registrar dados de contato e mensagens.
+var (
+	phoneKeys = map[string]bool{
+		"phone": true, "device": true, "number": true, "to": true, "from": true,
+		"chat": true, "sender": true, "recipient": true, "jid": true, "group": true,
+	}
+	bodyKeys = map[string]bool{"body": true, "text": true, "caption": true}
+)
+
+// phonePattern encontra números (10+ dígitos) soltos no texto, inclusive a parte
+// de usuário dos JIDs, para mascarar mensagens de bibliotecas de terceiros.
+var phonePattern = regexp.MustCompile(`\d{10,}`)
+
+// Options controla o nível e o mascaramento.
+type Options struct {
+	Level      slog.Level
+	JSON       bool
+	MaskPhones bool
+	MaskBodies bool
+}
+
+// OptionsFromEnv lê as opções das variáveis LOG_*.
+func OptionsFromEnv() Options {
+	return Options{
+		Level:      ParseLevel(os.Getenv("LOG_LEVEL")),
+		JSON:       !strings.EqualFold(os.Getenv("LOG_FORMAT"), "text"),
+		MaskPhones: envBool("LOG_MASK_PHONES", true),
+		MaskBodies: envBool("LOG_MASK_BODIES", true),
+	}
+}
+
+// Setup cria o logger a partir do ambiente e o torna o padrão do processo, inclusive
+// para chamadas ao pacote log. service identifica o binário (master ou client).
+func Setup(service string) *slog.Logger {
+	logger := New(os.Stdout, OptionsFromEnv()).With("service", service)
+	slog.SetDefault(logger)
+	log.SetFlags(0)
+	return logger
+}
+
+// New cria um logger que escreve em w com as opções informadas.
+func New(w io.Writer, opts Options) *slog.Logger {
+	handlerOpts := &slog.HandlerOptions{
+		Level:       opts.Level,
+		ReplaceAttr: replaceAttr(opts),
+	}
+	var handler slog.Handler = slog.NewTextHandler(w, handlerOpts)
+	if opts.JSON {
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	}
+	return slog.New(contextHandler{handler})
+}
+
+// ParseLevel converte LOG_LEVEL (debug, info, warn, error); vazio ou inválido vira info.
+func ParseLevel(s string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+func replaceAttr(opts Options) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		switch {
+		case len(groups) == 0 && a.Key == slog.MessageKey:
+			if opts.MaskPhones {
+				a.Value = slog.StringValue(maskPhonesIn(a.Value.String()))
+			}
+		case opts.MaskPhones && phoneKeys[a.Key] && a.Value.Kind() == slog.KindString:
+			a.Value = slog.StringValue(MaskPhone(a.Value.String()))
+		case opts.MaskBodies && bodyKeys[a.Key]:
+			a.Value = slog.StringValue(MaskBody(a.Value.String()))
+		case opts.MaskPhones && a.Value.Kind() == slog.KindString && !isIDKey(a.Key):
+			// Paths e URLs como /device/5511999999999/send
+			a.Value = slog.StringValue(maskPhonesIn(a.Value.String()))
+		case a.Value.Kind() == slog.KindAny:
+			if err, ok := a.Value.Any().(error); ok && opts.MaskPhones {
+				a.Value = slog.StringValue(maskPhonesIn(err.Error()))
+			}
+		}
+		return a
+	}
+}
+
+// MaskPhone mantém o DDI/DDD e os 2 últimos dígitos: 5511999999999 → 5511*******99.
+// JIDs mantêm o servidor: 5511999999999@s.whatsapp.net → 5511*******99@s.whatsapp.net.
+func MaskPhone(s string) string {
+	user, server, hasServer := strings.Cut(s, "@")
+	device := ""
+	if i := strings.IndexAny(user, ":."); i >= 0 {
+		user, device = user[:i], user[i:]
+	}
+	if len(user) > 6 {
+		user = user[:4] + strings.Repeat("*", len(user)-6) + user[len(user)-2:]
+	}
+	if hasServer {
+		return user + device + "@" + server
+	}
+	return user + device
+}
+
+// MaskBody troca o conteúdo da mensagem pelo seu tamanho.
+func MaskBody(s string) string {
+	if s == "" {
+		return s
+	}
+	return fmt.Sprintf("[%d caracteres]", len([]rune(s)))
+}
+
+// isIDKey indica identificadores (request_id, message_id...), que nunca são mascarados.
+func isIDKey(key string) bool {
+	return key == "id" || strings.HasSuffix(key, "_id")
+}
+
+// MaskPhones mascara todos os números encontrados no texto (paths, URLs, mensagens de erro).
+func MaskPhones(s string) string {
+	return maskPhonesIn(s)
+}
+
+func maskPhonesIn(s string) string {
+	return phonePattern.ReplaceAllStringFunc(s, MaskPhone)
+}
+
+func envBool(key string, def bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
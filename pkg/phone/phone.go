@@ -0,0 +1,138 @@
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultCountryCode é o DDI aplicado quando o número chega sem código de país.
+const DefaultCountryCode = "55"
+
+// ErrInvalid é retornado (embrulhado em *ValidationError) para qualquer número malformado.
+var ErrInvalid = errors.New("número de telefone inválido")
+
+// ValidationError descreve por que um número foi rejeitado.
+type ValidationError struct {
+	Input  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("número de telefone inválido %q: %s", e.Input, e.Reason)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalid
+}
+
+func invalid(input, reason string) error {
+	return &ValidationError{Input: input, Reason: reason}
+}
+
+// Normalize converte o que o usuário digitou em E.164 só com dígitos (ex: "5511999999999").
+//
+// Aceita formatação comum ("(11) 99999-9999", "+55 11 9999-9999", "011 99999-9999"),
+// assume DDI 55 quando ausente e, para celulares brasileiros antigos com 8 dígitos,
+// devolve a forma canônica com o nono dígito. Números de outros países precisam
+// vir com "+" ou "00" na frente.
+func Normalize(raw string) (string, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return "", invalid(raw, "vazio")
+	}
+
+	international := false
+	if strings.HasPrefix(s, "+") {
+		international = true
+		s = s[1:]
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+			// formatação, ignora
+		default:
+			return "", invalid(raw, fmt.Sprintf("caractere inesperado %q", r))
+		}
+	}
+	digits := b.String()
+
+	if !international && strings.HasPrefix(s, "00") {
+		international = true
+		digits = digits[2:]
+	}
+
+	if international {
+		if strings.HasPrefix(digits, DefaultCountryCode) {
+			return normalizeBR(raw, digits[len(DefaultCountryCode):])
+		}
+		if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+			return "", invalid(raw, "número internacional deve ter entre 8 e 15 dígitos")
+		}
+		return digits, nil
+	}
+
+	// Prefixo de tronco nacional (ex: "011 99999-9999")
+	digits = strings.TrimLeft(digits, "0")
+
+	switch len(digits) {
+	case 10, 11:
+		return normalizeBR(raw, digits)
+	case 12, 13:
+		if strings.HasPrefix(digits, DefaultCountryCode) {
+			return normalizeBR(raw, digits[len(DefaultCountryCode):])
+		}
+	case 8, 9:
+		return "", invalid(raw, "DDD ausente")
+	}
+	return "", invalid(raw, "formato não reconhecido")
+}
+
+// normalizeBR valida DDD + número local (sem o DDI) e devolve a forma canônica.
+func normalizeBR(raw, national string) (string, error) {
+	if len(national) != 10 && len(national) != 11 {
+		return "", invalid(raw, "número brasileiro deve ter DDD + 8 ou 9 dígitos")
+	}
+
+	ddd, local := national[:2], national[2:]
+	if ddd[0] == '0' || ddd[1] == '0' {
+		return "", invalid(raw, "DDD inválido "+ddd)
+	}
+
+	switch len(local) {
+	case 9:
+		if local[0] != '9' {
+			return "", invalid(raw, "celular com 9 dígitos deve começar com 9")
+		}
+	case 8:
+		if local[0] == '0' || local[0] == '1' {
+			return "", invalid(raw, "número local inválido")
+		}
+		if isLegacyMobile(local) {
+			local = "9" + local
+		}
+	}
+
+	return DefaultCountryCode + ddd + local, nil
+}
+
+// isLegacyMobile indica um celular no formato anterior ao nono dígito (8 dígitos iniciando em 6-9).
+func isLegacyMobile(local string) bool {
+	return len(local) == 8 && local[0] >= '6'
+}
+
+// Variants devolve as formas possíveis de um número já normalizado, canônica primeiro.
+//
+// Contas antigas de celulares brasileiros continuam registradas no WhatsApp sem o
+// nono dígito, então "5511999999999" também gera "551199999999". Para os demais
+// números o resultado tem um único elemento.
+func Variants(number string) []string {
+	if len(number) == 13 && strings.HasPrefix(number, DefaultCountryCode) && number[4] == '9' && isLegacyMobile(number[5:]) {
+		return []string{number, number[:4] + number[5:]}
+	}
+	return []string{number}
+}
@@ -0,0 +1,86 @@
+package phone
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"celular com nono dígito", "(11) 99999-9999", "5511999999999"},
+		{"celular sem nono dígito", "11 9999-9999", "5511999999999"},
+		{"fixo", "11 3333-4444", "551133334444"},
+		{"prefixo de tronco", "011 99999-9999", "5511999999999"},
+		{"com DDI sem +", "5511999999999", "5511999999999"},
+		{"com DDI sem nono dígito", "551188887777", "5511988887777"},
+		{"+55", "+55 11 99999-9999", "5511999999999"},
+		{"+55 sem nono dígito", "+55 11 8888-7777", "5511988887777"},
+		{"0055", "0055 11 99999-9999", "5511999999999"},
+		{"Singapura com +", "+6591234567", "6591234567"},
+		{"Singapura com 00", "006591234567", "6591234567"},
+		{"EUA", "+1 (415) 555-2671", "14155552671"},
+		{"Reino Unido", "+44 7911 123456", "447911123456"},
+		{"estrangeiro sem + vira BR", "6591234567", "5565991234567"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.raw)
+			if err != nil {
+				t.Fatalf("Normalize(%q): %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"vazio", "  "},
+		{"letras", "11 9999-abcd"},
+		{"sem DDD", "99999-9999"},
+		{"DDD com zero", "+55 10 99999-9999"},
+		{"nove dígitos sem 9", "11 89999-9999"},
+		{"internacional curto", "+1234567"},
+		{"internacional longo", "+1234567890123456"},
+		{"internacional começando com 0", "+0123456789"},
+		{"BR com dígitos demais", "+55 11 999999-99999"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.raw)
+			if err == nil {
+				t.Fatalf("Normalize(%q) = %q, want error", tt.raw, got)
+			}
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Normalize(%q) error %v não embrulha ErrInvalid", tt.raw, err)
+			}
+		})
+	}
+}
+
+func TestVariants(t *testing.T) {
+	tests := []struct {
+		number string
+		want   []string
+	}{
+		{"5511999999999", []string{"5511999999999", "551199999999"}},
+		{"551133334444", []string{"551133334444"}},
+		{"5511912345678", []string{"5511912345678"}}, // 1234-5678 não é celular antigo
+		{"6591234567", []string{"6591234567"}},
+	}
+	for _, tt := range tests {
+		if got := Variants(tt.number); !slices.Equal(got, tt.want) {
+			t.Errorf("Variants(%q) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Package ratelimit limita as requisições do proxy com token buckets em memória,
+// um por device, tenant ou API key e por orçamento (envio ou leitura).
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/simpplify-org/GO-simpzap/pkg/config"
+	"golang.org/x/time/rate"
+)
+
+// Escopos das regras.
+const (
+	ScopeDevice = "device"
+	ScopeTenant = "tenant"
+	ScopeKey    = "key" // X-API-Key
+)
+
+// Orçamentos: envios têm limite próprio, separado das leituras.
+const (
+	BudgetSend = "send"
+	BudgetRead = "read"
+)
+
+// AnySubject é a regra padrão de um escopo, usada quando o assunto não tem regra própria.
+const AnySubject = "*"
+
+var rejected = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "whatsapp_master_rate_limited_total",
+	Help: "Requisições recusadas pelo rate limit do proxy, por escopo e orçamento.",
+}, []string{"scope", "budget"})
+
+// Key identifica um bucket: device "5511999999999", tenant "acme" ou a API key.
+type Key struct {
+	Scope   string
+	Subject string
+	Budget  string
+}
+
+type bucket struct {
+	limit  config.Limit
+	tokens *rate.Limiter
+}
+
+// Limiter guarda as regras e os buckets. As regras são trocadas inteiras em SetRules;
+// os buckets existentes passam a usar o limite novo sem perder os tokens.
+type Limiter struct {
+	mu      sync.Mutex
+	rules   map[Key]config.Limit
+	buckets map[Key]*bucket
+}
+
+func New() *Limiter {
+	return &Limiter{
+		rules:   make(map[Key]config.Limit),
+		buckets: make(map[Key]*bucket),
+	}
+}
+
+// SetRules troca as regras. Um assunto AnySubject vale para todo o escopo.
+func (l *Limiter) SetRules(rules map[Key]config.Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rules = rules
+	for key, b := range l.buckets {
+		limit, ok := l.limitFor(key)
+		if !ok {
+			delete(l.buckets, key)
+			continue
+		}
+		if limit != b.limit {
+			now := time.Now()
+			b.limit = limit
+			b.tokens.SetLimitAt(now, perSecond(limit))
+			b.tokens.SetBurstAt(now, limit.Burst)
+		}
+	}
+}
+
+// HasRule informa se o assunto tem regra própria (sem contar a padrão AnySubject do escopo).
+func (l *Limiter) HasRule(key Key) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.rules[key]
+	return ok
+}
+
+// limitFor retorna a regra do assunto ou, sem ela, a padrão do escopo; exige l.mu.
+func (l *Limiter) limitFor(key Key) (config.Limit, bool) {
+	limit, ok := l.rules[key]
+	if !ok {
+		limit, ok = l.rules[Key{Scope: key.Scope, Subject: AnySubject, Budget: key.Budget}]
+	}
+	if !ok || limit.Unlimited() {
+		return config.Limit{}, false
+	}
+	return limit, true
+}
+
+// Decision é o resultado de Allow, com os valores do bucket mais apertado.
+type Decision struct {
+	Allowed    bool
+	Limited    bool // alguma regra se aplicou; sem isso não há cabeçalhos
+	TooLarge   bool // custo maior que a rajada: nunca cabe, Limit é o máximo por requisição
+	Key        Key  // bucket que recusou (ou o mais apertado)
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // até o bucket encher de novo
+	RetryAfter time.Duration // só quando recusada
+}
+
+// Allow consome cost tokens de cada bucket das chaves. Se algum não tiver saldo,
+// nada é consumido e a decisão traz o tempo de espera; se o custo passar da rajada,
+// esperar não adianta e a decisão vem com TooLarge, sem RetryAfter.
+func (l *Limiter) Allow(cost int, keys ...Key) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var d Decision
+	var reservations []*rate.Reservation
+	for _, key := range keys {
+		limit, ok := l.limitFor(key)
+		if !ok {
+			continue
+		}
+		b, ok := l.buckets[key]
+		if !ok {
+			b = &bucket{limit: limit, tokens: rate.NewLimiter(perSecond(limit), limit.Burst)}
+			l.buckets[key] = b
+		}
+
+		r := b.tokens.ReserveN(now, cost)
+		if wait := r.DelayFrom(now); !r.OK() || wait > 0 {
+			r.CancelAt(now)
+			for _, prev := range reservations {
+				prev.CancelAt(now)
+			}
+			rejected.WithLabelValues(key.Scope, key.Budget).Inc()
+			tokens := b.tokens.TokensAt(now)
+			if !r.OK() {
+				return Decision{
+					Limited:   true,
+					TooLarge:  true,
+					Key:       key,
+					Limit:     limit.Burst,
+					Remaining: max(0, int(math.Floor(tokens))),
+					Reset:     fill(limit, tokens),
+				}
+			}
+			return Decision{
+				Limited:    true,
+				Key:        key,
+				Limit:      limit.Burst,
+				Remaining:  0,
+				Reset:      fill(limit, tokens),
+				RetryAfter: wait,
+			}
+		}
+		reservations = append(reservations, r)
+
+		remaining := max(0, int(math.Floor(b.tokens.TokensAt(now))))
+		if !d.Limited || remaining < d.Remaining {
+			d = Decision{
+				Limited:   true,
+				Key:       key,
+				Limit:     limit.Burst,
+				Remaining: remaining,
+				Reset:     fill(limit, b.tokens.TokensAt(now)),
+			}
+		}
+	}
+	d.Allowed = true
+	return d
+}
+
+// Sweep descarta os buckets que voltaram a ficar cheios; recriá-los no próximo uso
+// dá o mesmo resultado.
+func (l *Limiter) Sweep() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for key, b := range l.buckets {
+		if b.tokens.TokensAt(now) >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// SetHeaders escreve RateLimit-Limit, RateLimit-Remaining e RateLimit-Reset e,
+// na recusa que se resolve esperando, Retry-After (em segundos, arredondados para cima).
+func (d Decision) SetHeaders(h http.Header) {
+	if !d.Limited {
+		return
+	}
+	h.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(seconds(d.Reset)))
+	if !d.Allowed && !d.TooLarge {
+		h.Set("Retry-After", strconv.Itoa(d.RetryAfterSeconds()))
+	}
+}
+
+// RetryAfterSeconds é a espera da recusa em segundos inteiros (no mínimo 1).
+func (d Decision) RetryAfterSeconds() int {
+	return max(1, seconds(d.RetryAfter))
+}
+
+func perSecond(limit config.Limit) rate.Limit {
+	return rate.Limit(limit.PerMinute / 60)
+}
+
+// fill é o tempo até o bucket com tokens voltar à rajada cheia.
+func fill(limit config.Limit, tokens float64) time.Duration {
+	missing := float64(limit.Burst) - tokens
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing / float64(perSecond(limit)) * float64(time.Second))
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
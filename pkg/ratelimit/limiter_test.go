@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/config"
+)
+
+var (
+	device = Key{Scope: ScopeDevice, Subject: "5511999999999", Budget: BudgetSend}
+	tenant = Key{Scope: ScopeTenant, Subject: "acme", Budget: BudgetSend}
+	apiKey = Key{Scope: ScopeKey, Subject: "k1", Budget: BudgetSend}
+)
+
+// 6/m repõe um token a cada 10s: nada é reposto durante o teste.
+func slow(burst int) config.Limit {
+	return config.Limit{PerMinute: 6, Burst: burst}
+}
+
+func TestAllow(t *testing.T) {
+	type call struct {
+		cost      int
+		keys      []Key
+		allowed   bool
+		limited   bool
+		tooLarge  bool
+		key       Key // bucket que recusou ou o mais apertado
+		remaining int
+	}
+	tests := []struct {
+		name  string
+		rules map[Key]config.Limit
+		calls []call
+	}{
+		{
+			name:  "sem regra não limita",
+			rules: map[Key]config.Limit{},
+			calls: []call{{cost: 1, keys: []Key{device}, allowed: true}},
+		},
+		{
+			name:  "regra padrão do escopo",
+			rules: map[Key]config.Limit{{Scope: ScopeDevice, Subject: AnySubject, Budget: BudgetSend}: slow(2)},
+			calls: []call{
+				{cost: 1, keys: []Key{device}, allowed: true, limited: true, key: device, remaining: 1},
+				{cost: 1, keys: []Key{device}, allowed: true, limited: true, key: device, remaining: 0},
+				{cost: 1, keys: []Key{device}, allowed: false, limited: true, key: device},
+			},
+		},
+		{
+			name: "regra própria sobrescreve a padrão",
+			rules: map[Key]config.Limit{
+				{Scope: ScopeDevice, Subject: AnySubject, Budget: BudgetSend}: slow(1),
+				device: slow(5),
+			},
+			calls: []call{{cost: 3, keys: []Key{device}, allowed: true, limited: true, key: device, remaining: 2}},
+		},
+		{
+			name:  "regra com per_minute 0 tira o limite",
+			rules: map[Key]config.Limit{{Scope: ScopeDevice, Subject: AnySubject, Budget: BudgetSend}: slow(1), device: {}},
+			calls: []call{
+				{cost: 5, keys: []Key{device}, allowed: true},
+				{cost: 5, keys: []Key{device}, allowed: true},
+			},
+		},
+		{
+			name:  "recusa de um bucket posterior devolve os tokens dos anteriores",
+			rules: map[Key]config.Limit{device: slow(5), tenant: slow(1)},
+			calls: []call{
+				{cost: 1, keys: []Key{device, tenant}, allowed: true, limited: true, key: tenant, remaining: 0},
+				{cost: 1, keys: []Key{device, tenant}, allowed: false, limited: true, key: tenant},
+				{cost: 1, keys: []Key{device, tenant}, allowed: false, limited: true, key: tenant},
+				// O device só pagou pela primeira requisição
+				{cost: 1, keys: []Key{device}, allowed: true, limited: true, key: device, remaining: 3},
+			},
+		},
+		{
+			name:  "o mais apertado vai nos cabeçalhos",
+			rules: map[Key]config.Limit{device: slow(10), tenant: slow(4), apiKey: slow(8)},
+			calls: []call{{cost: 2, keys: []Key{device, tenant, apiKey}, allowed: true, limited: true, key: tenant, remaining: 2}},
+		},
+		{
+			name:  "custo maior que a rajada nunca passa e não consome",
+			rules: map[Key]config.Limit{device: slow(5)},
+			calls: []call{
+				{cost: 6, keys: []Key{device}, allowed: false, limited: true, tooLarge: true, key: device},
+				{cost: 5, keys: []Key{device}, allowed: true, limited: true, key: device, remaining: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New()
+			l.SetRules(tt.rules)
+			for i, c := range tt.calls {
+				d := l.Allow(c.cost, c.keys...)
+				if d.Allowed != c.allowed || d.Limited != c.limited || d.TooLarge != c.tooLarge {
+					t.Fatalf("chamada %d: allowed=%v limited=%v too_large=%v, quer allowed=%v limited=%v too_large=%v",
+						i, d.Allowed, d.Limited, d.TooLarge, c.allowed, c.limited, c.tooLarge)
+				}
+				if !c.limited {
+					continue
+				}
+				if d.Key != c.key {
+					t.Errorf("chamada %d: key = %+v, quer %+v", i, d.Key, c.key)
+				}
+				if c.allowed && d.Remaining != c.remaining {
+					t.Errorf("chamada %d: remaining = %d, quer %d", i, d.Remaining, c.remaining)
+				}
+				if !c.allowed && !c.tooLarge && d.RetryAfter <= 0 {
+					t.Errorf("chamada %d: recusa sem RetryAfter", i)
+				}
+				if c.tooLarge && d.RetryAfter != 0 {
+					t.Errorf("chamada %d: lote que nunca cabe com RetryAfter %s", i, d.RetryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestSetRulesKeepsTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		next      config.Limit
+		remaining int // depois de consumir 3 com a regra inicial slow(5) e mais 1 com next
+		allowed   bool
+	}{
+		{name: "mesma regra", next: slow(5), remaining: 1, allowed: true},
+		{name: "rajada maior não enche o bucket", next: slow(10), remaining: 1, allowed: true},
+		{name: "rajada menor limita o saldo a ela", next: slow(1), remaining: 0, allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New()
+			l.SetRules(map[Key]config.Limit{device: slow(5)})
+			if d := l.Allow(3, device); !d.Allowed || d.Remaining != 2 {
+				t.Fatalf("consumo inicial: %+v", d)
+			}
+
+			l.SetRules(map[Key]config.Limit{device: tt.next})
+			d := l.Allow(1, device)
+			if d.Allowed != tt.allowed {
+				t.Fatalf("allowed = %v, quer %v (%+v)", d.Allowed, tt.allowed, d)
+			}
+			if d.Allowed && d.Remaining != tt.remaining {
+				t.Errorf("remaining = %d, quer %d", d.Remaining, tt.remaining)
+			}
+			if d.Limit != tt.next.Burst {
+				t.Errorf("limit = %d, quer a rajada nova %d", d.Limit, tt.next.Burst)
+			}
+		})
+	}
+}
+
+func TestSetRulesRemovesBuckets(t *testing.T) {
+	l := New()
+	l.SetRules(map[Key]config.Limit{device: slow(1)})
+	l.Allow(1, device)
+
+	// Sem regra o device deixa de ser limitado; com a regra de volta o bucket recomeça cheio
+	l.SetRules(map[Key]config.Limit{})
+	if d := l.Allow(1, device); !d.Allowed || d.Limited {
+		t.Fatalf("sem regra: %+v", d)
+	}
+	l.SetRules(map[Key]config.Limit{device: slow(1)})
+	if d := l.Allow(1, device); !d.Allowed {
+		t.Fatalf("regra recriada: %+v", d)
+	}
+}
+
+func TestHasRule(t *testing.T) {
+	l := New()
+	l.SetRules(map[Key]config.Limit{
+		{Scope: ScopeTenant, Subject: AnySubject, Budget: BudgetSend}: slow(1),
+		apiKey: {},
+	})
+	if !l.HasRule(apiKey) {
+		t.Error("regra própria com per_minute 0 deve contar")
+	}
+	if l.HasRule(tenant) {
+		t.Error("a regra padrão do escopo não é regra própria")
+	}
+	if l.HasRule(Key{Scope: ScopeKey, Subject: "outra", Budget: BudgetSend}) {
+		t.Error("key sem regra")
+	}
+}
@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// StatusRecorder guarda o status da resposta, mantendo Flush (SSE) e Hijack (WebSocket).
+// Usado pelo middleware de tracing, pelos logs do child e pelas métricas do proxy do master.
+type StatusRecorder struct {
+	http.ResponseWriter
+	status int
+
+	// Hijacked, se definido, embrulha a conexão do upgrade (ex: o túnel do proxy do master)
+	Hijacked func(net.Conn) net.Conn
+}
+
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: w}
+}
+
+// Status retorna o status enviado (200 se o handler não definiu nenhum).
+func (r *StatusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+func (r *StatusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *StatusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *StatusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *StatusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijack não suportado")
+	}
+	if r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	conn, brw, err := h.Hijack()
+	if err == nil && r.Hijacked != nil {
+		conn = r.Hijacked(conn)
+	}
+	return conn, brw, err
+}
+
+func (r *StatusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
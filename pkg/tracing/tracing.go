@@ -0,0 +1,134 @@
+// Package tracing propaga o ID de correlação (X-Request-Id) e, opcionalmente, spans
+// OpenTelemetry entre o master, os childs e os webhooks.
+//
+// Variáveis de ambiente:
+//
+//	OTEL_TRACES_EXPORTER         none (padrão), otlp ou stdout
+//	OTEL_EXPORTER_OTLP_ENDPOINT  coletor OTLP/HTTP (padrão http://localhost:4318)
+//	OTEL_SERVICE_NAME            sobrescreve o nome do serviço (whatsapp-master, whatsapp-client)
+//	OTEL_TRACES_SAMPLER          amostragem (padrão parentbased_always_on)
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EnvVars são as variáveis repassadas do master para os childs.
+var EnvVars = []string{
+	"OTEL_TRACES_EXPORTER", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+	"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_INSECURE", "OTEL_TRACES_SAMPLER", "OTEL_TRACES_SAMPLER_ARG",
+}
+
+const tracerName = "github.com/simpplify-org/GO-simpzap"
+
+// Setup configura a propagação (traceparent) e, se OTEL_TRACES_EXPORTER pedir, o
+// exportador de spans. A função retornada envia os spans pendentes e deve ser
+// chamada no encerramento.
+func Setup(ctx context.Context, service string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch name := strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_EXPORTER"))); name {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout", "console":
+		exporter, err = stdouttrace.New()
+	default:
+		return nil, fmt.Errorf("OTEL_TRACES_EXPORTER inválido: %q (use none, otlp ou stdout)", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar exportador de spans: %w", err)
+	}
+
+	// OTEL_SERVICE_NAME e OTEL_RESOURCE_ATTRIBUTES têm precedência sobre o nome padrão
+	res, err := resource.Merge(
+		resource.NewSchemaless(attribute.String("service.name", "whatsapp-"+service)),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	slog.Info("🔭 Tracing habilitado", "exporter", os.Getenv("OTEL_TRACES_EXPORTER"))
+	return provider.Shutdown, nil
+}
+
+// Start inicia um span com o tracer do projeto (no-op quando o tracing está desligado).
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// End registra o erro (se houver) e encerra o span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject copia o ID de correlação e o contexto do span para os cabeçalhos de uma
+// requisição de saída (child, webhooks).
+func Inject(ctx context.Context, header http.Header) {
+	if id := logging.RequestID(ctx); id != "" {
+		header.Set(logging.RequestIDHeader, id)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Middleware aceita o X-Request-Id recebido (ou gera um novo quando falta ou é inválido),
+// devolve-o na resposta, continua o trace do traceparent recebido e abre o span da requisição.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(logging.RequestIDHeader)
+		if !logging.ValidRequestID(requestID) {
+			requestID = logging.NewRequestID()
+			r.Header.Set(logging.RequestIDHeader, requestID)
+		}
+		w.Header().Set(logging.RequestIDHeader, requestID)
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx = logging.WithRequestID(ctx, requestID)
+		ctx, span := Start(ctx, "HTTP "+r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", logging.MaskPhones(r.URL.Path)),
+				attribute.String("request_id", requestID),
+			))
+		defer span.End()
+
+		rec := NewStatusRecorder(w)
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		status := rec.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
@@ -6,7 +6,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"github.com/fsouza/go-dockerclient"
-	"log"
+	"github.com/simpplify-org/GO-simpzap/pkg/config"
+	"log/slog"
 	"net"
 	"os"
 	"strconv"
@@ -16,21 +17,32 @@ import (
 
 type DockerManager struct {
 	client *docker.Client
+	cfg    config.Docker
 }
 
+// childDataDir é onde o volume do device é montado no container (DATA_DIR do child).
+const childDataDir = "/data"
+
 type ClientContainer struct {
-	ID       string
-	Host     string
-	Port     int
-	Endpoint string // http://host:port
+	ID          string
+	Host        string
+	Port        int
+	Endpoint    string         // http://host:port
+	Tenant      string         // cliente dono do device (label "tenant"), vazio se não informado
+	IdleTimeout *time.Duration // label "idle_timeout"; nil usa idle.timeout do master, 0 nunca para
 }
 
-func NewDockerManager() (*DockerManager, error) {
+func NewDockerManager(cfg config.Docker) (*DockerManager, error) {
 	c, err := docker.NewClientFromEnv()
 	if err != nil {
 		return nil, fmt.Errorf("erro ao inicializar docker client: %w", err)
 	}
-	return &DockerManager{client: c}, nil
+	return &DockerManager{client: c, cfg: cfg}, nil
+}
+
+// internalPort é a porta em que o child escuta dentro do container.
+func (dm *DockerManager) internalPort() docker.Port {
+	return docker.Port(fmt.Sprintf("%d/tcp", dm.cfg.InternalPort))
 }
 
 func (dm *DockerManager) EnsureImage(ctx context.Context, image string) error {
@@ -39,7 +51,7 @@ func (dm *DockerManager) EnsureImage(ctx context.Context, image string) error {
 	if err == nil {
 		return nil
 	}
-	log.Printf("[DockerManager] Pulling image %s ...", image)
+	slog.Info("Baixando imagem", "component", "docker", "image", image)
 	opts := docker.PullImageOptions{
 		Repository:   image,
 		OutputStream: os.Stdout,
@@ -51,8 +63,9 @@ func (dm *DockerManager) EnsureImage(ctx context.Context, image string) error {
 	return nil
 }
 
-// StartContainer cria e inicia um container com porta aleatória no host
-func (dm *DockerManager) StartContainer(ctx context.Context, image, namePrefix string, labels map[string]string, envs []string) (*ClientContainer, error) {
+// StartContainer cria e inicia um container com porta aleatória no host. O volume
+// (criado se não existir) guarda a sessão e o histórico entre paradas e recriações.
+func (dm *DockerManager) StartContainer(ctx context.Context, image, namePrefix, volume string, labels map[string]string, envs []string) (*ClientContainer, error) {
 	// Gera uma porta livre no host
 	hostPort, err := getFreePort()
 	if err != nil {
@@ -60,7 +73,7 @@ func (dm *DockerManager) StartContainer(ctx context.Context, image, namePrefix s
 	}
 
 	name := fmt.Sprintf("%s-%d", namePrefix, time.Now().UnixNano())
-	internalPort := docker.Port("8080/tcp")
+	internalPort := dm.internalPort()
 
 	// Mapeia porta interna do container para a externa
 	portBindings := map[docker.Port][]docker.PortBinding{
@@ -72,6 +85,10 @@ func (dm *DockerManager) StartContainer(ctx context.Context, image, namePrefix s
 		return nil, err
 	}
 
+	if _, err := dm.client.CreateVolume(docker.CreateVolumeOptions{Name: volume, Labels: labels, Context: ctx}); err != nil {
+		return nil, fmt.Errorf("erro ao criar volume %s: %w", volume, err)
+	}
+
 	container, err := dm.client.CreateContainer(docker.CreateContainerOptions{
 		Name: name,
 		Config: &docker.Config{
@@ -82,7 +99,10 @@ func (dm *DockerManager) StartContainer(ctx context.Context, image, namePrefix s
 		},
 		HostConfig: &docker.HostConfig{
 			PortBindings: portBindings,
-			AutoRemove:   true,
+			NetworkMode:  dm.cfg.NetworkMode,
+			Binds:        []string{volume + ":" + childDataDir},
+			// Sem AutoRemove: o container parado por inatividade precisa continuar existindo
+			AutoRemove: false,
 		},
 	})
 	if err != nil {
@@ -95,7 +115,7 @@ func (dm *DockerManager) StartContainer(ctx context.Context, image, namePrefix s
 
 	host := dm.getDockerHost()
 	endpoint := fmt.Sprintf("http://%s:%d", host, hostPort)
-	log.Printf("[Docker] ✅ Container %s iniciado (ID=%s) - Porta %d - Endpoint: %s", name, container.ID, hostPort, endpoint)
+	slog.Info("✅ Container iniciado", "component", "docker", "container", name, "container_id", container.ID, "port", hostPort, "endpoint", endpoint)
 
 	return &ClientContainer{
 		ID:       container.ID,
@@ -122,40 +142,67 @@ func (dm *DockerManager) FindContainerByLabel(ctx context.Context, labelKey, lab
 
 	c := containers[0]
 	if c.State != "running" {
-		if err := dm.client.StartContainer(c.ID, nil); err != nil {
-			return nil, fmt.Errorf("erro ao iniciar container existente %s: %w", c.ID, err)
-		}
+		return dm.RestartContainer(ctx, c.ID)
 	}
+	return dm.inspectContainer(c.ID)
+}
+
+// RestartContainer inicia de novo um container parado, mantendo a porta publicada.
+func (dm *DockerManager) RestartContainer(ctx context.Context, id string) (*ClientContainer, error) {
+	if err := dm.client.StartContainerWithContext(id, nil, ctx); err != nil {
+		return nil, fmt.Errorf("erro ao iniciar container existente %s: %w", id, err)
+	}
+	return dm.inspectContainer(id)
+}
 
-	inspect, err := dm.client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: c.ID})
+// inspectContainer monta o ClientContainer a partir da porta publicada e dos labels.
+func (dm *DockerManager) inspectContainer(id string) (*ClientContainer, error) {
+	inspect, err := dm.client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: id})
 	if err != nil {
 		return nil, err
 	}
 
 	host := dm.getDockerHost()
 	port := 0
-	if bindings, ok := inspect.NetworkSettings.Ports["8080/tcp"]; ok && len(bindings) > 0 {
+	if bindings, ok := inspect.NetworkSettings.Ports[dm.internalPort()]; ok && len(bindings) > 0 {
 		port, _ = strconv.Atoi(bindings[0].HostPort)
 	}
 
 	return &ClientContainer{
-		ID:       c.ID,
-		Host:     host,
-		Port:     port,
-		Endpoint: fmt.Sprintf("http://%s:%d", host, port),
+		ID:          id,
+		Host:        host,
+		Port:        port,
+		Endpoint:    fmt.Sprintf("http://%s:%d", host, port),
+		Tenant:      inspect.Config.Labels["tenant"],
+		IdleTimeout: idleTimeoutLabel(inspect.Config.Labels),
 	}, nil
 }
+
+// idleTimeoutLabel lê o label idle_timeout do container (nil se ausente ou inválido).
+func idleTimeoutLabel(labels map[string]string) *time.Duration {
+	d, err := time.ParseDuration(labels["idle_timeout"])
+	if err != nil {
+		return nil
+	}
+	return &d
+}
+
 func (dm *DockerManager) StopContainer(ctx context.Context, id string) error {
-	timeout := 5
-	err := dm.client.StopContainer(id, uint(timeout))
+	timeout := uint(dm.cfg.StopTimeout.Std().Seconds())
+	err := dm.client.StopContainer(id, timeout)
 	if err != nil {
-		log.Printf("[Docker] erro ao parar container %s: %v", id, err)
+		slog.Warn("Erro ao parar container", "component", "docker", "container_id", id, "error", err)
 		return err
 	}
-	log.Printf("[Docker] Container %s parado", id)
+	slog.Info("Container parado", "component", "docker", "container_id", id)
 	return nil
 }
 
+// RemoveVolume apaga o volume do device (sessão e histórico).
+func (dm *DockerManager) RemoveVolume(ctx context.Context, name string) error {
+	return dm.client.RemoveVolumeWithOptions(docker.RemoveVolumeOptions{Name: name, Context: ctx})
+}
+
 func (dm *DockerManager) RemoveContainer(ctx context.Context, id string) error {
 	opts := docker.RemoveContainerOptions{ID: id, RemoveVolumes: true, Force: true}
 	if err := dm.client.RemoveContainer(opts); err != nil {
@@ -165,9 +212,9 @@ func (dm *DockerManager) RemoveContainer(ctx context.Context, id string) error {
 }
 
 func (dm *DockerManager) getDockerHost() string {
-	// 1. Tenta var de ambiente primeiro
-	if h := os.Getenv("DOCKER_BRIDGE_HOST"); h != "" {
-		return h
+	// 1. Tenta a configuração primeiro (docker.bridge_host / DOCKER_BRIDGE_HOST)
+	if dm.cfg.BridgeHost != "" {
+		return dm.cfg.BridgeHost
 	}
 
 	// 2. Tenta descobrir o default gateway (IP do host Docker na rede bridge) via /proc/net/route
@@ -0,0 +1,207 @@
+package whatsapp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/eventhub"
+)
+
+const (
+	aggregateHistory   = 5000             // eventos guardados para retomada no /events do master
+	aggregateSubBuffer = 1024             // fila de cada assinante antes de ser desconectado
+	followSyncInterval = 5 * time.Second  // frequência com que novos/removidos devices são detectados
+	followMaxDelay     = 30 * time.Second // espera máxima entre tentativas de reassinar um child
+)
+
+// EventStream assina o /events de cada child e republica tudo em um único hub,
+// com o número e o tenant do device em cada evento.
+type EventStream struct {
+	zap       *ZapPkg
+	hub       *eventhub.Hub
+	client    *http.Client
+	mu        sync.Mutex
+	followers map[string]context.CancelFunc // key: número do device
+	onConnect func(number string)           // chamado a cada (re)assinatura de um child
+}
+
+func NewEventStream(zap *ZapPkg) *EventStream {
+	return &EventStream{
+		zap:       zap,
+		hub:       eventhub.New(aggregateHistory, aggregateSubBuffer),
+		client:    &http.Client{}, // sem timeout: a resposta é um stream
+		followers: make(map[string]context.CancelFunc),
+	}
+}
+
+// Hub retorna o hub agregado servido em /events.
+func (es *EventStream) Hub() *eventhub.Hub {
+	return es.hub
+}
+
+// OnConnect registra fn para ser chamada sempre que o stream de um child é (re)aberto,
+// o que acontece quando o device é criado, quando o master sobe e quando o container reinicia.
+func (es *EventStream) OnConnect(fn func(number string)) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.onConnect = fn
+}
+
+// Run mantém uma assinatura por device até ctx ser cancelado.
+func (es *EventStream) Run(ctx context.Context) {
+	// Carrega os containers que já existiam antes do master subir
+	if _, err := es.zap.ListDevices(ctx); err != nil {
+		slog.Error("Erro ao listar devices", "component", "event_stream", "error", err)
+	}
+
+	ticker := time.NewTicker(followSyncInterval)
+	defer ticker.Stop()
+	for {
+		es.sync(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sync inicia a assinatura dos devices novos e encerra a dos removidos.
+func (es *EventStream) sync(ctx context.Context) {
+	devices := es.zap.deviceTenants()
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	for number, cancel := range es.followers {
+		if _, ok := devices[number]; !ok {
+			cancel()
+			delete(es.followers, number)
+			slog.Info("Assinatura encerrada", "component", "event_stream", "device", number)
+		}
+	}
+	for number, tenant := range devices {
+		if _, ok := es.followers[number]; ok {
+			continue
+		}
+		fctx, cancel := context.WithCancel(ctx)
+		es.followers[number] = cancel
+		go es.follow(fctx, number, tenant)
+	}
+}
+
+// follow mantém a assinatura do child, reconectando com backoff quando o container
+// reinicia ou a conexão cai. Retoma a partir do último ID recebido; depois de um restart
+// do child o ID é de outro processo e o child responde com events_lost mais o histórico novo.
+func (es *EventStream) follow(ctx context.Context, number, tenant string) {
+	var lastID uint64
+	delay := time.Second
+
+	for {
+		start := time.Now()
+		err := es.read(ctx, number, tenant, &lastID)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > followMaxDelay {
+			// A conexão ficou de pé por um tempo; a queda não faz parte de uma sequência de falhas
+			delay = time.Second
+		}
+		slog.Warn("Stream do child caiu, reconectando", "component", "event_stream", "device", number, "retry_in", delay.String(), "error", err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = min(delay*2, followMaxDelay)
+	}
+}
+
+// read consome o SSE do child até a conexão cair.
+func (es *EventStream) read(ctx context.Context, number, tenant string, lastID *uint64) error {
+	endpoint, err := es.zap.GetDeviceEndpoint(number)
+	if err != nil {
+		return err
+	}
+
+	url := endpoint + "/events"
+	if *lastID > 0 {
+		url = fmt.Sprintf("%s?last_event_id=%d", url, *lastID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := es.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("child respondeu %d", resp.StatusCode)
+	}
+
+	es.mu.Lock()
+	onConnect := es.onConnect
+	es.mu.Unlock()
+	if onConnect != nil {
+		go onConnect(number)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue // id:, event:, pings e separadores
+		}
+
+		var evt struct {
+			ID        uint64          `json:"id"`
+			Type      string          `json:"type"`
+			Timestamp time.Time       `json:"timestamp"`
+			Data      json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(data), &evt); err != nil {
+			slog.Warn("Evento inválido", "component", "event_stream", "device", number, "error", err)
+			continue
+		}
+		if evt.ID > 0 {
+			if evt.ID <= *lastID {
+				// Um child reiniciado numera a partir do horário de boot, acima do último ID;
+				// um ID menor só vem de um relógio que voltou. A retomada passa a usar o novo
+				slog.Warn("Numeração de eventos do child voltou", "component", "event_stream", "device", number, "last_event_id", *lastID, "id", evt.ID)
+			}
+			*lastID = evt.ID
+		}
+		if evt.Type == "events_lost" {
+			slog.Warn("Child descartou eventos antes da retomada", "component", "event_stream", "device", number, "last_event_id", *lastID)
+		}
+		if evt.Type == "message" {
+			// Mensagem recebida conta como uso: o device não para enquanto conversa
+			es.zap.touch(number)
+		}
+
+		es.hub.Publish(eventhub.Event{
+			Type:      evt.Type,
+			Device:    number,
+			Tenant:    tenant,
+			Timestamp: evt.Timestamp,
+			Data:      evt.Data,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("stream encerrado pelo child")
+}
@@ -1,6 +1,7 @@
 package whatsapp
 
 import (
+	"encoding/json"
 	"errors"
 	"net"
 	"net/http"
@@ -35,12 +36,22 @@ func singleJoiningSlash(a, b string) string {
 	return a + b
 }
 
+// writeError responde no mesmo formato de erro da API do master: {"error": "..."}.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
 func (s *ZapPkg) waitUntilHealthy(endpoint string, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
 		resp, err := http.Get(endpoint + "/health")
-		if err == nil && resp.StatusCode == http.StatusOK {
-			return nil
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
 		}
 		time.Sleep(300 * time.Millisecond)
 	}
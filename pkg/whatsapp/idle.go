@@ -0,0 +1,296 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrDeviceStopped indica que o container do device foi parado por inatividade;
+// ele volta a subir com Acquire.
+var ErrDeviceStopped = errors.New("device parado por inatividade")
+
+// deviceActivity é o uso recente de um device, base do scale-to-zero.
+type deviceActivity struct {
+	last    time.Time // última requisição ou mensagem recebida
+	active  int       // requisições e streams (WebSocket, SSE) em andamento
+	stopped bool      // container parado por inatividade
+}
+
+// activityOf retorna (criando, se preciso) o registro de uso do device; exige s.actMu.
+func (s *ZapPkg) activityOf(deviceID string) *deviceActivity {
+	a, ok := s.activity[deviceID]
+	if !ok {
+		a = &deviceActivity{last: time.Now()}
+		s.activity[deviceID] = a
+	}
+	return a
+}
+
+// touch registra uso do device (ex: mensagem recebida pelo stream de eventos).
+func (s *ZapPkg) touch(deviceID string) {
+	s.actMu.Lock()
+	defer s.actMu.Unlock()
+	s.activityOf(deviceID).last = time.Now()
+}
+
+// setStopped marca o device como parado (ou rodando), conforme o estado do container.
+func (s *ZapPkg) setStopped(deviceID string, stopped bool) {
+	s.actMu.Lock()
+	defer s.actMu.Unlock()
+	a := s.activityOf(deviceID)
+	if a.stopped && !stopped {
+		a.last = time.Now()
+	}
+	a.stopped = stopped
+}
+
+// lastActivity retorna o último uso registrado do device.
+func (s *ZapPkg) lastActivity(deviceID string) (time.Time, bool) {
+	s.actMu.Lock()
+	defer s.actMu.Unlock()
+	a, ok := s.activity[deviceID]
+	if !ok {
+		return time.Time{}, false
+	}
+	return a.last, true
+}
+
+func (s *ZapPkg) isStopped(deviceID string) bool {
+	s.actMu.Lock()
+	defer s.actMu.Unlock()
+	a, ok := s.activity[deviceID]
+	return ok && a.stopped
+}
+
+func (s *ZapPkg) forgetActivity(deviceID string) {
+	s.actMu.Lock()
+	defer s.actMu.Unlock()
+	delete(s.activity, deviceID)
+	delete(s.locks, deviceID)
+}
+
+// hasDevice informa se o master conhece o device, parado ou não.
+func (s *ZapPkg) hasDevice(deviceID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.devices[deviceID]
+	return ok
+}
+
+// deviceLock serializa parada e despertar de um mesmo device.
+func (s *ZapPkg) deviceLock(deviceID string) *sync.Mutex {
+	s.actMu.Lock()
+	defer s.actMu.Unlock()
+	lock, ok := s.locks[deviceID]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.locks[deviceID] = lock
+	}
+	return lock
+}
+
+// Acquire marca o device como em uso e retorna o endpoint do child. Um device parado
+// por inatividade sobe de novo e a chamada espera o child conectar ao WhatsApp (até
+// idle.wake_timeout). release deve ser chamado quando a requisição terminar.
+func (s *ZapPkg) Acquire(ctx context.Context, deviceID string) (endpoint string, release func(), err error) {
+	s.actMu.Lock()
+	s.activityOf(deviceID).active++
+	s.actMu.Unlock()
+
+	release = func() {
+		s.actMu.Lock()
+		defer s.actMu.Unlock()
+		a := s.activityOf(deviceID)
+		a.active--
+		a.last = time.Now()
+	}
+
+	if err := s.wake(ctx, deviceID); err != nil {
+		release()
+		return "", nil, err
+	}
+	endpoint, err = s.GetDeviceEndpoint(deviceID)
+	if err != nil {
+		release()
+		return "", nil, err
+	}
+	return endpoint, release, nil
+}
+
+// wake sobe o container parado e espera o child conectar; não faz nada se o device já está rodando.
+func (s *ZapPkg) wake(ctx context.Context, deviceID string) error {
+	lock := s.deviceLock(deviceID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !s.isStopped(deviceID) {
+		return nil
+	}
+
+	s.mu.RLock()
+	old, ok := s.devices[deviceID]
+	s.mu.RUnlock()
+	if !ok {
+		return errors.New("device não encontrado")
+	}
+
+	start := time.Now()
+	slog.InfoContext(ctx, "⏰ Acordando device", "device", deviceID)
+	cc, err := s.dockerMgr.RestartContainer(ctx, old.ID)
+	if err == nil {
+		err = s.waitUntilHealthy(cc.Endpoint, s.cfg.HealthTimeout.Std())
+	}
+	if err != nil {
+		// A porta publicada pode ter sido ocupada enquanto o container estava parado;
+		// um container novo com o mesmo volume mantém a sessão
+		slog.WarnContext(ctx, "Container parado não subiu, recriando", "device", deviceID, "error", err)
+		_ = s.dockerMgr.RemoveContainer(ctx, old.ID)
+		cc, err = s.startDevice(ctx, deviceID, DeviceOptions{Tenant: old.Tenant, IdleTimeout: old.IdleTimeout})
+	}
+	if err != nil {
+		observeDeviceOperation("wake", start, err)
+		return fmt.Errorf("erro ao acordar device: %w", err)
+	}
+
+	s.mu.Lock()
+	s.devices[deviceID] = cc
+	s.mu.Unlock()
+	s.setStopped(deviceID, false)
+
+	err = s.waitUntilConnected(ctx, cc.Endpoint, s.idle.WakeTimeout.Std())
+	observeDeviceOperation("wake", start, err)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Device acordado", "device", deviceID, "endpoint", cc.Endpoint, "duration_ms", time.Since(start).Milliseconds())
+	return nil
+}
+
+// waitUntilConnected consulta o /status do child até ele conectar ao WhatsApp. Um
+// device que não vai conectar sozinho (sem sessão, pareando, substituído, banido ou
+// desconectado sem reconexão automática) é liberado na hora.
+func (s *ZapPkg) waitUntilConnected(ctx context.Context, endpoint string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := &http.Client{Transport: s.transport, Timeout: 5 * time.Second}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/status", nil)
+		if resp, err := client.Do(req); err == nil {
+			var status struct {
+				State     string `json:"state"`
+				JID       string `json:"jid"`
+				Reconnect bool   `json:"reconnect"`
+			}
+			err = json.NewDecoder(resp.Body).Decode(&status)
+			resp.Body.Close()
+			if err == nil && !awaitingConnection(status.State, status.JID != "", status.Reconnect) {
+				return nil
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("device não conectou ao WhatsApp em %s", timeout)
+		}
+	}
+}
+
+// awaitingConnection informa se vale esperar o child: só enquanto há sessão e ele
+// está conectando ou vai reconectar sozinho.
+func awaitingConnection(state string, hasSession, reconnect bool) bool {
+	if !hasSession {
+		return false
+	}
+	switch state {
+	case "connecting":
+		return true
+	case "disconnected":
+		return reconnect
+	}
+	return false // connected, logged_out, replaced, banned
+}
+
+// idleTimeout é o tempo sem uso até o device parar (0 nunca para).
+func (s *ZapPkg) idleTimeout(cc *ClientContainer) time.Duration {
+	if cc.IdleTimeout != nil {
+		return *cc.IdleTimeout
+	}
+	return s.idle.Timeout.Std()
+}
+
+// RunIdle para os devices ociosos a cada idle.check_interval, até ctx ser cancelado.
+// Ocioso é o device sem requisições, streams abertos ou mensagens recebidas durante
+// o seu idle timeout.
+func (s *ZapPkg) RunIdle(ctx context.Context) {
+	ticker := time.NewTicker(s.idle.CheckInterval.Std())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.stopIdleDevices(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *ZapPkg) stopIdleDevices(ctx context.Context) {
+	s.mu.RLock()
+	candidates := make(map[string]*ClientContainer, len(s.devices))
+	for id, cc := range s.devices {
+		if s.idleTimeout(cc) > 0 {
+			candidates[id] = cc
+		}
+	}
+	s.mu.RUnlock()
+
+	for id, cc := range candidates {
+		if ctx.Err() != nil {
+			return
+		}
+		if s.idleFor(id, s.idleTimeout(cc)) {
+			s.stopIdle(ctx, id)
+		}
+	}
+}
+
+// idleFor informa se o device está rodando e sem uso há pelo menos timeout.
+func (s *ZapPkg) idleFor(deviceID string, timeout time.Duration) bool {
+	s.actMu.Lock()
+	defer s.actMu.Unlock()
+	a := s.activityOf(deviceID)
+	return !a.stopped && a.active == 0 && time.Since(a.last) >= timeout
+}
+
+// stopIdle para o container do device, conferindo de novo a ociosidade com o lock do device.
+func (s *ZapPkg) stopIdle(ctx context.Context, deviceID string) {
+	lock := s.deviceLock(deviceID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	s.mu.RLock()
+	cc, ok := s.devices[deviceID]
+	s.mu.RUnlock()
+	if !ok || !s.idleFor(deviceID, s.idleTimeout(cc)) {
+		return
+	}
+
+	start := time.Now()
+	err := s.dockerMgr.StopContainer(ctx, cc.ID)
+	observeDeviceOperation("sleep", start, err)
+	if err != nil {
+		return
+	}
+	s.setStopped(deviceID, true)
+	slog.Info("💤 Device parado por inatividade", "device", deviceID, "idle_timeout", s.idleTimeout(cc).String())
+}
@@ -0,0 +1,116 @@
+package whatsapp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/simpplify-org/GO-simpzap/pkg/tracing"
+)
+
+var (
+	deviceOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "whatsapp_master_device_operation_duration_seconds",
+		Help:    "Duração da criação e remoção de devices (containers), por resultado.",
+		Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 15, 30, 60},
+	}, []string{"operation", "result"})
+
+	proxyRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "whatsapp_master_proxy_requests_total",
+		Help: "Requisições encaminhadas aos childs, por device e status HTTP.",
+	}, []string{"device", "code"})
+
+	proxyDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "whatsapp_master_proxy_request_duration_seconds",
+		Help:    "Latência das requisições encaminhadas aos childs (sem WebSocket e SSE).",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"device"})
+
+	healthCheckFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "whatsapp_master_health_check_failures_total",
+		Help: "Health checks de childs que não responderam a tempo após subir.",
+	}, []string{"device"})
+)
+
+// observeDeviceOperation registra a duração de uma criação/remoção de device.
+func observeDeviceOperation(operation string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	deviceOperationDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
+
+// deviceCollector conta os containers por estado (running, exited...) e as sessões
+// reportadas pelos childs no momento da coleta.
+type deviceCollector struct {
+	zap      *ZapPkg
+	devices  *prometheus.Desc
+	sessions *prometheus.Desc
+}
+
+// RegisterMetrics registra as métricas de devices do master no registry padrão.
+func (s *ZapPkg) RegisterMetrics() error {
+	err := prometheus.Register(&deviceCollector{
+		zap: s,
+		devices: prometheus.NewDesc("whatsapp_master_devices",
+			"Containers de devices por estado.", []string{"state"}, nil),
+		sessions: prometheus.NewDesc("whatsapp_master_device_sessions",
+			"Devices pelo último estado de sessão reportado (paired, logged_out).", []string{"state"}, nil),
+	})
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		return nil
+	}
+	return err
+}
+
+func (c *deviceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.devices
+	ch <- c.sessions
+}
+
+func (c *deviceCollector) Collect(ch chan<- prometheus.Metric) {
+	containers, err := c.zap.dockerMgr.client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {"app=whatsapp-client"}},
+	})
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.devices, err)
+	} else {
+		byState := map[string]int{}
+		for _, container := range containers {
+			byState[container.State]++
+		}
+		for state, n := range byState {
+			ch <- prometheus.MustNewConstMetric(c.devices, prometheus.GaugeValue, float64(n), state)
+		}
+	}
+
+	c.zap.mu.RLock()
+	bySession := map[string]int{}
+	for _, session := range c.zap.sessions {
+		bySession[session.State]++
+	}
+	c.zap.mu.RUnlock()
+	for state, n := range bySession {
+		ch <- prometheus.MustNewConstMetric(c.sessions, prometheus.GaugeValue, float64(n), state)
+	}
+}
+
+// observeProxy registra a requisição encaminhada ao child. Streams (WebSocket e SSE)
+// entram na contagem, mas não na latência, que seria o tempo de vida da conexão.
+func observeProxy(device string, rec *tracing.StatusRecorder, start time.Time) {
+	status := rec.Status()
+	proxyRequests.WithLabelValues(device, strconv.Itoa(status)).Inc()
+
+	if status == http.StatusSwitchingProtocols || strings.HasPrefix(rec.Header().Get("Content-Type"), "text/event-stream") {
+		return
+	}
+	proxyDuration.WithLabelValues(device).Observe(time.Since(start).Seconds())
+}
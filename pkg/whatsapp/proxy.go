@@ -0,0 +1,104 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/simpplify-org/GO-simpzap/pkg/config"
+	"github.com/simpplify-org/GO-simpzap/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// proxyCancelKey guarda no contexto da requisição o cancelamento usado para
+// encerrar respostas SSE no Shutdown.
+type proxyCancelKey struct{}
+
+// deviceProxy é o reverse proxy de um device, reaproveitado entre as requisições
+// enquanto o endpoint do container não mudar.
+type deviceProxy struct {
+	endpoint string
+	proxy    *httputil.ReverseProxy
+}
+
+// newProxyTransport cria o transporte compartilhado pelos proxies dos devices.
+func newProxyTransport(cfg config.Proxy) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout.Std(),
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Transport{
+		DialContext:           dialer.DialContext, // sem HTTP_PROXY: os childs estão na rede local
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout.Std(),
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout.Std(),
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// proxyFor retorna o proxy do device, recriando-o quando o endpoint mudou
+// (container recriado ou reiniciado em outra porta).
+func (s *ZapPkg) proxyFor(deviceID, endpoint string) (*httputil.ReverseProxy, error) {
+	s.proxyMu.Lock()
+	defer s.proxyMu.Unlock()
+
+	cached, ok := s.proxies[deviceID]
+	if ok && cached.endpoint == endpoint {
+		return cached.proxy, nil
+	}
+
+	target, err := url.Parse(endpoint)
+	if err != nil || target.Host == "" {
+		return nil, fmt.Errorf("endpoint inválido: %q", endpoint)
+	}
+	if ok {
+		// A porta antiga pode ser reaproveitada por outro container; conexões ociosas
+		// para ela não servem mais
+		s.transport.CloseIdleConnections()
+		slog.Info("Endpoint do device mudou, recriando proxy", "device", deviceID, "old", cached.endpoint, "new", endpoint)
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			// X-Request-Id e traceparent seguem para o child
+			tracing.Inject(pr.Out.Context(), pr.Out.Header)
+		},
+		Transport: s.transport,
+		ModifyResponse: func(resp *http.Response) error {
+			// Streams SSE ficam registrados para o encerramento do master
+			ctx := resp.Request.Context()
+			cancel, ok := ctx.Value(proxyCancelKey{}).(context.CancelFunc)
+			if ok && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+				st := &sseStream{cancel: cancel}
+				s.streams.add(st)
+				context.AfterFunc(ctx, func() { s.streams.remove(st) })
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			trace.SpanFromContext(r.Context()).RecordError(err)
+			slog.ErrorContext(r.Context(), "Falha no proxy", "device", deviceID, "target", target.String(), "error", err)
+			http.Error(w, "Proxy error: "+err.Error(), http.StatusBadGateway)
+		},
+	}
+	s.proxies[deviceID] = &deviceProxy{endpoint: endpoint, proxy: proxy}
+	return proxy, nil
+}
+
+// dropProxy descarta o proxy de um device removido.
+func (s *ZapPkg) dropProxy(deviceID string) {
+	s.proxyMu.Lock()
+	defer s.proxyMu.Unlock()
+	if _, ok := s.proxies[deviceID]; ok {
+		delete(s.proxies, deviceID)
+		s.transport.CloseIdleConnections()
+	}
+}
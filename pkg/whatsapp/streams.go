@@ -0,0 +1,228 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+)
+
+// wsCloseGoingAway é o frame de fechamento (1001, going away) enviado aos clientes
+// dos WebSockets repassados quando o master encerra.
+var wsCloseGoingAway = func() []byte {
+	reason := "servidor reiniciando"
+	frame := []byte{0x88, byte(2 + len(reason)), 0x03, 0xE9}
+	return append(frame, reason...)
+}()
+
+// stream é uma conexão longa do proxy (WebSocket ou SSE) que o http.Server não
+// encerra sozinho no Shutdown.
+type stream interface {
+	drain() // encerra de forma limpa
+	close() // encerra na marra, quando o prazo acaba
+}
+
+// streamSet guarda os streams abertos pelo proxy para o encerramento do master.
+type streamSet struct {
+	mu       sync.Mutex
+	streams  map[stream]struct{}
+	draining bool
+}
+
+func newStreamSet() *streamSet {
+	return &streamSet{streams: make(map[stream]struct{})}
+}
+
+// add registra o stream; se o master já está encerrando, ele é drenado na hora.
+func (set *streamSet) add(st stream) {
+	set.mu.Lock()
+	set.streams[st] = struct{}{}
+	draining := set.draining
+	set.mu.Unlock()
+
+	if draining {
+		go st.drain()
+	}
+}
+
+func (set *streamSet) remove(st stream) {
+	set.mu.Lock()
+	delete(set.streams, st)
+	set.mu.Unlock()
+}
+
+func (set *streamSet) len() int {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	return len(set.streams)
+}
+
+// shutdown drena todos os streams e espera até o prazo de ctx; o que sobrar é fechado.
+func (set *streamSet) shutdown(ctx context.Context) error {
+	set.mu.Lock()
+	set.draining = true
+	for st := range set.streams {
+		// Um cliente lento não pode atrasar o fechamento dos outros
+		go st.drain()
+	}
+	set.mu.Unlock()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for set.len() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			set.mu.Lock()
+			for st := range set.streams {
+				st.close()
+			}
+			set.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// sseStream é um SSE repassado; cancelar a requisição ao child encerra a resposta
+// e o cliente reconecta com o Last-Event-ID.
+type sseStream struct {
+	cancel context.CancelFunc
+}
+
+func (s *sseStream) drain() { s.cancel() }
+func (s *sseStream) close() { s.cancel() }
+
+// tunnelConn é o lado do cliente de um upgrade repassado ao child. As escritas
+// vêm da cópia child → cliente do ReverseProxy; no encerramento o frame de
+// fechamento só é escrito entre dois frames, para não corromper o stream.
+type tunnelConn struct {
+	net.Conn
+	set       *streamSet
+	websocket bool // false para outros protocolos de upgrade, que são apenas fechados
+
+	mu      sync.Mutex // serializa as escritas com o frame de fechamento
+	frames  frameTracker
+	closing bool // drain pedido: fecha no próximo limite de frame
+	closed  bool
+	once    sync.Once
+}
+
+func (set *streamSet) tunnel(conn net.Conn, websocket bool) net.Conn {
+	c := &tunnelConn{Conn: conn, set: set, websocket: websocket}
+	set.add(c)
+	return c
+}
+
+func (c *tunnelConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+
+	n, err := c.Conn.Write(p)
+	c.frames.advance(p[:n])
+	if c.closing && c.frames.boundary() {
+		c.goAway()
+	}
+	return n, err
+}
+
+func (c *tunnelConn) drain() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closing = true
+	if !c.websocket || c.frames.boundary() {
+		c.goAway()
+	}
+}
+
+// goAway envia o frame de fechamento (só em WebSocket) e fecha a conexão; exige c.mu.
+func (c *tunnelConn) goAway() {
+	if c.websocket {
+		c.Conn.SetWriteDeadline(time.Now().Add(time.Second))
+		c.Conn.Write(wsCloseGoingAway)
+	}
+	c.closed = true
+	c.Close()
+}
+
+func (c *tunnelConn) close() {
+	c.Close()
+}
+
+// Close é chamado pelo ReverseProxy ao fim do túnel e pelo encerramento do master.
+func (c *tunnelConn) Close() error {
+	var err error
+	c.once.Do(func() {
+		c.set.remove(c)
+		err = c.Conn.Close()
+	})
+	return err
+}
+
+// frameTracker acompanha os frames WebSocket escritos para saber quando o stream
+// está entre dois frames.
+type frameTracker struct {
+	header    [14]byte
+	headerLen int    // bytes do cabeçalho do próximo frame já escritos
+	remaining uint64 // bytes de payload que faltam no frame atual
+}
+
+func (t *frameTracker) advance(p []byte) {
+	for len(p) > 0 {
+		if t.remaining > 0 {
+			n := min(uint64(len(p)), t.remaining)
+			t.remaining -= n
+			p = p[n:]
+			continue
+		}
+
+		t.header[t.headerLen] = p[0]
+		t.headerLen++
+		p = p[1:]
+		if size, ok := t.payloadSize(); ok {
+			t.headerLen = 0
+			t.remaining = size
+		}
+	}
+}
+
+// payloadSize retorna o tamanho do payload quando o cabeçalho está completo.
+func (t *frameTracker) payloadSize() (uint64, bool) {
+	if t.headerLen < 2 {
+		return 0, false
+	}
+	length := t.header[1] & 0x7f
+	need := 2
+	switch length {
+	case 126:
+		need += 2
+	case 127:
+		need += 8
+	}
+	if t.header[1]&0x80 != 0 { // máscara
+		need += 4
+	}
+	if t.headerLen < need {
+		return 0, false
+	}
+
+	switch length {
+	case 126:
+		return uint64(binary.BigEndian.Uint16(t.header[2:4])), true
+	case 127:
+		return binary.BigEndian.Uint64(t.header[2:10]), true
+	default:
+		return uint64(length), true
+	}
+}
+
+func (t *frameTracker) boundary() bool {
+	return t.headerLen == 0 && t.remaining == 0
+}
@@ -2,17 +2,28 @@ package whatsapp
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
+	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/fsouza/go-dockerclient"
+	"github.com/simpplify-org/GO-simpzap/pkg/config"
+	"github.com/simpplify-org/GO-simpzap/pkg/logging"
+	"github.com/simpplify-org/GO-simpzap/pkg/phone"
+	"github.com/simpplify-org/GO-simpzap/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Gerencia containers por device, faz proxy das chamadas.
@@ -21,23 +32,104 @@ type ZapPkg struct {
 	mu          sync.RWMutex
 	devices     map[string]*ClientContainer // key: deviceID // VAI SER SO O NUMERO MESMO
 	clientImage string                      // imagem do child (ex: "myrepo/whats-child:latest")
+	masterURL   string                      // URL que os childs usam para avisar o master
+	secret      []byte                      // assina os tokens dos childs (DeviceToken)
+	cfg         config.Docker               // porta interna, rede e timeouts dos childs
+	sessions    map[string]SessionState     // último estado de sessão reportado por cada device
+	streams     *streamSet                  // WebSockets e SSE abertos pelo proxy
+	proxyMu     sync.Mutex
+	proxies     map[string]*deviceProxy // key: deviceID; recriado quando o endpoint muda
+	transport   *http.Transport         // conexões keep-alive com os childs, compartilhadas pelos proxies
+	idle        config.Idle
+	policy      config.Devices  // auto-criação pelo proxy e limite por tenant
+	known       map[string]bool // devices.known normalizados
+	actMu       sync.Mutex
+	activity    map[string]*deviceActivity // key: deviceID; uso recente, para o scale-to-zero
+	locks       map[string]*sync.Mutex     // serializa parada e despertar de cada device
 }
 
-func NewZapPkg() *ZapPkg {
-	dm, err := NewDockerManager()
+// ErrTenantLimit indica que o tenant já tem devices.max_per_tenant devices.
+var ErrTenantLimit = errors.New("limite de devices do tenant atingido")
+
+// DeviceOptions são as opções de criação de um device.
+type DeviceOptions struct {
+	Tenant      string         // cliente dono do device, gravado como label do container
+	IdleTimeout *time.Duration // sobrescreve idle.timeout do master; 0 nunca para
+}
+
+// SessionState é o último estado de sessão reportado pelo child (paired, logged_out).
+type SessionState struct {
+	State     string    `json:"state"`
+	Reason    string    `json:"reason,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewZapPkg(cfg config.Master) *ZapPkg {
+	dm, err := NewDockerManager(cfg.Docker)
 	if err != nil {
-		log.Fatal("Erro ao iniciar docker manager: ", err)
+		slog.Error("Erro ao iniciar docker manager", "error", err)
+		os.Exit(1)
+	}
+
+	// Os childs alcançam o master pela bridge do Docker, a menos que master_url diga outra coisa
+	masterURL := cfg.MasterURL
+	if masterURL == "" {
+		masterURL = fmt.Sprintf("http://%s:%d", dm.getDockerHost(), cfg.Port())
+	}
+
+	known := make(map[string]bool, len(cfg.Devices.Known))
+	for _, number := range cfg.Devices.Known {
+		if normalized, err := phone.Normalize(number); err == nil {
+			known[normalized] = true
+		}
 	}
 
 	return &ZapPkg{
 		dockerMgr:   dm,
 		devices:     make(map[string]*ClientContainer),
-		clientImage: "zap-client:latest",
+		clientImage: cfg.Docker.ImageRef(),
+		masterURL:   masterURL,
+		secret:      []byte(cfg.DeviceSecret),
+		cfg:         cfg.Docker,
+		sessions:    make(map[string]SessionState),
+		streams:     newStreamSet(),
+		proxies:     make(map[string]*deviceProxy),
+		transport:   newProxyTransport(cfg.Proxy),
+		idle:        cfg.Idle,
+		policy:      cfg.Devices,
+		known:       known,
+		activity:    make(map[string]*deviceActivity),
+		locks:       make(map[string]*sync.Mutex),
 	}
 }
 
+// Shutdown encerra os streams abertos pelo proxy: os WebSockets recebem o frame de
+// fechamento (1001) e os SSE são cortados, para que os clientes reconectem na nova
+// instância do master. O que não terminar até o prazo de ctx é fechado.
+func (s *ZapPkg) Shutdown(ctx context.Context) error {
+	return s.streams.shutdown(ctx)
+}
+
+// SetSessionState registra o estado de sessão reportado pelo child do device.
+func (s *ZapPkg) SetSessionState(deviceID string, state SessionState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[deviceID] = state
+	slog.Info("Sessão do device atualizada", "device", deviceID, "state", state.State, "reason", state.Reason)
+}
+
 // CreateDevice cria container para device, se já existir retorna o existente.
-func (s *ZapPkg) CreateDevice(ctx context.Context, phoneNumber string) (*ClientContainer, error) {
+// phoneNumber deve vir normalizado (phone.Normalize).
+func (s *ZapPkg) CreateDevice(ctx context.Context, phoneNumber string, opts DeviceOptions) (*ClientContainer, error) {
+	// O número vira nome de container, label e volume: nada é criado sem validar
+	normalized, err := phone.Normalize(phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if normalized != phoneNumber {
+		return nil, fmt.Errorf("%w: use a forma normalizada %s", phone.ErrInvalid, normalized)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -45,45 +137,109 @@ func (s *ZapPkg) CreateDevice(ctx context.Context, phoneNumber string) (*ClientC
 		return c, nil
 	}
 
-	namePrefix := "whats-device-" + sanitizeName(phoneNumber)
-	labels := map[string]string{
-		"app":          "whatsapp-client",
-		"phone_number": phoneNumber,
-	}
-
 	existing, err := s.dockerMgr.FindContainerByLabel(ctx, "phone_number", phoneNumber)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao buscar container existente: %w", err)
 	}
 
 	if existing != nil {
-		log.Printf("[ZapPkg] Reutilizando container existente para %s (ID=%s)", phoneNumber, existing.ID)
+		slog.Info("Reutilizando container existente", "device", phoneNumber, "container_id", existing.ID)
 		s.devices[phoneNumber] = existing
+		s.setStopped(phoneNumber, false)
 		return existing, nil
 	}
 
+	if limit := s.policy.MaxPerTenant; limit > 0 && s.countTenant(opts.Tenant) >= limit {
+		return nil, fmt.Errorf("%w (%d)", ErrTenantLimit, limit)
+	}
+
+	start := time.Now()
+	cc, err := s.startDevice(ctx, phoneNumber, opts)
+	observeDeviceOperation("create", start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	s.devices[phoneNumber] = cc
+	s.setStopped(phoneNumber, false)
+	slog.Info("Device criado", "device", phoneNumber, "endpoint", cc.Endpoint, "duration_ms", time.Since(start).Milliseconds())
+	return cc, nil
+}
+
+// countTenant conta os devices do tenant, parados ou não; exige s.mu.
+func (s *ZapPkg) countTenant(tenant string) int {
+	n := 0
+	for _, c := range s.devices {
+		if c.Tenant == tenant {
+			n++
+		}
+	}
+	return n
+}
+
+// canAutoCreate informa se o proxy pode criar o container de um número que o master
+// ainda não conhece, conforme devices.auto_create.
+func (s *ZapPkg) canAutoCreate(deviceID string) bool {
+	switch s.policy.AutoCreate {
+	case config.AutoCreateOn:
+		return true
+	case config.AutoCreateKnown:
+		return s.known[deviceID]
+	default:
+		return false
+	}
+}
+
+// startDevice cria o container do device sobre o volume dele e espera o child responder.
+func (s *ZapPkg) startDevice(ctx context.Context, phoneNumber string, opts DeviceOptions) (*ClientContainer, error) {
+	namePrefix := "whats-device-" + sanitizeName(phoneNumber)
+	labels := map[string]string{
+		"app":          "whatsapp-client",
+		"phone_number": phoneNumber,
+	}
+	if opts.Tenant != "" {
+		labels["tenant"] = opts.Tenant
+	}
+	if opts.IdleTimeout != nil {
+		labels["idle_timeout"] = opts.IdleTimeout.String()
+	}
+
 	envs := []string{
 		fmt.Sprintf("PHONE_NUMBER=%s", phoneNumber),
-		fmt.Sprintf("LOG_LEVEL=info"),
+		fmt.Sprintf("MASTER_URL=%s", s.masterURL),
+		fmt.Sprintf("MASTER_TOKEN=%s", s.DeviceToken(phoneNumber)),
+		fmt.Sprintf("LISTEN_ADDR=:%d", s.cfg.InternalPort),
+		fmt.Sprintf("DATA_DIR=%s", childDataDir),
+	}
+	// O child herda a configuração de log e de tracing do master (LOG_LEVEL, máscaras, OTEL_*...)
+	for _, key := range slices.Concat(logging.EnvVars, tracing.EnvVars) {
+		if value, ok := os.LookupEnv(key); ok {
+			envs = append(envs, key+"="+value)
+		}
 	}
 
-	cc, err := s.dockerMgr.StartContainer(ctx, s.clientImage, namePrefix, labels, envs)
+	cc, err := s.dockerMgr.StartContainer(ctx, s.clientImage, namePrefix, deviceVolume(phoneNumber), labels, envs)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao iniciar container para numero %s: %w", phoneNumber, err)
 	}
+	cc.Tenant = opts.Tenant
+	cc.IdleTimeout = opts.IdleTimeout
 
 	// health-check no endpoint do child para garantir start
-	if err := s.waitUntilHealthy(cc.Endpoint, 15*time.Second); err != nil {
+	if err := s.waitUntilHealthy(cc.Endpoint, s.cfg.HealthTimeout.Std()); err != nil {
+		healthCheckFailures.WithLabelValues(phoneNumber).Inc()
 		_ = s.dockerMgr.StopContainer(ctx, cc.ID)
 		_ = s.dockerMgr.RemoveContainer(ctx, cc.ID)
 		return nil, fmt.Errorf("container iniciou mas não respondeu: %w", err)
 	}
-
-	s.devices[phoneNumber] = cc
-	log.Printf("[Service] Device criado: %s -> %s", phoneNumber, cc.Endpoint)
 	return cc, nil
 }
 
+// deviceVolume é o nome do volume com a sessão e o histórico do device.
+func deviceVolume(phoneNumber string) string {
+	return "whats-device-" + sanitizeName(phoneNumber) + "-data"
+}
+
 // RemoveDevice para e remove
 func (s *ZapPkg) RemoveDevice(ctx context.Context, deviceID string) error {
 	s.mu.Lock()
@@ -94,15 +250,24 @@ func (s *ZapPkg) RemoveDevice(ctx context.Context, deviceID string) error {
 		return errors.New("device não encontrado")
 	}
 
+	start := time.Now()
 	if err := s.dockerMgr.StopContainer(ctx, cc.ID); err != nil {
-		log.Printf("[Service] falha ao parar container %s: %v", cc.ID, err)
+		slog.Warn("Falha ao parar container", "device", deviceID, "container_id", cc.ID, "error", err)
+	}
+	err := s.dockerMgr.RemoveContainer(ctx, cc.ID)
+	if err != nil {
+		slog.Warn("Falha ao remover container", "device", deviceID, "container_id", cc.ID, "error", err)
 	}
-	if err := s.dockerMgr.RemoveContainer(ctx, cc.ID); err != nil {
-		log.Printf("[Service] falha ao remover container %s: %v", cc.ID, err)
+	// Remover o device apaga a sessão; parar por inatividade não
+	if verr := s.dockerMgr.RemoveVolume(ctx, deviceVolume(deviceID)); verr != nil {
+		slog.Warn("Falha ao remover volume", "device", deviceID, "volume", deviceVolume(deviceID), "error", verr)
 	}
+	observeDeviceOperation("remove", start, err)
 
 	delete(s.devices, deviceID)
-	log.Printf("[Service] Device %s removido", deviceID)
+	s.dropProxy(deviceID)
+	s.forgetActivity(deviceID)
+	slog.Info("Device removido", "device", deviceID)
 	return nil
 }
 
@@ -111,11 +276,62 @@ func (s *ZapPkg) GetDeviceEndpoint(deviceID string) (string, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	if c, ok := s.devices[deviceID]; ok {
+		if s.isStopped(deviceID) {
+			return "", ErrDeviceStopped
+		}
 		return c.Endpoint, nil
 	}
 	return "", errors.New("device não iniciado")
 }
 
+// DeviceNumbers retorna os números dos devices conhecidos pelo master.
+func (s *ZapPkg) DeviceNumbers() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	numbers := make([]string, 0, len(s.devices))
+	for number := range s.devices {
+		numbers = append(numbers, number)
+	}
+	return numbers
+}
+
+// DeviceToken é o token que o child do device usa para avisar o master
+// (HMAC-SHA256 do número com device_secret).
+func (s *ZapPkg) DeviceToken(deviceID string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(deviceID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyDeviceToken confere o token enviado pelo child do device.
+func (s *ZapPkg) VerifyDeviceToken(deviceID, token string) bool {
+	return hmac.Equal([]byte(token), []byte(s.DeviceToken(deviceID)))
+}
+
+// DeviceTenant retorna o tenant do device (vazio se não informado ou desconhecido).
+func (s *ZapPkg) DeviceTenant(deviceID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if c, ok := s.devices[deviceID]; ok {
+		return c.Tenant
+	}
+	return ""
+}
+
+// deviceTenants retorna os devices em execução com o tenant de cada um.
+func (s *ZapPkg) deviceTenants() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	devices := make(map[string]string, len(s.devices))
+	for number, c := range s.devices {
+		if s.isStopped(number) {
+			continue // a assinatura volta quando o device acordar
+		}
+		devices[number] = c.Tenant
+	}
+	return devices
+}
+
 // ProxyHandler gera um http.Handler que roteia para o container do device.
 // pathPrefix é prefixo usado para extrair device do path, por exemplo "/device/{deviceID}/..."
 func (s *ZapPkg) ProxyHandler() http.Handler {
@@ -130,51 +346,69 @@ func (s *ZapPkg) ProxyHandler() http.Handler {
 			http.Error(w, "device não informado", http.StatusBadRequest)
 			return
 		}
-		deviceID := parts[1]
-
-		// garante que o device exista
-		endpoint, err := s.GetDeviceEndpoint(deviceID)
+		rawDeviceID := parts[1]
+		deviceID, err := phone.Normalize(rawDeviceID)
 		if err != nil {
-			ctx := r.Context()
-			if _, cerr := s.CreateDevice(ctx, deviceID); cerr != nil {
-				http.Error(w, "erro ao criar device: "+cerr.Error(), http.StatusInternalServerError)
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// garante que o device exista; número desconhecido só vira container se a política permitir
+		if !s.hasDevice(deviceID) {
+			if !s.canAutoCreate(deviceID) {
+				slog.DebugContext(r.Context(), "Device desconhecido", "device", deviceID, "auto_create", s.policy.AutoCreate)
+				writeError(w, http.StatusNotFound, "device "+deviceID+" não encontrado; crie com POST /create")
+				return
+			}
+			if _, cerr := s.CreateDevice(r.Context(), deviceID, DeviceOptions{}); cerr != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(cerr, ErrTenantLimit) {
+					status = http.StatusForbidden
+				}
+				writeError(w, status, "erro ao criar device: "+cerr.Error())
 				return
 			}
-			endpoint, _ = s.GetDeviceEndpoint(deviceID)
 		}
 
-		target, err := url.Parse(endpoint)
+		// acorda o device parado por inatividade; a requisição (ou o stream) o mantém em uso
+		endpoint, release, err := s.Acquire(r.Context(), deviceID)
 		if err != nil {
-			http.Error(w, "endpoint inválido", http.StatusInternalServerError)
+			writeError(w, http.StatusServiceUnavailable, "device indisponível: "+err.Error())
+			return
+		}
+		defer release()
+
+		proxy, err := s.proxyFor(deviceID, endpoint)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
 		// remap a URL para remover /device/{deviceID} do path antes de repassar
 		// ex: /device/123/message/send -> /message/send
-		stripPrefix := fmt.Sprintf("/device/%s", deviceID)
+		stripPrefix := fmt.Sprintf("/device/%s", rawDeviceID)
 		r.URL.Path = singleJoiningSlash("/", r.URL.Path[len(stripPrefix):])
 
-		proxy := httputil.NewSingleHostReverseProxy(target)
-		
-		// Preservar cabeçalhos de WebSocket que podem ser removidos pelo reverse proxy padrão do Go
-		originalDirector := proxy.Director
-		proxy.Director = func(req *http.Request) {
-			originalDirector(req)
-			
-			// Se for um upgrade de WebSocket, garante que os cabeçalhos cruciais sejam explicitamente passados ao child
-			if r.Header.Get("Upgrade") != "" {
-				req.Header.Set("Upgrade", r.Header.Get("Upgrade"))
-				req.Header.Set("Connection", "Upgrade")
-			}
+		ctx, span := tracing.Start(r.Context(), "proxy device", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("device", logging.MaskPhone(deviceID)), attribute.String("url.path", logging.MaskPhones(r.URL.Path))))
+		defer span.End()
+		// O cancelamento encerra respostas SSE no Shutdown do master
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		r = r.WithContext(context.WithValue(ctx, proxyCancelKey{}, cancel))
+
+		rec := tracing.NewStatusRecorder(w)
+		defer observeProxy(deviceID, rec, time.Now())
+		w = rec
+
+		// O ReverseProxy repassa o upgrade (WebSocket) e faz o Hijack; o túnel fica
+		// registrado para o encerramento do master
+		isWebSocket := strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+		rec.Hijacked = func(conn net.Conn) net.Conn {
+			return s.streams.tunnel(conn, isWebSocket)
 		}
 
-		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-			log.Printf("[Proxy Error] falha no proxy para %s: %v", target.String(), err)
-			http.Error(w, "Proxy error: "+err.Error(), http.StatusBadGateway)
-		}
-		
-		isWebSocket := r.Header.Get("Upgrade") != ""
-		log.Printf("[Proxy] Encaminhando req %s para %s (Path: %s) [WS: %t]", r.Method, target.String(), r.URL.Path, isWebSocket)
+		slog.DebugContext(r.Context(), "Encaminhando requisição", "device", deviceID, "method", r.Method, "target", endpoint, "path", r.URL.Path, "websocket", isWebSocket)
 		proxy.ServeHTTP(w, r)
 	})
 
@@ -182,11 +416,15 @@ func (s *ZapPkg) ProxyHandler() http.Handler {
 }
 
 type DeviceInfo struct {
-	ID       string `json:"id"`
-	Number   string `json:"number"`
-	Endpoint string `json:"endpoint"`
-	WsUrl    string `json:"ws_url"`
-	Status   string `json:"status"`
+	ID             string        `json:"id"`
+	Number         string        `json:"number"`
+	Tenant         string        `json:"tenant,omitempty"`
+	Endpoint       string        `json:"endpoint"`
+	WsUrl          string        `json:"ws_url"`
+	Status         string        `json:"status"`
+	IdleTimeout    string        `json:"idle_timeout,omitempty"`
+	LastActivityAt *time.Time    `json:"last_activity_at,omitempty"`
+	Session        *SessionState `json:"session,omitempty"`
 }
 
 // ListDevices busca todos os containers no Docker com o label app=whatsapp-client
@@ -219,7 +457,7 @@ func (s *ZapPkg) ListDevices(ctx context.Context) ([]DeviceInfo, error) {
 		}
 
 		port := 0
-		if bindings, ok := inspect.NetworkSettings.Ports["8080/tcp"]; ok && len(bindings) > 0 {
+		if bindings, ok := inspect.NetworkSettings.Ports[s.dockerMgr.internalPort()]; ok && len(bindings) > 0 {
 			port, _ = strconv.Atoi(bindings[0].HostPort)
 		}
 
@@ -228,22 +466,37 @@ func (s *ZapPkg) ListDevices(ctx context.Context) ([]DeviceInfo, error) {
 
 		// Sincroniza o cache interno em memória
 		cc := &ClientContainer{
-			ID:       c.ID,
-			Host:     host,
-			Port:     port,
-			Endpoint: endpoint,
+			ID:          c.ID,
+			Host:        host,
+			Port:        port,
+			Endpoint:    endpoint,
+			Tenant:      c.Labels["tenant"],
+			IdleTimeout: idleTimeoutLabel(c.Labels),
 		}
 		s.devices[phoneNumber] = cc
 
 		status := c.State // "running", "exited", etc.
+		// Container parado (por inatividade ou não) acorda na próxima requisição
+		s.setStopped(phoneNumber, status != "running")
 
-		list = append(list, DeviceInfo{
+		info := DeviceInfo{
 			ID:       c.ID,
 			Number:   phoneNumber,
+			Tenant:   cc.Tenant,
 			Endpoint: endpoint,
 			WsUrl:    wsUrl,
 			Status:   status,
-		})
+		}
+		if timeout := s.idleTimeout(cc); timeout > 0 {
+			info.IdleTimeout = timeout.String()
+		}
+		if last, ok := s.lastActivity(phoneNumber); ok {
+			info.LastActivityAt = &last
+		}
+		if session, ok := s.sessions[phoneNumber]; ok {
+			info.Session = &session
+		}
+		list = append(list, info)
 	}
 
 	return list, nil